@@ -4,16 +4,22 @@ import (
 	"context"
 	stdlog "log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	_ "github.com/ksj/car-auction/docs"
 	"github.com/ksj/car-auction/internal/api"
 	"github.com/ksj/car-auction/internal/config"
+	"github.com/ksj/car-auction/internal/jwtkey"
 	"github.com/ksj/car-auction/internal/log"
+	"github.com/ksj/car-auction/internal/mail"
 	"github.com/ksj/car-auction/internal/metrics"
 	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/oidc"
+	"github.com/ksj/car-auction/internal/rbac"
 	"github.com/ksj/car-auction/internal/repo"
 	"github.com/ksj/car-auction/internal/service"
+	"github.com/ksj/car-auction/internal/storage"
 	"github.com/ksj/car-auction/internal/tracing"
 	"github.com/ksj/car-auction/internal/ws"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -50,22 +56,203 @@ func main() {
 	}
 
 	// 4) AutoMigrate: スキーマの自動生成／更新
-	if err := db.AutoMigrate(&model.Auction{}, &model.Bid{}, &model.User{}); err != nil {
+	if err := db.AutoMigrate(
+		&model.Auction{}, &model.Bid{}, &model.User{},
+		&model.SavedSearch{}, &model.Notification{}, &model.AuctionPhoto{},
+		&model.ConditionReport{}, &model.PanelDamage{}, &model.Inspection{},
+		&model.AuditLog{}, &model.UserRole{}, &model.Organization{}, &model.OrgMember{}, &model.RefreshToken{},
+		&model.UserToken{}, &model.RecoveryCode{}, &model.LoginAttempt{}, &model.RateLimitBucket{}, &model.APIKey{},
+		&model.UserIdentity{}, &model.SigningKey{}, &model.DealerVerification{}, &model.VerificationDocument{},
+		&model.VehicleLock{},
+	); err != nil {
 		stdlog.Fatal(err)
 	}
 
 	// 5) WebSocket ハブの生成および実行
 	hub := ws.NewHub()
+	hub.SetPresenceInterval(config.Cfg.WSPresenceInterval)
 	go hub.Run()
 
+	// JWT の署名鍵: RS256 / EdDSA では鍵を保存先で共有し、定期的にローテーション
+	// 切り替え後も、発行済みのトークン（アクセストークン・二要素認証や OIDC ログインの途中トークン）が切れるまで古い鍵で検証
+	var keyStore jwtkey.Store
+	switch config.Cfg.JwtKeyStore {
+	case "memory":
+		keyStore = jwtkey.NewMemoryStore()
+	case "db":
+		keyStore = repo.NewSigningKeyRepo(db)
+	default:
+		stdlog.Fatalf("unknown JWT_KEY_STORE %q", config.Cfg.JwtKeyStore)
+	}
+	signingKeys, err := jwtkey.New(keyStore, jwtkey.Options{
+		Alg:              config.Cfg.JwtAlg,
+		Issuer:           config.Cfg.JwtIssuer,
+		Audience:         config.Cfg.JwtAudience,
+		Secret:           config.Cfg.JwtSecret,
+		RotationInterval: config.Cfg.JwtKeyRotation,
+		Retention:        max(config.Cfg.AccessTokenTTL, 10*time.Minute) + time.Minute,
+	})
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	if err := signingKeys.Maintain(time.Now()); err != nil {
+		stdlog.Fatal(err)
+	}
+	go signingKeys.Run(context.Background(), time.Minute)
+	service.UseSigningKeys(signingKeys)
+	api.UseSigningKeys(signingKeys)
+
 	// 6) リポジトリおよびサービスの初期化
 	auctionRepo := repo.NewAuctionRepo(db)
 	bidRepo := repo.NewBidRepo(db)
 	userRepo := repo.NewUserRepo(db)
 
-	auctionSvc := service.NewAuctionService(auctionRepo)
+	// 複数ロール導入前のユーザーの users.role を付与ロールとして登録
+	if err := userRepo.BackfillRoles(); err != nil {
+		stdlog.Fatal(err)
+	}
+	// 既存オークションの current_price を補完（検索・並び替え用の非正規化列）
+	if err := auctionRepo.BackfillCurrentPrice(); err != nil {
+		stdlog.Fatal(err)
+	}
+	// 全文検索インデックス（MySQL: FULLTEXT ngram）の作成
+	if err := auctionRepo.Search.Migrate(); err != nil {
+		stdlog.Fatal(err)
+	}
+
+	// アップロードファイルの保存先（ローカルは /static/ で署名付き URL を配信、S3 は事前署名 URL）
+	signer := storage.NewSigner(config.Cfg.StorageSigningKey, config.Cfg.StorageURLTTL)
+	var store storage.BlobStore
+	switch config.Cfg.StorageBackend {
+	case "local":
+		store = storage.NewLocal(config.Cfg.StorageDir, "/static/", signer)
+	case "s3":
+		s3 := storage.NewS3(config.Cfg.S3Endpoint, config.Cfg.S3Region, config.Cfg.S3Bucket,
+			config.Cfg.S3AccessKey, config.Cfg.S3SecretKey, config.Cfg.StorageURLTTL)
+		s3.PathStyle = config.Cfg.S3PathStyle
+		store = s3
+	default:
+		stdlog.Fatalf("unknown STORAGE_BACKEND %q", config.Cfg.StorageBackend)
+	}
+	// 旧来の /static/<ファイル名> 形式で保存されたカバー写真をストレージキーに移行
+	if err := auctionRepo.BackfillPhotoKeys("/static/"); err != nil {
+		stdlog.Fatal(err)
+	}
+
+	auctionSvc := service.NewAuctionService(auctionRepo, hub, store)
 	bidSvc := service.NewBidService(bidRepo, hub)
-	userSvc := service.NewUserService(userRepo)
+	userSvc := service.NewUserService(userRepo, repo.NewRefreshTokenRepo(db))
+	// 停止・削除されたアカウントの発行済みトークンを拒否
+	api.UseAccountCheck(userSvc.CheckAccount)
+	// 販売店システム連携用の API キー ("Authorization: ApiKey ...")
+	apiKeySvc := service.NewAPIKeyService(repo.NewAPIKeyRepo(db), userRepo)
+	api.UseAPIKeyAuth(apiKeySvc.Authenticate)
+	if config.Cfg.AdminEmail != "" {
+		if err := userSvc.EnsureAdmin(config.Cfg.AdminEmail, config.Cfg.AdminPassword); err != nil {
+			stdlog.Fatal(err)
+		}
+	}
+
+	// 通知と保存済み検索条件: 新規出品時に保存済み検索条件と照合して通知
+	mailer := mail.New(config.Cfg.SMTPHost, config.Cfg.SMTPPort,
+		config.Cfg.SMTPUser, config.Cfg.SMTPPassword, config.Cfg.MailFrom, config.Cfg.MailDir)
+	notificationSvc := service.NewNotificationService(repo.NewNotificationRepo(db), userRepo, mailer)
+	// メールアドレス確認とパスワード再設定: サインアップ時に確認メールを送り、未確認のユーザーの入札を拒否
+	accountSvc := service.NewAccountService(userRepo, repo.NewUserTokenRepo(db), repo.NewRefreshTokenRepo(db),
+		mailer, config.Cfg.AppURL, config.Cfg.RequireEmailVerification)
+	userSvc.OnSignup(accountSvc.SignupListener)
+	bidSvc.AddGuard(accountSvc.GuardBid)
+	// 二要素認証: ログインの 2 段階目の検証と、高額入札・出品に二要素認証を必須とするポリシー
+	mfaSvc := service.NewMFAService(userRepo, repo.NewRecoveryCodeRepo(db), config.Cfg.MFAIssuer, service.MFAPolicy{
+		BidThreshold:      config.Cfg.MFABidThreshold,
+		RequireForSelling: config.Cfg.MFARequiredForSelling,
+	})
+	userSvc.UseMFA(mfaSvc.Verify)
+	bidSvc.AddGuard(mfaSvc.GuardBid)
+	auctionSvc.AddGuard(mfaSvc.GuardCreateAuction)
+	savedSearchSvc := service.NewSavedSearchService(repo.NewSavedSearchRepo(db), auctionRepo, notificationSvc)
+	auctionSvc.OnCreate(savedSearchSvc.MatchNewAuction)
+	go savedSearchSvc.Run(context.Background())
+	inspectionRepo := repo.NewInspectionRepo(db)
+	conditionReportRepo := repo.NewConditionReportRepo(db)
+	photoSvc := service.NewPhotoService(repo.NewPhotoRepo(db), auctionRepo, inspectionRepo, store)
+	conditionReportSvc := service.NewConditionReportService(conditionReportRepo, auctionRepo, inspectionRepo)
+	inspectionSvc := service.NewInspectionService(inspectionRepo, auctionRepo, conditionReportRepo, userRepo, notificationSvc)
+	auditSvc := service.NewAuditService(repo.NewAuditRepo(db))
+	adminSvc := service.NewAdminService(userRepo, auctionRepo, repo.NewStatsRepo(db), auditSvc, notificationSvc, hub)
+	// ログインの総当たり対策: 失敗が続くアカウント・接続元 IP に待ち時間とロックをかけ、ログインの試行を監査ログに記録
+	var loginAttempts service.LoginAttemptStore
+	switch config.Cfg.LoginAttemptStore {
+	case "memory":
+		loginAttempts = service.NewMemoryLoginAttemptStore()
+	case "db":
+		loginAttempts = repo.NewLoginAttemptRepo(db)
+	default:
+		stdlog.Fatalf("unknown LOGIN_ATTEMPT_STORE %q", config.Cfg.LoginAttemptStore)
+	}
+	loginGuard := service.NewLoginGuard(loginAttempts, service.LoginPolicy{
+		FreeAttempts:         config.Cfg.LoginFreeAttempts,
+		AccountLockThreshold: config.Cfg.LoginAccountLockThreshold,
+		IPLockThreshold:      config.Cfg.LoginIPLockThreshold,
+		LockoutDuration:      config.Cfg.LoginLockoutDuration,
+	}, auditSvc)
+	userSvc.UseLoginGuard(loginGuard)
+	adminSvc.UseLoginGuard(loginGuard)
+	// 本人によるプロフィール・パスワード・メールアドレスの変更とアカウントの削除の申請を監査ログに記録
+	userSvc.UseAudit(auditSvc)
+	accountSvc.UseAudit(auditSvc)
+	// ディーラー組織: 組織メンバーの入札を与信枠・同一組織内の競り合い禁止で検証し、出品を組織に帰属
+	orgSvc := service.NewOrganizationService(repo.NewOrganizationRepo(db), userRepo, auditSvc, config.Cfg.OrgDefaultCreditLimit)
+	bidSvc.AddGuard(orgSvc.GuardBid)
+	auctionSvc.OnCreate(orgSvc.AttributeAuction)
+	// 業者の本人確認 (KYC): 古物商許可証などの書類を管理者が審査し、ポリシーにより未承認のユーザーの入札を拒否
+	verificationSvc := service.NewVerificationService(repo.NewVerificationRepo(db), userRepo, store, auditSvc, notificationSvc,
+		service.VerificationPolicy{
+			RequireForBidding: config.Cfg.KYCRequiredForBidding,
+			BidThreshold:      config.Cfg.KYCBidThreshold,
+		})
+	bidSvc.AddGuard(verificationSvc.GuardBid)
+
+	// 外部の IdP (OpenID Connect) によるログイン: IdP のクレームをユーザー・ロールに対応付け、アプリのトークンを発行
+	var oidcSvc *service.OIDCService
+	if config.Cfg.OIDCIssuer != "" {
+		roleMap, err := service.ParseOIDCRoleMap(config.Cfg.OIDCRoleMap)
+		if err != nil {
+			stdlog.Fatal(err)
+		}
+		if !rbac.ValidRole(config.Cfg.OIDCDefaultRole) {
+			stdlog.Fatalf("unknown OIDC_DEFAULT_ROLE %q", config.Cfg.OIDCDefaultRole)
+		}
+		oidcClient := oidc.New(oidc.Config{
+			Issuer:       config.Cfg.OIDCIssuer,
+			ClientID:     config.Cfg.OIDCClientID,
+			ClientSecret: config.Cfg.OIDCClientSecret,
+			RedirectURL:  config.Cfg.OIDCRedirectURL,
+			Scopes:       config.Cfg.OIDCScopes,
+		}, nil)
+		oidcSvc = service.NewOIDCService(oidcClient, repo.NewUserIdentityRepo(db), userSvc, auditSvc, service.OIDCPolicy{
+			RoleClaim:     config.Cfg.OIDCRoleClaim,
+			RoleMap:       roleMap,
+			AutoProvision: config.Cfg.OIDCAutoProvision,
+			DefaultRole:   config.Cfg.OIDCDefaultRole,
+		})
+	}
+
+	// レート制限: API 全体・アカウント操作・入札のルート群ごとにトークンバケットで制限
+	var rateStore api.RateLimitStore
+	switch config.Cfg.RateLimitStore {
+	case "memory":
+		rateStore = api.NewMemoryRateLimitStore()
+	case "db":
+		rateStore = repo.NewRateLimitRepo(db)
+	default:
+		stdlog.Fatalf("unknown RATE_LIMIT_STORE %q", config.Cfg.RateLimitStore)
+	}
+	api.UseRateLimiter(api.NewRateLimiter(rateStore,
+		api.RateLimitPolicy{Name: api.RateLimitAPI, Limit: config.Cfg.RateLimitAPIPerMinute, Period: time.Minute},
+		api.RateLimitPolicy{Name: api.RateLimitAuth, Limit: config.Cfg.RateLimitAuthPerMinute, Period: time.Minute},
+		api.RateLimitPolicy{Name: api.RateLimitBids, Limit: config.Cfg.RateLimitBidsPerMinute, Period: time.Minute},
+	))
 
 	// 7) トレーシングの初期化
 	shutdown := tracing.Init()
@@ -75,7 +262,7 @@ func main() {
 
 	// 8) ルーター設定
 	r := mux.NewRouter()
-	r.Use(api.CORSMiddleware)
+	r.Use(api.CORSMiddleware, api.RateLimit(api.RateLimitAPI))
 
 	// メトリクスとヘルスチェックのエンドポイント
 	r.Handle("/metrics", promhttp.Handler())
@@ -83,14 +270,30 @@ func main() {
 
 	// ビジネスドメインルートの登録
 	api.RegisterUserRoutes(r, userSvc)
+	api.RegisterAccountRoutes(r, accountSvc)
+	api.RegisterProfileRoutes(r, userSvc)
+	api.RegisterMFARoutes(r, mfaSvc)
+	api.RegisterAPIKeyRoutes(r, apiKeySvc)
+	if oidcSvc != nil {
+		api.RegisterOIDCRoutes(r, oidcSvc)
+	}
 	api.RegisterAuctionRoutes(r, auctionSvc)
 	api.RegisterWSRoutes(r, hub)
 	api.RegisterBidRoutes(r, bidSvc)
+	api.RegisterSavedSearchRoutes(r, savedSearchSvc)
+	api.RegisterNotificationRoutes(r, notificationSvc)
+	api.RegisterPhotoRoutes(r, photoSvc)
+	api.RegisterConditionReportRoutes(r, conditionReportSvc)
+	api.RegisterInspectionRoutes(r, inspectionSvc)
+	api.RegisterAdminRoutes(r, adminSvc)
+	api.RegisterOrganizationRoutes(r, orgSvc)
+	api.RegisterVerificationRoutes(r, verificationSvc)
 
 	// Swagger UI
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 	stdlog.Printf("Swagger UI: http://localhost:%s/swagger/index.html\n", config.Cfg.Port)
 	api.RegisterHealthRoute(r)
+	api.RegisterJWKSRoute(r, signingKeys)
 
 	// トレーシングミドルウェア: 各リクエストに対してスパンを生成
 	r.Use(func(next http.Handler) http.Handler {
@@ -102,10 +305,9 @@ func main() {
 		})
 	})
 
-	// ファイルアップロードおよび静的ファイルのサーブ
-	r.HandleFunc("/upload", api.UploadHandler).Methods("POST")
-	r.PathPrefix("/static/").
-		Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./uploads"))))
+	// ファイルアップロード（写真を登録できる出品者・検査員のみ）および静的ファイルのサーブ
+	r.Handle("/upload", api.AuthMiddleware(api.RequirePermission(rbac.AuctionDocument)(api.UploadHandler(photoSvc)))).Methods("POST")
+	r.PathPrefix("/static/").Handler(api.StaticHandler(store, signer)).Methods("GET", "HEAD")
 
 	// 9) サーバ起動
 	addr := ":" + config.Cfg.Port
@@ -5,6 +5,12 @@ import AuctionList from './pages/AuctionList'
 import AuctionDetail from './pages/AuctionDetail'
 import NotFound from './pages/NotFound'
 import AuctionCreate from "./pages/AuctionCreate"
+import VerifyEmail from './pages/VerifyEmail'
+import ForgotPassword from './pages/ForgotPassword'
+import ResetPassword from './pages/ResetPassword'
+import OIDCCallback from './pages/OIDCCallback'
+import ConfirmEmail from './pages/ConfirmEmail'
+import Profile from './pages/Profile'
 
 export default function App() {
   return (
@@ -14,6 +20,12 @@ export default function App() {
         <Route path="/" element={<Navigate to="/login" replace />} />
         <Route path="/signup" element={<Signup />} />
         <Route path="/login" element={<Login />} />
+        <Route path="/verify-email" element={<VerifyEmail />} />
+        <Route path="/forgot-password" element={<ForgotPassword />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/oidc/callback" element={<OIDCCallback />} />
+        <Route path="/confirm-email" element={<ConfirmEmail />} />
+        <Route path="/profile" element={<Profile />} />
         <Route path="/auctions/create" element={<AuctionCreate />} />
         <Route path="/auctions" element={<AuctionList />} />
         <Route path="/auctions/:id" element={<AuctionDetail />} />
@@ -9,8 +9,10 @@ export default function AuctionCreate() {
   const [maker, setMaker]         = useState('')
   const [modelName, setModelName] = useState('')
   const [mileage, setMileage]     = useState(0)
+  const [vehicleId, setVehicleId] = useState('')
   const [year, setYear]           = useState(2020)
   const [photoUrl, setPhotoUrl]      = useState('')
+  const [photoKey, setPhotoKey]      = useState('')
   const [endAt, setEndAt]         = useState('')
   const navigate                  = useNavigate()
 
@@ -22,10 +24,11 @@ export default function AuctionCreate() {
   form.append('file', file)
 
   try {
-    const res = await api.post<{ url: string }>('/api/upload', form, {
+    const res = await api.post<{ url: string, key: string }>('/api/upload', form, {
       headers: { 'Content-Type': 'multipart/form-data' }
     })
     setPhotoUrl(res.data.url)
+    setPhotoKey(res.data.key)
   } catch {
     alert('写真のアップロードに失敗しました。')
   }
@@ -41,8 +44,12 @@ export default function AuctionCreate() {
         maker,
         model_name: modelName,
         mileage,
+        // 17 桁なら VIN、それ以外（例: ZVW30-1234567）は国内の車台番号として送信
+        ...(/^[A-Za-z0-9]{17}$/.test(vehicleId.trim())
+          ? { vin: vehicleId.trim() }
+          : { chassis_number: vehicleId.trim() }),
         year,
-        photo_url: photoUrl,
+        photo_key: photoKey,
         end_at: new Date(endAt).toISOString(),
       })
       navigate(`/auctions/${res.data.id}`)
@@ -96,6 +103,17 @@ export default function AuctionCreate() {
           />
         </div>
 
+        <div>
+          <label className="block mb-1 font-medium">車台番号 (VIN または 型式-番号, 任意)</label>
+          <input
+            type="text"
+            placeholder="ex: ZVW30-1234567"
+            value={vehicleId}
+            onChange={e => setVehicleId(e.target.value)}
+            className="w-full p-2 border rounded"
+          />
+        </div>
+
         <div className="grid grid-cols-2 gap-4">
           <div>
             <label className="block mb-1 font-medium">メーカー名</label>
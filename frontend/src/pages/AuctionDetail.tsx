@@ -1,7 +1,7 @@
 import { useState, useEffect, useRef } from 'react'
 import { useParams, useNavigate } from 'react-router-dom'
 import { getAuction, listBids, placeBid } from '../services/api'
-import type { Auction, Bid } from '../services/api'
+import type { Auction, Bid, Viewers } from '../services/api'
 
 export default function AuctionDetail() {
   const { id } = useParams<{id: string}>()
@@ -13,19 +13,21 @@ export default function AuctionDetail() {
   const [size] = useState(10)
   const [amount, setAmount] = useState(0)
   const [loading, setLoading] = useState(true)
-  const [error, setError] = useState<string | null>(null)
+  const [error, setError] = useState<string[]>([])
 
-  const [role, setRole] = useState<string | null>(null)
+  const [viewers, setViewers] = useState<Viewers | null>(null)
+
+  const [roles, setRoles] = useState<string[]>([])
   const [currentUserId, setCurrentUserId] = useState<number | null>(null)
   useEffect(() => {
     const token = localStorage.getItem('token')
     if (token) {
       try {
         const payload = JSON.parse(atob(token.split('.')[1]))
-        setRole(payload.role)
+        setRoles(payload.roles ?? (payload.role ? [payload.role] : []))
         setCurrentUserId(payload.user_id)
       } catch {
-        setRole(null)
+        setRoles([])
         setCurrentUserId(null)
       }
     }
@@ -35,7 +37,10 @@ export default function AuctionDetail() {
     if (!id) return
     setLoading(true)
     getAuction(+id)
-      .then(res => setAuction(res.data))
+      .then(res => {
+        setAuction(res.data)
+        setViewers(res.data.viewers ?? null)
+      })
       .catch(err => setError(err.message))
     listBids(+id, page, size)
       .then(res => setBids(res.data.data))
@@ -52,10 +57,16 @@ export default function AuctionDetail() {
   useEffect(() => {
     if (!id || currentUserId == null) return
     const protocol = window.location.protocol === 'https:' ? 'wss' : 'ws'
-    const wsUrl = `${protocol}://${window.location.host}/ws/auctions/${id}`
+    const token = localStorage.getItem('token')
+    const query = token ? `?token=${encodeURIComponent(token)}` : ''
+    const wsUrl = `${protocol}://${window.location.host}/ws/auctions/${id}${query}`
     const socket = new WebSocket(wsUrl)
     socket.onmessage = e => {
-      const ev: { bid: Bid; new_end_at?: string } = JSON.parse(e.data)
+      const ev = JSON.parse(e.data)
+      if (ev.type === 'viewer_count') {
+        setViewers(ev as Viewers)
+        return
+      }
       if (ev.bid.user_id !== currentUserId) {
         setBids(prev => [ev.bid, ...prev])
       }
@@ -91,9 +102,14 @@ export default function AuctionDetail() {
       <button onClick={() => navigate(-1)} className="text-sm underline">← バック</button>
 
     <h1 className="text-3xl font-bold">{auction.title}</h1>
+    {viewers && (
+      <p className="text-sm text-gray-500">
+        閲覧中: {viewers.viewers}人（ログイン中 {viewers.users}人 / 匿名 {viewers.anonymous}人）
+      </p>
+    )}
 
     {auction.photo_url
-      ? <img src={`http://localhost:8080${auction.photo_url}`} alt={auction.title} className="max-w-[400px] max-h-[300px] w-auto h-auto object-contain rounded shadow" />
+      ? <img src={auction.photo_url.startsWith('/') ? `http://localhost:8080${auction.photo_url}` : auction.photo_url} alt={auction.title} className="max-w-[400px] max-h-[300px] w-auto h-auto object-contain rounded shadow" />
       : <div className="w-full h-64 bg-gray-200 flex items-center justify-center rounded">写真なし</div>
     }
 
@@ -122,7 +138,7 @@ export default function AuctionDetail() {
       </li>
     </ul>
 
-      {role === 'bidder' && (
+      {roles.includes('bidder') && (
         <div className="flex items-center space-x-2">
           <input
             type="number"
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react'
-import { listAuctions, deleteAuction } from '../services/api'
+import { listAuctions, deleteAuction, logout } from '../services/api'
 import type { Auction } from '../services/api'
 import { useNavigate } from 'react-router-dom'
 
@@ -7,7 +7,7 @@ export default function AuctionList() {
   const [auctions, setAuctions] = useState<Auction[]>([])
   const [loading,  setLoading]  = useState(true)
   const [error,    setError]    = useState<string|null>(null)
-  const [role,     setRole]     = useState<string|null>(null)
+  const [roles,    setRoles]    = useState<string[]>([])
   const [currentUserId, setUserId] = useState<number|null>(null)
   const navigate = useNavigate()
 
@@ -17,10 +17,10 @@ export default function AuctionList() {
       try {
         const payload = JSON.parse(atob(token.split('.')[1]))
         setUserId(payload.user_id)
-        setRole(payload.role)
+        setRoles(payload.roles ?? (payload.role ? [payload.role] : []))
       } catch {
         setUserId(null)
-        setRole(null)
+        setRoles([])
       }
     }
   }, [])
@@ -49,8 +49,8 @@ export default function AuctionList() {
     }
   }
   
-  const handleLogout = () => {
-    localStorage.removeItem('token')
+  const handleLogout = async () => {
+    await logout()
     navigate('/login')
   }
 
@@ -63,7 +63,7 @@ export default function AuctionList() {
       <div className="flex justify-between items-center mb-6">
         <h1 className="text-2xl font-bold">オークションリスト</h1>
         <div className="flex space-x-2">
-          {role === 'seller' && (
+          {roles.includes('seller') && (
             <button
               onClick={() => navigate('/auctions/create')}
               className="px-4 py-2 bg-green-600 text-white rounded hover:bg-green-700 transition"
@@ -71,6 +71,12 @@ export default function AuctionList() {
               出品登録
             </button>
           )}
+          <button
+            onClick={() => navigate('/profile')}
+            className="px-4 py-2 border rounded hover:bg-gray-100 transition"
+          >
+            プロフィール
+          </button>
           <button
             onClick={handleLogout}
             className="px-4 py-2 bg-red-600 text-white rounded hover:bg-red-700 transition"
@@ -96,7 +102,7 @@ export default function AuctionList() {
               </p>
             </div>
 
-            {role === 'seller' && currentUserId === a.seller_id && (
+            {roles.includes('seller') && currentUserId === a.seller_id && (
               <button
                 onClick={e => {
                   e.stopPropagation()
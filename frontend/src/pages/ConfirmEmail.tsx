@@ -0,0 +1,28 @@
+import { useEffect, useRef, useState } from 'react'
+import { Link, useSearchParams } from 'react-router-dom'
+import { confirmEmailChange } from '../services/api'
+
+export default function ConfirmEmail() {
+  const [params] = useSearchParams()
+  const [status, setStatus] = useState<'pending' | 'done' | 'error'>('pending')
+  // トークンは一回限りのため、StrictMode で effect が 2 回実行されても 1 度だけ送信
+  const sent = useRef(false)
+
+  useEffect(() => {
+    if (sent.current) return
+    sent.current = true
+    confirmEmailChange(params.get('token') ?? '')
+      .then(() => setStatus('done'))
+      .catch(() => setStatus('error'))
+  }, [params])
+
+  return (
+    <div className="max-w-sm mx-auto p-4 space-y-4">
+      <h1 className="text-2xl mb-4">メールアドレスの変更</h1>
+      {status === 'pending' && <p>確認中…</p>}
+      {status === 'done' && <p>メールアドレスを変更しました。次回から新しいメールアドレスでログインしてください。</p>}
+      {status === 'error' && <p className="text-red-600">リンクが無効か期限切れ、またはメールアドレスが既に使われています。</p>}
+      <Link to="/login" className="text-blue-600 underline">ログインへ</Link>
+    </div>
+  )
+}
@@ -0,0 +1,41 @@
+import { useState } from 'react'
+import { Link } from 'react-router-dom'
+import { forgotPassword } from '../services/api'
+
+export default function ForgotPassword() {
+  const [email, setEmail] = useState('')
+  const [sent, setSent]   = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      await forgotPassword(email)
+      setSent(true)
+    } catch (err) {
+      console.error(err)
+      alert('送信に失敗しました。')
+    }
+  }
+
+  if (sent) {
+    return (
+      <div className="max-w-sm mx-auto p-4 space-y-4">
+        <p>登録されているメールアドレスの場合、パスワード再設定のリンクを送信しました。</p>
+        <Link to="/login" className="text-blue-600 underline">ログインへ</Link>
+      </div>
+    )
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className="max-w-sm mx-auto p-4 space-y-4">
+      <h1 className="text-2xl mb-4">パスワードの再設定</h1>
+      <input
+        type="email" value={email}
+        onChange={e=>setEmail(e.target.value)}
+        placeholder="Email"
+        className="w-full p-2 border mb-4"
+      />
+      <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">再設定メールを送信</button>
+    </form>
+  )
+}
@@ -1,6 +1,8 @@
 import { useState } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { login } from '../services/api'
+import axios from 'axios'
+import { login, loginMFA, oidcEnabled, oidcLoginURL, saveSession } from '../services/api'
+import type { AuthResponse } from '../services/api'
 
 export default function Login() {
   const [email, setEmail]         = useState('')
@@ -11,8 +13,16 @@ export default function Login() {
     e.preventDefault()
     try {
       const res = await login(email, password)
-      const { token, role } = res.data
-      localStorage.setItem('token', token)
+      let auth: AuthResponse
+      if ('mfa_required' in res.data) {
+        const code = window.prompt('認証アプリのコード（またはリカバリーコード）を入力してください')
+        if (!code) return
+        auth = (await loginMFA(res.data.mfa_token, code)).data
+      } else {
+        auth = res.data
+      }
+      const { role } = auth
+      saveSession(auth)
       if (role === 'seller') {
         navigate('/auctions')
       } else {
@@ -20,6 +30,11 @@ export default function Login() {
       }
     } catch (err) {
       console.error(err)
+      if (axios.isAxiosError(err) && err.response?.status === 429) {
+        const retry = err.response.headers['retry-after']
+        alert(`ログインの失敗が続いたため、一時的にログインできません。${retry ? `${retry} 秒後に` : 'しばらくしてから'}再度お試しください。`)
+        return
+      }
       alert('ログインに失敗しました。 メールアドレスとパスワードを確認してください。')
     }
   }
@@ -40,6 +55,15 @@ export default function Login() {
         className="w-full p-2 border mb-4"
       />
       <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">ログイン</button>
+      {oidcEnabled && (
+        <button
+          type="button"
+          onClick={() => { window.location.href = oidcLoginURL }}
+          className="w-full py-2 border border-blue-500 text-blue-600 rounded"
+        >
+          社内アカウントでログイン
+        </button>
+      )}
       <div className="mt-4 text-center">
        <button
          type="button"
@@ -49,6 +73,11 @@ export default function Login() {
          会員登録
        </button>
      </div>
+      <div className="text-center">
+        <button type="button" onClick={() => navigate('/forgot-password')} className="text-sm text-blue-600 underline">
+          パスワードをお忘れの方
+        </button>
+      </div>
     </form>
   )
 }
@@ -0,0 +1,47 @@
+import { useEffect, useRef, useState } from 'react'
+import { Link, useNavigate } from 'react-router-dom'
+import { loginMFA, saveSession } from '../services/api'
+
+// 社内 IdP でのログイン後、API から URL のフラグメント (#token=...) でトークンを受け取ります
+export default function OIDCCallback() {
+  const navigate = useNavigate()
+  const [error, setError] = useState(false)
+  // StrictMode で effect が 2 回実行されても 1 度だけ処理
+  const done = useRef(false)
+
+  useEffect(() => {
+    if (done.current) return
+    done.current = true
+    const params = new URLSearchParams(window.location.hash.slice(1))
+    // トークンを履歴に残さない
+    window.history.replaceState(null, '', window.location.pathname)
+    const finish = async () => {
+      const mfaToken = params.get('mfa_token')
+      if (mfaToken) {
+        const code = window.prompt('認証アプリのコード（またはリカバリーコード）を入力してください')
+        if (!code) throw new Error('mfa cancelled')
+        saveSession((await loginMFA(mfaToken, code)).data)
+      } else {
+        const token = params.get('token')
+        const refreshToken = params.get('refresh_token')
+        if (!token || !refreshToken) throw new Error('missing token')
+        saveSession({ token, refresh_token: refreshToken })
+      }
+      navigate('/auctions', { replace: true })
+    }
+    finish().catch(err => {
+      console.error(err)
+      setError(true)
+    })
+  }, [navigate])
+
+  return (
+    <div className="max-w-sm mx-auto p-4 space-y-4">
+      <h1 className="text-2xl mb-4">社内アカウントでログイン</h1>
+      {error
+        ? <p className="text-red-600">ログインに失敗しました。もう一度お試しください。</p>
+        : <p>ログイン中…</p>}
+      {error && <Link to="/login" className="text-blue-600 underline">ログインへ</Link>}
+    </div>
+  )
+}
@@ -0,0 +1,258 @@
+import { useEffect, useState } from 'react'
+import { useNavigate } from 'react-router-dom'
+import axios from 'axios'
+import {
+  cancelDeletion, changePassword, deleteVerificationDocument, getMe, getVerification, requestDeletion,
+  requestEmailChange, saveSession, submitVerification, updateProfile, uploadVerificationDocument,
+} from '../services/api'
+import type {
+  DealerVerification, Profile as ProfileData, ProfileUpdate, VerificationDocumentKind, VerificationSubmit,
+} from '../services/api'
+
+const verificationStatusLabels = {
+  draft: '未提出', pending: '審査中', approved: '承認済み', rejected: '却下', revoked: '承認取り消し',
+}
+
+const documentKindLabels: Record<VerificationDocumentKind, string> = {
+  antique_dealer_licence: '古物商許可証', company_registration: '登記事項証明書・開業届', identity: '代表者の本人確認書類',
+}
+
+// errorMessage は API のエラー本文（http.Error のメッセージ）を取り出します
+function errorMessage(err: unknown) {
+  if (axios.isAxiosError(err) && typeof err.response?.data === 'string') return err.response.data.trim()
+  return String(err)
+}
+
+export default function Profile() {
+  const [me, setMe]               = useState<ProfileData | null>(null)
+  const [form, setForm]           = useState<ProfileUpdate>({})
+  const [current, setCurrent]     = useState('')
+  const [password, setPassword]   = useState('')
+  const [newEmail, setNewEmail]   = useState('')
+  const [kyc, setKyc]             = useState<DealerVerification | null>(null)
+  const [docKind, setDocKind]     = useState<VerificationDocumentKind>('antique_dealer_licence')
+  const [kycForm, setKycForm]     = useState<VerificationSubmit>({ company_name: '', licence_number: '' })
+  const navigate                  = useNavigate()
+
+  const load = (p: ProfileData) => {
+    setMe(p)
+    setForm({
+      display_name: p.display_name, phone: p.phone, address: p.address,
+      company: p.company, language: p.language, currency: p.currency,
+    })
+  }
+
+  useEffect(() => {
+    getMe().then(res => load(res.data)).catch(() => navigate('/login'))
+    // 申請が無い場合は 404
+    getVerification().then(res => setKyc(res.data)).catch(() => setKyc(null))
+  }, [navigate])
+
+  if (!me) return <div className="p-4">ローディング中…</div>
+
+  const field = (key: 'display_name' | 'phone' | 'address' | 'company', label: string) => (
+    <label className="block">
+      <span className="text-sm">{label}</span>
+      <input
+        value={form[key] ?? ''}
+        onChange={e => setForm({ ...form, [key]: e.target.value })}
+        className="w-full p-2 border"
+      />
+    </label>
+  )
+
+  const handleSave = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      load((await updateProfile(form)).data)
+      alert('プロフィールを保存しました。')
+    } catch (err) {
+      alert('保存できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const handlePassword = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      saveSession((await changePassword(current, password)).data)
+      setCurrent('')
+      setPassword('')
+      alert('パスワードを変更しました。他の端末はログアウトされました。')
+    } catch (err) {
+      alert('変更できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const handleEmail = async (e: React.FormEvent) => {
+    e.preventDefault()
+    const pw = window.prompt('現在のパスワードを入力してください')
+    if (!pw) return
+    try {
+      await requestEmailChange(pw, newEmail)
+      alert(`${newEmail} に確認メールを送りました。メールのリンクから変更を完了してください。`)
+      setNewEmail('')
+    } catch (err) {
+      alert('申請できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const handleDeletion = async () => {
+    try {
+      if (me.deletion_requested_at) {
+        load((await cancelDeletion()).data)
+        return
+      }
+      const pw = window.prompt('アカウントの削除を申請します。現在のパスワードを入力してください')
+      if (!pw) return
+      const reason = window.prompt('削除の理由（任意）') ?? ''
+      load((await requestDeletion(pw, reason)).data)
+    } catch (err) {
+      alert('処理できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  // 却下・取り消し後の添付は新しい申請になるため、添付のたびに最新の申請を読み直す
+  const handleDocument = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.currentTarget.files?.[0]
+    e.currentTarget.value = ''
+    if (!file) return
+    try {
+      await uploadVerificationDocument(docKind, file)
+      setKyc((await getVerification()).data)
+    } catch (err) {
+      alert('書類を添付できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const handleRemoveDocument = async (id: number) => {
+    try {
+      await deleteVerificationDocument(id)
+      setKyc((await getVerification()).data)
+    } catch (err) {
+      alert('削除できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const handleSubmitVerification = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      setKyc((await submitVerification(kycForm)).data)
+      alert('本人確認を申請しました。審査の結果は通知でお知らせします。')
+    } catch (err) {
+      alert('申請できませんでした: ' + errorMessage(err))
+    }
+  }
+
+  const editable = !kyc || kyc.status === 'draft' || kyc.status === 'rejected' || kyc.status === 'revoked'
+  const drafting = kyc?.status === 'draft'
+
+  return (
+    <div className="max-w-md mx-auto p-4 space-y-8">
+      <div className="flex justify-between items-center">
+        <h1 className="text-2xl">プロフィール</h1>
+        <button type="button" onClick={() => navigate('/auctions')} className="text-blue-600 underline">戻る</button>
+      </div>
+      <p className="text-sm">
+        {me.email}（{me.email_verified ? '確認済み' : '未確認'}）/ ロール: {me.roles.join(', ')}
+      </p>
+
+      <form onSubmit={handleSave} className="space-y-2">
+        {field('display_name', '表示名')}
+        {field('phone', '電話番号')}
+        {field('address', '住所')}
+        {field('company', '会社名')}
+        <div className="flex space-x-2">
+          <select
+            value={form.language}
+            onChange={e => setForm({ ...form, language: e.target.value as ProfileData['language'] })}
+            className="p-2 border"
+          >
+            <option value="ja">日本語</option>
+            <option value="en">English</option>
+            <option value="ko">한국어</option>
+          </select>
+          <select
+            value={form.currency}
+            onChange={e => setForm({ ...form, currency: e.target.value as ProfileData['currency'] })}
+            className="p-2 border"
+          >
+            {['JPY', 'USD', 'EUR', 'KRW'].map(c => <option key={c} value={c}>{c}</option>)}
+          </select>
+        </div>
+        <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">保存</button>
+      </form>
+
+      <form onSubmit={handlePassword} className="space-y-2">
+        <h2 className="text-lg">パスワードの変更</h2>
+        <input type="password" value={current} onChange={e => setCurrent(e.target.value)}
+          placeholder="現在のパスワード" className="w-full p-2 border" />
+        <input type="password" value={password} onChange={e => setPassword(e.target.value)}
+          placeholder="新しいパスワード (8 文字以上)" className="w-full p-2 border" />
+        <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">変更</button>
+      </form>
+
+      <form onSubmit={handleEmail} className="space-y-2">
+        <h2 className="text-lg">メールアドレスの変更</h2>
+        <input type="email" value={newEmail} onChange={e => setNewEmail(e.target.value)}
+          placeholder="新しいメールアドレス" className="w-full p-2 border" />
+        <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">確認メールを送る</button>
+      </form>
+
+      <div className="space-y-2">
+        <h2 className="text-lg">業者の本人確認</h2>
+        <p className="text-sm">
+          状態: {kyc ? verificationStatusLabels[kyc.status] : '未申請'}
+          {me.dealer_verified_at && `（${new Date(me.dealer_verified_at).toLocaleDateString()} 承認）`}
+        </p>
+        {kyc?.review_reason && <p className="text-sm text-red-600">理由: {kyc.review_reason}</p>}
+        {kyc?.status === 'draft' && (
+          <ul className="text-sm list-disc pl-5">
+            {kyc.documents.map(d => (
+              <li key={d.id}>
+                <a href={d.url} target="_blank" rel="noreferrer" className="text-blue-600 underline">
+                  {documentKindLabels[d.kind]}
+                </a>
+                <button type="button" onClick={() => handleRemoveDocument(d.id)} className="ml-2 text-red-600">削除</button>
+              </li>
+            ))}
+          </ul>
+        )}
+        {editable && (
+          <div className="flex space-x-2">
+            <select
+              value={docKind}
+              onChange={e => setDocKind(e.target.value as VerificationDocumentKind)}
+              className="p-2 border"
+            >
+              {Object.entries(documentKindLabels).map(([k, label]) => <option key={k} value={k}>{label}</option>)}
+            </select>
+            <input type="file" accept="image/jpeg,image/png,image/webp,application/pdf" onChange={handleDocument} />
+          </div>
+        )}
+        {drafting && (
+          <form onSubmit={handleSubmitVerification} className="space-y-2">
+            <input value={kycForm.company_name} onChange={e => setKycForm({ ...kycForm, company_name: e.target.value })}
+              placeholder="商号（屋号）" className="w-full p-2 border" />
+            <input value={kycForm.licence_number} onChange={e => setKycForm({ ...kycForm, licence_number: e.target.value })}
+              placeholder="古物商許可番号 (12 桁)" className="w-full p-2 border" />
+            <input value={kycForm.corporate_number ?? ''} onChange={e => setKycForm({ ...kycForm, corporate_number: e.target.value })}
+              placeholder="法人番号 (13 桁, 個人事業主は空欄)" className="w-full p-2 border" />
+            <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">審査を申請する</button>
+          </form>
+        )}
+      </div>
+
+      <div className="space-y-2">
+        <h2 className="text-lg">アカウントの削除</h2>
+        {me.deletion_requested_at && (
+          <p className="text-sm text-red-600">
+            {new Date(me.deletion_requested_at).toLocaleString()} に削除を申請しました。管理者の確認後に削除されます。
+          </p>
+        )}
+        <button type="button" onClick={handleDeletion} className="w-full py-2 bg-red-600 text-white rounded">
+          {me.deletion_requested_at ? '削除の申請を取り消す' : '削除を申請する'}
+        </button>
+      </div>
+    </div>
+  )
+}
@@ -0,0 +1,34 @@
+import { useState } from 'react'
+import { useNavigate, useSearchParams } from 'react-router-dom'
+import { resetPassword } from '../services/api'
+
+export default function ResetPassword() {
+  const [params]              = useSearchParams()
+  const [password, setPassword] = useState('')
+  const navigate              = useNavigate()
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      await resetPassword(params.get('token') ?? '', password)
+      alert('パスワードを変更しました。新しいパスワードでログインしてください。')
+      navigate('/login')
+    } catch (err) {
+      console.error(err)
+      alert('リンクが無効か期限切れ、またはパスワードが短すぎます（8 文字以上）。')
+    }
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className="max-w-sm mx-auto p-4 space-y-4">
+      <h1 className="text-2xl mb-4">新しいパスワード</h1>
+      <input
+        type="password" value={password}
+        onChange={e=>setPassword(e.target.value)}
+        placeholder="Password (8 文字以上)"
+        className="w-full p-2 border mb-4"
+      />
+      <button type="submit" className="w-full py-2 bg-blue-500 text-white rounded">変更</button>
+    </form>
+  )
+}
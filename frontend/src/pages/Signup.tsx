@@ -5,14 +5,16 @@ import { useNavigate } from 'react-router-dom'
 export default function Signup() {
   const [email, setEmail]     = useState('')
   const [password, setPassword] = useState('')
-  const [role, setRole]         = useState<'bidder'|'seller'>('bidder')
+  const [roles, setRoles]       = useState<Array<'bidder'|'seller'>>(['bidder'])
+  const toggleRole = (r: 'bidder'|'seller') =>
+    setRoles(prev => prev.includes(r) ? prev.filter(x => x !== r) : [...prev, r])
   const navigate = useNavigate()
   const [error, setError]       = useState<string|null>(null)
 
    const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     try {
-      await signup({ email, password, role })
+      await signup({ email, password, roles })
       navigate("/login")
     } catch (err: any) {
       setError("登録されているメールアドレスです。")
@@ -40,25 +42,25 @@ export default function Signup() {
         className="w-full p-2 border rounded"
       />
       <fieldset className="mb-4">
-        <legend className="mb-1">役割</legend>
+        <legend className="mb-1">役割（複数選択可）</legend>
         <label className="mr-4">
           <input
-            type="radio"
+            type="checkbox"
             value="bidder"
-            checked={role==="bidder"}
-            onChange={()=>setRole("bidder")}
+            checked={roles.includes("bidder")}
+            onChange={()=>toggleRole("bidder")}
           /> 入札者
         </label>
-        <label>
+        <label className="mr-4">
           <input
-            type="radio"
+            type="checkbox"
             value="seller"
-            checked={role==="seller"}
-            onChange={()=>setRole("seller")}
+            checked={roles.includes("seller")}
+            onChange={()=>toggleRole("seller")}
           /> 出品者
         </label>
       </fieldset>
-      <button type="submit" className="w-full py-2 bg-green-500 text-white rounded">
+      <button type="submit" disabled={roles.length === 0} className="w-full py-2 bg-green-500 text-white rounded">
         登録する
       </button>
       <button
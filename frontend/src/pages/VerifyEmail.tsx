@@ -0,0 +1,28 @@
+import { useEffect, useRef, useState } from 'react'
+import { Link, useSearchParams } from 'react-router-dom'
+import { verifyEmail } from '../services/api'
+
+export default function VerifyEmail() {
+  const [params] = useSearchParams()
+  const [status, setStatus] = useState<'pending' | 'done' | 'error'>('pending')
+  // トークンは一回限りのため、StrictMode で effect が 2 回実行されても 1 度だけ送信
+  const sent = useRef(false)
+
+  useEffect(() => {
+    if (sent.current) return
+    sent.current = true
+    verifyEmail(params.get('token') ?? '')
+      .then(() => setStatus('done'))
+      .catch(() => setStatus('error'))
+  }, [params])
+
+  return (
+    <div className="max-w-sm mx-auto p-4 space-y-4">
+      <h1 className="text-2xl mb-4">メールアドレスの確認</h1>
+      {status === 'pending' && <p>確認中…</p>}
+      {status === 'done' && <p>メールアドレスの確認が完了しました。入札できるようになりました。</p>}
+      {status === 'error' && <p className="text-red-600">リンクが無効か期限切れです。確認メールを再送してください。</p>}
+      <Link to="/login" className="text-blue-600 underline">ログインへ</Link>
+    </div>
+  )
+}
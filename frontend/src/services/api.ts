@@ -23,9 +23,40 @@ api.interceptors.request.use((config: InternalAxiosRequestConfig) => {
   return config
 })
 
+// アクセストークンの期限切れ (401) ではリフレッシュトークンで再発行して 1 度だけ再試行
+api.interceptors.response.use(undefined, async (error) => {
+  const config = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined
+  const refreshToken = localStorage.getItem('refresh_token')
+  if (error.response?.status !== 401 || !config || config._retried || !refreshToken
+    || config.url?.startsWith('/api/users/')) {
+    return Promise.reject(error)
+  }
+  config._retried = true
+  try {
+    const res = await api.post<AuthResponse>('/api/users/refresh', { refresh_token: refreshToken })
+    saveSession(res.data)
+  } catch {
+    clearSession()
+    return Promise.reject(error)
+  }
+  return api(config)
+})
+
+export type Role = 'bidder' | 'seller' | 'inspector' | 'admin'
+
+// 二要素認証が有効なアカウントは mfa_required と mfa_token のみを返し、/users/login/mfa でログインを完了
+export interface MFAChallenge {
+  mfa_required: true
+  mfa_token: string
+}
+
 export interface AuthResponse {
   token: string
-  role: 'seller' | 'bidder'
+  refresh_token: string
+  expires_in: number
+  role: Role
+  roles: Role[]
+  permissions: string[]
 }
 
 export interface LoginRequest {
@@ -36,7 +67,7 @@ export interface LoginRequest {
 export interface SignupRequest {
   email: string
   password: string
-  role: 'bidder' | 'seller'
+  roles: Array<'bidder' | 'seller'>
 }
 
 export interface Auction {
@@ -52,6 +83,14 @@ export interface Auction {
   year: number         
   photo_url: string
   seller_id: number
+  viewers?: Viewers
+}
+
+export interface Viewers {
+  viewers: number
+  users: number
+  bidders: number
+  anonymous: number
 }
 
 export interface Bid {
@@ -73,7 +112,140 @@ export const signup = (req: SignupRequest) =>
   api.post<AuthResponse>('/api/users/signup', req)
 
 export const login = (email: string, password: string) =>
-  api.post<AuthResponse>('/api/users/login', { email, password })
+  api.post<AuthResponse | MFAChallenge>('/api/users/login', { email, password })
+
+export const loginMFA = (mfaToken: string, code: string) =>
+  api.post<AuthResponse>('/api/users/login/mfa', { mfa_token: mfaToken, code })
+
+// oidcLoginURL は社内 IdP (OpenID Connect) でのログインを開始する URL です（ページ遷移で開きます）
+// API 側で OIDC_ISSUER を設定した場合のみ VITE_OIDC_ENABLED=true としてボタンを表示します
+export const oidcEnabled = import.meta.env.VITE_OIDC_ENABLED === 'true'
+export const oidcLoginURL = `${BASE_URL ?? ''}/api/auth/oidc/login`
+
+export function saveSession(res: Pick<AuthResponse, 'token' | 'refresh_token'>) {
+  localStorage.setItem('token', res.token)
+  localStorage.setItem('refresh_token', res.refresh_token)
+}
+
+export function clearSession() {
+  localStorage.removeItem('token')
+  localStorage.removeItem('refresh_token')
+}
+
+export const verifyEmail = (token: string) =>
+  api.post('/api/users/verify-email', { token })
+
+export const resendVerification = () =>
+  api.post('/api/users/me/verify-email')
+
+export const forgotPassword = (email: string) =>
+  api.post('/api/users/forgot-password', { email })
+
+export const resetPassword = (token: string, password: string) =>
+  api.post('/api/users/reset-password', { token, password })
+
+// Profile は GET /users/me のユーザー情報です（ロール・権限はサーバーの現在の値）
+export interface Profile {
+  id: number
+  email: string
+  role: Role
+  roles: Role[]
+  permissions: string[]
+  display_name: string
+  phone: string
+  address: string
+  company: string
+  language: 'ja' | 'en' | 'ko'
+  currency: 'JPY' | 'USD' | 'EUR' | 'KRW'
+  email_verified: boolean
+  mfa_enabled: boolean
+  deletion_requested_at?: string
+  dealer_verified_at?: string
+}
+
+export type ProfileUpdate = Partial<Pick<Profile,
+  'display_name' | 'phone' | 'address' | 'company' | 'language' | 'currency'>>
+
+export const getMe = () =>
+  api.get<Profile>('/api/users/me')
+
+export const updateProfile = (req: ProfileUpdate) =>
+  api.patch<Profile>('/api/users/me', req)
+
+// 他の端末はログアウトされ、この端末には新しいトークンが返るため saveSession で保存し直す
+export const changePassword = (currentPassword: string, password: string) =>
+  api.post<AuthResponse>('/api/users/me/password', { current_password: currentPassword, password })
+
+// 新しいアドレスに届く確認メールのリンク (/confirm-email) で変更が完了
+export const requestEmailChange = (password: string, email: string) =>
+  api.post('/api/users/me/email', { password, email })
+
+export const confirmEmailChange = (token: string) =>
+  api.post('/api/users/confirm-email', { token })
+
+export const requestDeletion = (password: string, reason: string) =>
+  api.post<Profile>('/api/users/me/deletion', { password, reason })
+
+export const cancelDeletion = () =>
+  api.delete<Profile>('/api/users/me/deletion')
+
+// 業者の本人確認 (KYC): 古物商許可証・登記事項証明書を添付して提出し、管理者の審査を受ける
+export type VerificationStatus = 'draft' | 'pending' | 'approved' | 'rejected' | 'revoked'
+export type VerificationDocumentKind = 'antique_dealer_licence' | 'company_registration' | 'identity'
+
+export interface VerificationDocument {
+  id: number
+  kind: VerificationDocumentKind
+  content_type: string
+  url: string
+}
+
+export interface DealerVerification {
+  id: number
+  status: VerificationStatus
+  company_name: string
+  licence_number: string
+  corporate_number?: string
+  documents: VerificationDocument[]
+  review_reason?: string
+  submitted_at?: string
+  reviewed_at?: string
+}
+
+export interface VerificationSubmit {
+  company_name: string
+  licence_number: string
+  corporate_number?: string
+}
+
+// 申請がまだ無ければ 404
+export const getVerification = () =>
+  api.get<DealerVerification>('/api/users/me/verification')
+
+// 画像または PDF（却下・取り消し後に添付すると新しい申請になる）
+export const uploadVerificationDocument = (kind: VerificationDocumentKind, file: File) => {
+  const data = new FormData()
+  data.append('kind', kind)
+  data.append('file', file)
+  return api.post<VerificationDocument>('/api/users/me/verification/documents', data, {
+    headers: { 'Content-Type': 'multipart/form-data' }
+  })
+}
+
+export const deleteVerificationDocument = (id: number) =>
+  api.delete(`/api/users/me/verification/documents/${id}`)
+
+export const submitVerification = (req: VerificationSubmit) =>
+  api.post<DealerVerification>('/api/users/me/verification/submit', req)
+
+// all=true で全端末からログアウト
+export async function logout(all = false) {
+  const refreshToken = localStorage.getItem('refresh_token')
+  clearSession()
+  if (refreshToken) {
+    await api.post('/api/users/logout', { refresh_token: refreshToken, all }).catch(() => {})
+  }
+}
 
 export const listAuctions = (
   page = 1,
@@ -101,7 +273,9 @@ export interface CreateAuctionRequest {
   model_name: string
   mileage: number
   year: number
-  photo_url: string
+  photo_key: string
+  vin?: string
+  chassis_number?: string
 }
 
 export const createAuction = (req: CreateAuctionRequest) =>
@@ -17,6 +17,7 @@ require (
 	go.opentelemetry.io/otel/sdk v1.37.0
 	go.uber.org/zap v1.27.0
 	golang.org/x/crypto v0.39.0
+	golang.org/x/image v0.28.0
 	gorm.io/driver/mysql v1.6.0
 	gorm.io/gorm v1.30.0
 )
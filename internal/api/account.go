@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeAccountError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAccountToken), errors.Is(err, service.ErrInvalidPassword),
+		errors.Is(err, service.ErrInvalidEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrIncorrectPassword):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrEmailAlreadyVerified), errors.Is(err, service.ErrEmailInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterAccountRoutes はメールアドレス確認・パスワード再設定・メールアドレス変更のルートを登録します
+//
+//	POST /users/verify-email     メールアドレスの確認（{"token": "..."}）
+//	POST /users/me/verify-email  確認メールの再送（要ログイン）
+//	POST /users/forgot-password  パスワード再設定メールの送信（{"email": "..."}, 未登録でも 202）
+//	POST /users/reset-password   パスワードの再設定（{"token": "...", "password": "..."}, 全端末からログアウト）
+//	POST /users/me/email         メールアドレスの変更の申請（{"password": "...", "email": "..."}, 要ログイン, 変更先に確認メールを送信）
+//	POST /users/confirm-email    メールアドレスの変更の確認（{"token": "..."}）
+func RegisterAccountRoutes(r *mux.Router, svc *service.AccountService) {
+	ur := r.PathPrefix("/users").Subrouter()
+	ur.Use(RateLimit(RateLimitAuth))
+
+	ur.HandleFunc("/verify-email", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.VerifyEmail(req.Token); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+
+	ur.Handle("/me/verify-email", AuthMiddleware(RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		if err := svc.SendVerification(userID); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})))).Methods(http.MethodPost)
+
+	ur.HandleFunc("/forgot-password", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+		if err := svc.ForgotPassword(req.Email); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}).Methods(http.MethodPost)
+
+	ur.HandleFunc("/reset-password", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.ResetPassword(req.Token, req.Password); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+
+	ur.Handle("/me/email", AuthMiddleware(RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req struct {
+			Password string `json:"password"`
+			Email    string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.RequestEmailChange(service.Actor{UserID: userID, IP: clientIP(r)}, req.Password, req.Email); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})))).Methods(http.MethodPost)
+
+	ur.HandleFunc("/confirm-email", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.ConfirmEmailChange(req.Token); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+}
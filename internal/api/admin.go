@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeAdminError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAdminTargetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAdminAction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAdminConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeAdmin(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// clientIP は接続元 IP アドレスを返します（監査ログ用）
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// adminActor はリクエストの管理者と接続元を返します
+func adminActor(r *http.Request) service.Actor {
+	userID, _, _ := FromContext(r)
+	return service.Actor{UserID: userID, IP: clientIP(r)}
+}
+
+// pathID はパスパラメータ {id} を返します（ルートの正規表現で数値であることを保証しています）
+func pathID(r *http.Request) uint {
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	return uint(id)
+}
+
+// decodeReason は {"reason": "..."} 形式のリクエストボディを読み取ります（ボディ無しも許可）
+func decodeReason(r *http.Request) (string, error) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", err
+		}
+	}
+	return req.Reason, nil
+}
+
+// RegisterAdminRoutes は管理者向けのルートを登録します（各ルートに対応する管理権限が必要です）
+//
+//	GET  /admin/users                   ユーザー検索（q=メールアドレスの部分一致, role, suspended, deletion_requested, page, size）
+//	GET  /admin/users/{id}              ユーザー詳細
+//	POST /admin/users/{id}/suspend      アカウント停止（{"reason": "..."} 必須）
+//	POST /admin/users/{id}/reinstate    アカウント再開
+//	POST /admin/users/{id}/unlock       ログイン失敗によるロックの解除
+//	PUT  /admin/users/{id}/roles        ロールの置き換え（{"roles": ["bidder", "seller"], "reason": "..."}）
+//	POST /admin/auctions/{id}/close     オークションの即時終了（{"reason": "..."} 必須）
+//	POST /admin/auctions/{id}/cancel    オークションの取り消し（{"reason": "..."} 必須）
+//	POST /admin/bids/{id}/void          入札の無効化（{"reason": "..."} 必須）
+//	GET  /admin/stats                   システム統計
+//	GET  /admin/audit-logs              監査ログ（actor_id, action, target_type, target_id, before_id, limit）
+func RegisterAdminRoutes(r *mux.Router, svc *service.AdminService) {
+	ar := r.PathPrefix("/admin").Subrouter()
+	ar.Use(AuthMiddleware)
+	// guard は権限 perm を要求するルート群を返します
+	guard := func(perm string) *mux.Router {
+		sr := ar.NewRoute().Subrouter()
+		sr.Use(RequirePermission(perm))
+		return sr
+	}
+	users := guard(rbac.UserManage)
+
+	users.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		page, err := strconv.Atoi(q.Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		size, err := strconv.Atoi(q.Get("size"))
+		if err != nil || size < 1 {
+			size = 20
+		}
+		if size > service.MaxPageSize {
+			size = service.MaxPageSize
+		}
+		uq := repo.UserQuery{Email: strings.TrimSpace(q.Get("q")), Role: q.Get("role")}
+		if v := q.Get("suspended"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid suspended: "+strconv.Quote(v), http.StatusBadRequest)
+				return
+			}
+			uq.Suspended = &b
+		}
+		if v := q.Get("deletion_requested"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid deletion_requested: "+strconv.Quote(v), http.StatusBadRequest)
+				return
+			}
+			uq.DeletionRequested = &b
+		}
+		users, total, err := svc.ListUsers(uq, page, size)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		resp := PaginatedResponse{Data: make([]any, len(users)), Page: page, Size: size, TotalCount: total}
+		for i, u := range users {
+			resp.Data[i] = u
+		}
+		writeAdmin(w, resp)
+	}).Methods(http.MethodGet)
+
+	users.HandleFunc("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		u, err := svc.GetUser(pathID(r))
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeAdmin(w, u)
+	}).Methods(http.MethodGet)
+
+	users.HandleFunc("/users/{id:[0-9]+}/roles", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Roles  []string `json:"roles"`
+			Reason string   `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := svc.SetUserRoles(adminActor(r), pathID(r), req.Roles, req.Reason)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeAdmin(w, u)
+	}).Methods(http.MethodPut)
+
+	// 理由付きの管理操作: ボディの reason を読み取り、結果の対象を返します
+	actions := []struct {
+		perm string
+		path string
+		fn   func(actor service.Actor, id uint, reason string) (any, error)
+	}{
+		{rbac.UserManage, "/users/{id:[0-9]+}/suspend", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.SuspendUser(a, id, reason)
+		}},
+		{rbac.UserManage, "/users/{id:[0-9]+}/reinstate", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.ReinstateUser(a, id, reason)
+		}},
+		{rbac.UserManage, "/users/{id:[0-9]+}/unlock", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.UnlockUser(a, id, reason)
+		}},
+		{rbac.AuctionModerate, "/auctions/{id:[0-9]+}/close", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.CloseAuction(a, id, reason)
+		}},
+		{rbac.AuctionModerate, "/auctions/{id:[0-9]+}/cancel", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.CancelAuction(a, id, reason)
+		}},
+		{rbac.BidVoid, "/bids/{id:[0-9]+}/void", func(a service.Actor, id uint, reason string) (any, error) {
+			return svc.VoidBid(a, id, reason)
+		}},
+	}
+	for _, act := range actions {
+		guard(act.perm).HandleFunc(act.path, func(w http.ResponseWriter, r *http.Request) {
+			reason, err := decodeReason(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			v, err := act.fn(adminActor(r), pathID(r), reason)
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			writeAdmin(w, v)
+		}).Methods(http.MethodPost)
+	}
+
+	guard(rbac.StatsRead).HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		st, err := svc.Stats()
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeAdmin(w, st)
+	}).Methods(http.MethodGet)
+
+	guard(rbac.AuditRead).HandleFunc("/audit-logs", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		aq := repo.AuditQuery{Action: q.Get("action"), TargetType: q.Get("target_type")}
+		ids := []struct {
+			name string
+			dst  *uint
+		}{
+			{"actor_id", &aq.ActorID},
+			{"target_id", &aq.TargetID},
+			{"before_id", &aq.BeforeID},
+		}
+		for _, p := range ids {
+			v := q.Get(p.name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				http.Error(w, "invalid "+p.name+": "+strconv.Quote(v), http.StatusBadRequest)
+				return
+			}
+			*p.dst = uint(n)
+		}
+		logs, err := svc.AuditLogs(aq, parseLimit(q))
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeAdmin(w, logs)
+	}).Methods(http.MethodGet)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeAPIKeyError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAPIKeyNotFound), errors.Is(err, service.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterAPIKeyRoutes は API キーの管理ルートを登録します（ログインセッションのみ, API キーでは管理できません）
+// 作成したキーは "Authorization: ApiKey <key>" で Bearer トークンの代わりに使えます
+//
+//	GET    /users/me/api-keys       自分の API キーの一覧（キー本体は返しません）
+//	POST   /users/me/api-keys       作成（{"name": "DMS", "scopes": ["bid:place"], "expires_at": "...", "allowed_ips": ["203.0.113.0/24"]}）
+//	                                キー本体は作成時の応答でのみ返します
+//	DELETE /users/me/api-keys/{id}  失効
+func RegisterAPIKeyRoutes(r *mux.Router, svc *service.APIKeyService) {
+	kr := r.PathPrefix("/users/me/api-keys").Subrouter()
+	kr.Use(AuthMiddleware, RequireSession)
+
+	kr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		list, err := svc.List(userID)
+		if err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}).Methods(http.MethodGet)
+
+	kr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req service.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		k, err := svc.Create(userID, req)
+		if err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, k)
+	}).Methods(http.MethodPost)
+
+	kr.HandleFunc("/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		if err := svc.Revoke(userID, pathID(r)); err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+}
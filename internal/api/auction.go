@@ -2,15 +2,22 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
 	"github.com/ksj/car-auction/internal/service"
 )
 
 // 1) GET /auctions
 // オークションの一覧をページネーション付きで取得するハンドラ
+// q (タイトル・説明文の全文検索、関連度順), maker, model_name, year_min/max, mileage_min/max, price_min/max, status, seller_id, sort で絞り込み・並び替えできます
+// transmission, fuel_type, body_type, drivetrain, colour, displacement_min/max, owners_max, accident_history, grade_min で車両状態表の項目による絞り込みもできます
+// facets=true を指定するとメーカー・車種・年式・走行距離・状態ごとの件数も返します
+// cursor または limit を指定した場合はキーセット（カーソル）ページングで CursorResponse を返します
 func listAuctionsHandler(svc *service.AuctionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
@@ -22,21 +29,51 @@ func listAuctionsHandler(svc *service.AuctionService) http.HandlerFunc {
 		if err != nil || size < 1 {
 			size = 10
 		}
-		title := q.Get("title")
+		if size > service.MaxPageSize {
+			size = service.MaxPageSize
+		}
+		filter, err := parseAuctionFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		items, total, err := svc.PaginatedAuctions(page, size, title)
+		if isCursorRequest(q) {
+			limit := parseLimit(q)
+			pg, err := svc.CursorAuctions(filter, q.Get("cursor"), limit)
+			if errors.Is(err, service.ErrInvalidFilter) || errors.Is(err, repo.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(newCursorResponse(pg, limit))
+			return
+		}
+
+		withFacets, _ := strconv.ParseBool(q.Get("facets"))
+		res, err := svc.SearchAuctions(page, size, filter, withFacets)
+		if errors.Is(err, service.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		resp := PaginatedResponse{
-			Data:       make([]any, len(items)),
-			Page:       page,
-			Size:       size,
-			TotalCount: total,
+		resp := AuctionListResponse{
+			PaginatedResponse: PaginatedResponse{
+				Data:       make([]any, len(res.Items)),
+				Page:       page,
+				Size:       size,
+				TotalCount: res.Total,
+			},
+			Facets: res.Facets,
 		}
-		for i, v := range items {
+		for i, v := range res.Items {
 			resp.Data[i] = v
 		}
 		_ = json.NewEncoder(w).Encode(resp)
@@ -44,7 +81,7 @@ func listAuctionsHandler(svc *service.AuctionService) http.HandlerFunc {
 }
 
 // 2) GET /auctions/{id}
-// 指定IDのオークション詳細を取得するハンドラ
+// 指定IDのオークション詳細（閲覧中の人数を含む）を取得するハンドラ
 func getAuctionHandler(svc *service.AuctionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := mux.Vars(r)["id"]
@@ -53,7 +90,7 @@ func getAuctionHandler(svc *service.AuctionService) http.HandlerFunc {
 			http.Error(w, "invalid auction id", http.StatusBadRequest)
 			return
 		}
-		a, err := svc.GetAuction(uint(id))
+		a, err := svc.GetAuctionDetail(uint(id))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -77,7 +114,17 @@ func createAuctionHandler(svc *service.AuctionService) http.HandlerFunc {
 			return
 		}
 		a, err := svc.CreateAuction(userID, req)
-		if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidVIN):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, service.ErrDuplicateVIN):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, service.ErrMFARequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -148,6 +195,21 @@ func DeleteAuctionHandler(svc *service.AuctionService) http.HandlerFunc {
 	}
 }
 
+// 6) GET /vehicles/decode?vin=...&chassis_number=...
+// VIN または国内の車台番号を検証し、メーカー・車種・年式・工場などをデコードして返すハンドラ
+func decodeVehicleHandler(svc *service.AuctionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		spec, err := svc.DecodeVehicle(q.Get("vin"), q.Get("chassis_number"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(spec)
+	}
+}
+
 // RegisterAuctionRoutes: オークション関連のルートを登録
 func RegisterAuctionRoutes(r *mux.Router, svc *service.AuctionService) {
 	ar := r.PathPrefix("/auctions").Subrouter()
@@ -156,15 +218,17 @@ func RegisterAuctionRoutes(r *mux.Router, svc *service.AuctionService) {
 
 	ar.HandleFunc("/{id:[0-9]+}", getAuctionHandler(svc)).Methods(http.MethodGet)
 
+	r.HandleFunc("/vehicles/decode", decodeVehicleHandler(svc)).Methods(http.MethodGet)
+
 	seller := ar.Methods(http.MethodPost).Subrouter()
-	seller.Use(AuthMiddleware, RequireRole("seller"))
+	seller.Use(AuthMiddleware, RequirePermission(rbac.AuctionCreate))
 	seller.HandleFunc("", createAuctionHandler(svc)).Methods(http.MethodPost)
 
 	put := ar.Methods(http.MethodPut).Subrouter()
-	put.Use(AuthMiddleware)
+	put.Use(AuthMiddleware, RequirePermission(rbac.AuctionUpdate))
 	put.HandleFunc("/{id}", updateAuctionHandler(svc)).Methods(http.MethodPut)
 
 	del := ar.Methods(http.MethodDelete).Subrouter()
-	del.Use(AuthMiddleware)
+	del.Use(AuthMiddleware, RequirePermission(rbac.AuctionUpdate))
 	del.HandleFunc("/{id}", DeleteAuctionHandler(svc)).Methods(http.MethodDelete)
 }
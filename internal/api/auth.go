@@ -2,23 +2,46 @@ package api
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
-	"github.com/ksj/car-auction/internal/config"
+	"github.com/ksj/car-auction/internal/jwtkey"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
 )
 
 type ctxKey string
 
+// accountCheck はトークン検証後にアカウントの状態（停止・削除）とトークンの世代番号を確認する関数です（nil なら確認しません）
+var accountCheck func(userID uint, version int) error
+
+// UseAccountCheck は AuthMiddleware が毎リクエスト呼び出すアカウント状態の確認関数を設定します
+func UseAccountCheck(fn func(userID uint, version int) error) { accountCheck = fn }
+
+// signingKeys はアクセストークンの検証に使う鍵です（nil ならすべての Bearer トークンを拒否します）
+var signingKeys *jwtkey.Set
+
+// UseSigningKeys は AuthMiddleware がアクセストークンの検証に使う鍵を設定します
+// 鍵のアルゴリズム以外・iss / aud の不一致・exp の無いトークンは拒否します
+func UseSigningKeys(ks *jwtkey.Set) { signingKeys = ks }
+
+// apiKeyAuth は "Authorization: ApiKey ..." のキーを検証する関数です（nil なら API キーを受け付けません）
+var apiKeyAuth func(key, ip string) (*service.APIKeyPrincipal, error)
+
+// UseAPIKeyAuth は AuthMiddleware が API キーの検証に使う関数を設定します
+func UseAPIKeyAuth(fn func(key, ip string) (*service.APIKeyPrincipal, error)) { apiKeyAuth = fn }
+
 const (
 	userIDKey ctxKey = "user_id"
-	roleKey   ctxKey = "user_role"
+	rolesKey  ctxKey = "user_roles"
+	// scopesKey は API キーで認証した場合のキーの権限です（JWT の場合は保存しません）
+	scopesKey ctxKey = "api_key_scopes"
 )
 
-// AuthMiddleware は JWT を検証し、user_id と role をコンテキストに保存します
+// AuthMiddleware は JWT (Bearer) または API キー (ApiKey) を検証し、user_id と付与ロールをコンテキストに保存します
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 1) Authorization ヘッダーの取得
@@ -27,71 +50,150 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "authorization header is required", http.StatusUnauthorized)
 			return
 		}
-		// 2) "Bearer トークン" 形式の検証
-		log.Printf("[AUTH DBG] Authorization header: %q\n", authHeader)
-
+		// 2) "Bearer トークン" または "ApiKey キー" 形式の検証
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "authorization header format must be Bearer {token}", http.StatusUnauthorized)
-			return
-		}
-
-		// 3) JWT のパース
-		token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.Cfg.JwtSecret), nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
-			log.Printf("[AUTH DBG] token parse error: %v\n", err)
+		if len(parts) == 2 && parts[0] == "ApiKey" {
+			apiKeyMiddleware(next, w, r, parts[1])
 			return
 		}
-
-		// 4) Claims の取得
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			http.Error(w, "authorization header format must be Bearer {token} or ApiKey {key}", http.StatusUnauthorized)
 			return
 		}
 
-		// 5) user_id と role の抽出
-		uidFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			http.Error(w, "invalid user_id claim", http.StatusUnauthorized)
+		// 3) JWT のパースと user_id / roles / ver の抽出
+		userID, roles, version, err := parseToken(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		userID := uint(uidFloat)
-
-		role, ok := claims["role"].(string)
-		if !ok {
-			http.Error(w, "invalid role claim", http.StatusUnauthorized)
-			return
+		if accountCheck != nil {
+			if err := accountCheck(userID, version); err != nil {
+				if errors.Is(err, service.ErrAccountSuspended) {
+					http.Error(w, err.Error(), http.StatusForbidden)
+				} else {
+					http.Error(w, "invalid token", http.StatusUnauthorized)
+				}
+				return
+			}
 		}
-		log.Printf("[AUTH DBG] authenticated user=%d, role=%q\n", userID, role)
 
-		// 6) コンテキストに保存して次のハンドラーへ
+		// 4) コンテキストに保存して次のハンドラーへ
 		ctx := context.WithValue(r.Context(), userIDKey, userID)
-		ctx = context.WithValue(ctx, roleKey, role)
+		ctx = context.WithValue(ctx, rolesKey, roles)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// FromContext はコンテキストから user_id と role を取得します
-func FromContext(r *http.Request) (userID uint, role string, ok bool) {
+// apiKeyMiddleware は API キーを検証し、所有者の user_id・付与ロールとキーの権限をコンテキストに保存します
+func apiKeyMiddleware(next http.Handler, w http.ResponseWriter, r *http.Request, key string) {
+	if apiKeyAuth == nil {
+		http.Error(w, "api keys are not enabled", http.StatusUnauthorized)
+		return
+	}
+	p, err := apiKeyAuth(key, clientIP(r))
+	switch {
+	case errors.Is(err, service.ErrAccountSuspended), errors.Is(err, service.ErrAPIKeyIPNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx := context.WithValue(r.Context(), userIDKey, p.UserID)
+	ctx = context.WithValue(ctx, rolesKey, p.Roles)
+	ctx = context.WithValue(ctx, scopesKey, p.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// parseToken は JWT を検証し、クレームから user_id・付与ロール・トークンの世代番号を取り出します
+// roles クレームの無い旧形式のトークンは role クレームを唯一のロールとして扱い、
+// ver クレームの無いトークンは世代番号 0 として扱います
+func parseToken(raw string) (uint, []string, int, error) {
+	claims, err := signingKeys.Parse(raw)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	// 二要素認証の途中トークンなど、用途 (typ) 付きのトークンは API の認証に使えません
+	if _, ok := claims["typ"]; ok {
+		return 0, nil, 0, errors.New("invalid token type")
+	}
+	uidFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, nil, 0, errors.New("invalid user_id claim")
+	}
+	var version int
+	if v, ok := claims["ver"]; ok {
+		f, ok := v.(float64)
+		if !ok {
+			return 0, nil, 0, errors.New("invalid ver claim")
+		}
+		version = int(f)
+	}
+	if list, ok := claims["roles"].([]any); ok {
+		roles := make([]string, 0, len(list))
+		for _, v := range list {
+			role, ok := v.(string)
+			if !ok {
+				return 0, nil, 0, errors.New("invalid roles claim")
+			}
+			roles = append(roles, role)
+		}
+		return uint(uidFloat), roles, version, nil
+	}
+	role, ok := claims["role"].(string)
+	if !ok {
+		return 0, nil, 0, errors.New("invalid role claim")
+	}
+	return uint(uidFloat), []string{role}, version, nil
+}
+
+// FromContext はコンテキストから user_id と付与ロールを取得します
+func FromContext(r *http.Request) (userID uint, roles []string, ok bool) {
 	uid, ok1 := r.Context().Value(userIDKey).(uint)
-	rl, ok2 := r.Context().Value(roleKey).(string)
+	rl, ok2 := r.Context().Value(rolesKey).([]string)
 	return uid, rl, ok1 && ok2
 }
 
-// RequireRole は指定されたロールのみアクセスを許可するミドルウェアを返します
-func RequireRole(wantRole string) mux.MiddlewareFunc {
+// apiKeyScopes は API キーで認証した場合にキーの権限を返します（JWT なら ok が false）
+func apiKeyScopes(r *http.Request) (scopes []string, ok bool) {
+	scopes, ok = r.Context().Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// RequirePermission は指定された権限をすべて持つユーザーのみアクセスを許可するミドルウェアを返します
+// 権限はトークンのロールから rbac の対応表で求めます（API キーの場合はキーの権限にも含まれている必要があります）
+func RequirePermission(perms ...string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			_, role, ok := FromContext(r)
-			if !ok || role != wantRole {
+			_, roles, ok := FromContext(r)
+			if !ok {
 				http.Error(w, "forbidden", http.StatusForbidden)
 				return
 			}
+			scopes, byKey := apiKeyScopes(r)
+			for _, p := range perms {
+				if !rbac.Can(roles, p) || (byKey && !slices.Contains(scopes, p)) {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// RequireSession はログインセッション (JWT) でのみ利用できるルートを保護するミドルウェアです
+// API キーの作成や二要素認証の設定など、アカウント自体を管理する操作を API キーで行えないようにします
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, byKey := apiKeyScopes(r); byKey {
+			http.Error(w, "this operation requires a login session", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
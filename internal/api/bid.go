@@ -2,10 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
 	"github.com/ksj/car-auction/internal/service"
 )
 
@@ -14,9 +17,27 @@ func RegisterBidRoutes(r *mux.Router, svc *service.BidService) {
 	br := r.PathPrefix("/auctions/{id:[0-9]+}/bids").Subrouter()
 
 	// GET /auctions/{id}/bids?page=&size=
+	// GET /auctions/{id}/bids?cursor=&limit= (キーセットページング)
 	br.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		aid, _ := strconv.Atoi(vars["id"])
+
+		if q := r.URL.Query(); isCursorRequest(q) {
+			limit := parseLimit(q)
+			pg, err := svc.CursorBids(uint(aid), q.Get("cursor"), limit)
+			if errors.Is(err, repo.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(newCursorResponse(pg, limit))
+			return
+		}
+
 		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
 		if page < 1 {
@@ -50,7 +71,7 @@ func RegisterBidRoutes(r *mux.Router, svc *service.BidService) {
 
 	// POST /auctions/{id}/bids
 	pr := br.Methods(http.MethodPost).Subrouter()
-	pr.Use(AuthMiddleware, RequireRole("bidder"))
+	pr.Use(AuthMiddleware, RequirePermission(rbac.BidPlace), RateLimit(RateLimitBids))
 	pr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
 		userID, _, ok := FromContext(r)
 		if !ok {
@@ -66,6 +87,16 @@ func RegisterBidRoutes(r *mux.Router, svc *service.BidService) {
 			return
 		}
 		bid, err := svc.PlaceBid(uint(aid), userID, req.Amount)
+		switch {
+		case errors.Is(err, service.ErrAwaitingInspection), errors.Is(err, service.ErrOrgConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, service.ErrOrgForbidden), errors.Is(err, service.ErrCreditLimitExceeded),
+			errors.Is(err, service.ErrEmailNotVerified), errors.Is(err, service.ErrMFARequired),
+			errors.Is(err, service.ErrVerificationRequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeConditionReportError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeConditionReportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotAuctionOwner), errors.Is(err, service.ErrNotAssignedInspector):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrConditionReportNotFound), errors.Is(err, service.ErrAuctionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidConditionReport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrConditionReportLocked):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterConditionReportRoutes は車両状態表のルートを登録します
+//
+//	GET /auctions/{id}/condition-report  車両状態表の取得
+//	PUT /auctions/{id}/condition-report  車両状態表の提出・置き換え（出品者、検査中は割り当てられた検査員）
+func RegisterConditionReportRoutes(r *mux.Router, svc *service.ConditionReportService) {
+	cr := r.PathPrefix("/auctions/{id:[0-9]+}/condition-report").Subrouter()
+
+	cr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		aid, _ := strconv.Atoi(mux.Vars(r)["id"])
+		report, err := svc.Get(uint(aid))
+		if err != nil {
+			writeConditionReportError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(report)
+	}).Methods(http.MethodGet)
+
+	submit := cr.Methods(http.MethodPut).Subrouter()
+	submit.Use(AuthMiddleware, RequirePermission(rbac.AuctionDocument))
+	submit.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, _ := strconv.Atoi(mux.Vars(r)["id"])
+		var req service.ConditionReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := svc.Submit(userID, uint(aid), req)
+		if err != nil {
+			writeConditionReportError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(report)
+	}).Methods(http.MethodPut)
+}
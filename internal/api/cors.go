@@ -11,6 +11,9 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		// レート制限の状況をフロントエンドから読めるようにします
+		w.Header().Set("Access-Control-Expose-Headers",
+			"Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy")
 
 		// ブラウザのプリフライトリクエスト(OPTIONS)には即座に応答します。
 		if r.Method == http.MethodOptions {
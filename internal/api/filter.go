@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ksj/car-auction/internal/repo"
+)
+
+// parseAuctionFilter は GET /auctions のクエリパラメータを検索条件に変換します
+// 値域の検証はサービス層 (service.ValidateFilter) で行います
+func parseAuctionFilter(q url.Values) (repo.AuctionFilter, error) {
+	f := repo.AuctionFilter{
+		Query:      strings.TrimSpace(q.Get("q")),
+		Title:      q.Get("title"),
+		Makers:     splitList(q["maker"]),
+		ModelNames: splitList(q["model_name"]),
+		Status:     q.Get("status"),
+		Sort:       splitList(q["sort"]),
+
+		Transmissions: splitList(q["transmission"]),
+		FuelTypes:     splitList(q["fuel_type"]),
+		BodyTypes:     splitList(q["body_type"]),
+		Drivetrains:   splitList(q["drivetrain"]),
+		Colours:       splitList(q["colour"]),
+		GradeMin:      q.Get("grade_min"),
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"year_min", &f.YearMin},
+		{"year_max", &f.YearMax},
+		{"mileage_min", &f.MileageMin},
+		{"mileage_max", &f.MileageMax},
+		{"price_min", &f.PriceMin},
+		{"price_max", &f.PriceMax},
+		{"displacement_min", &f.DisplacementMin},
+		{"displacement_max", &f.DisplacementMax},
+		{"owners_max", &f.OwnersMax},
+	}
+	for _, p := range ints {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid %s: %q", p.name, v)
+		}
+		*p.dst = n
+	}
+	if v := q.Get("seller_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return f, fmt.Errorf("invalid seller_id: %q", v)
+		}
+		f.SellerID = uint(id)
+	}
+	if v := q.Get("accident_history"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid accident_history: %q", v)
+		}
+		f.AccidentHistory = &b
+	}
+	return f, nil
+}
+
+// splitList は "a,b" 形式および同名パラメータの繰り返しを 1 つのリストにまとめます
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeInspectionError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeInspectionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotAuctionOwner), errors.Is(err, service.ErrNotAssignedInspector):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInspectionNotFound), errors.Is(err, service.ErrAuctionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidInspection):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInspectionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterInspectionRoutes は第三者検査のルートを登録します
+//
+//	POST /auctions/{id}/inspections   検査の依頼（出品者, {"inspector_id": 3} で直接割り当て）
+//	GET  /auctions/{id}/inspections   検査履歴と状態（出品者・割り当てられた検査員）
+//	GET  /inspections                 引き受け可能な依頼と自分の担当（検査員）
+//	POST /inspections/{id}/claim      依頼の引き受け（検査員）
+//	POST /inspections/{id}/decline    担当の辞退（割り当てられた検査員）
+//	POST /inspections/{id}/complete   評価点の確定（割り当てられた検査員, {"grade": "4.5", "notes": "..."}）
+//	POST /inspections/{id}/cancel     依頼の取り消し（出品者）
+func RegisterInspectionRoutes(r *mux.Router, svc *service.InspectionService) {
+	ar := r.PathPrefix("/auctions/{id:[0-9]+}/inspections").Subrouter()
+	ar.Use(AuthMiddleware)
+
+	history := ar.Methods(http.MethodGet).Subrouter()
+	history.Use(RequirePermission(rbac.InspectionRead))
+	history.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, _ := strconv.Atoi(mux.Vars(r)["id"])
+		list, err := svc.ListByAuction(userID, uint(aid))
+		if err != nil {
+			writeInspectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}).Methods(http.MethodGet)
+
+	seller := ar.Methods(http.MethodPost).Subrouter()
+	seller.Use(RequirePermission(rbac.InspectionRequest))
+	seller.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, _ := strconv.Atoi(mux.Vars(r)["id"])
+		var req service.InspectionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		in, err := svc.Request(userID, uint(aid), req)
+		if err != nil {
+			writeInspectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, in)
+	})
+
+	ir := r.PathPrefix("/inspections").Subrouter()
+	ir.Use(AuthMiddleware)
+
+	inspector := ir.NewRoute().Subrouter()
+	inspector.Use(RequirePermission(rbac.InspectionPerform))
+	inspector.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		list, err := svc.Queue(userID)
+		if err != nil {
+			writeInspectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}).Methods(http.MethodGet)
+
+	// action は検査 ID を受け取る状態遷移ハンドラを生成します
+	action := func(fn func(userID, id uint, r *http.Request) (*model.Inspection, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			userID, _, _ := FromContext(r)
+			id, _ := strconv.Atoi(mux.Vars(r)["id"])
+			in, err := fn(userID, uint(id), r)
+			if err != nil {
+				writeInspectionError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, in)
+		}
+	}
+	inspector.HandleFunc("/{id:[0-9]+}/claim", action(func(userID, id uint, _ *http.Request) (*model.Inspection, error) {
+		return svc.Claim(userID, id)
+	})).Methods(http.MethodPost)
+	inspector.HandleFunc("/{id:[0-9]+}/decline", action(func(userID, id uint, _ *http.Request) (*model.Inspection, error) {
+		return svc.Decline(userID, id)
+	})).Methods(http.MethodPost)
+	inspector.HandleFunc("/{id:[0-9]+}/complete", action(func(userID, id uint, r *http.Request) (*model.Inspection, error) {
+		var req service.InspectionSignOff
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errors.Join(service.ErrInvalidInspection, err)
+		}
+		return svc.Complete(userID, id, req)
+	})).Methods(http.MethodPost)
+
+	owner := ir.NewRoute().Subrouter()
+	owner.Use(RequirePermission(rbac.InspectionRequest))
+	owner.HandleFunc("/{id:[0-9]+}/cancel", action(func(userID, id uint, _ *http.Request) (*model.Inspection, error) {
+		return svc.Cancel(userID, id)
+	})).Methods(http.MethodPost)
+}
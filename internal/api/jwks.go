@@ -0,0 +1,21 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/jwtkey"
+)
+
+// RegisterJWKSRoute は他のサービスがアクセストークンを検証するための公開鍵の一覧を登録します
+//
+//	GET /.well-known/jwks.json  公開鍵 (RFC 7517, 使用開始前の次の鍵とローテーション後も検証に使う鍵を含む, HS256 では空)
+func RegisterJWKSRoute(r *mux.Router, ks *jwtkey.Set) {
+	r.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(jwtkey.JWKSMaxAge.Seconds())))
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": ks.JWKS()})
+	}).Methods(http.MethodGet)
+}
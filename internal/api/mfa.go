@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeMFAError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrMFAAlreadyEnabled), errors.Is(err, service.ErrMFANotEnrolled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// decodeCode は {"code": "..."} 形式のリクエストボディを読み取ります
+func decodeCode(r *http.Request) (string, error) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", err
+	}
+	return req.Code, nil
+}
+
+// RegisterMFARoutes は二要素認証 (TOTP) の設定ルートを登録します（要ログイン）
+//
+//	GET    /users/me/mfa                 設定状況（有効か・残りのリカバリーコード数）
+//	POST   /users/me/mfa/totp            登録手続きの開始（共有鍵と otpauth:// URI を返却）
+//	POST   /users/me/mfa/totp/activate   認証アプリのコードを確認して有効化（{"code": "123456"}, リカバリーコードを返却）
+//	DELETE /users/me/mfa/totp            無効化（{"code": "..."}, ワンタイムコードまたはリカバリーコード）
+//	POST   /users/me/mfa/recovery-codes  リカバリーコードの再発行（{"code": "123456"}）
+func RegisterMFARoutes(r *mux.Router, svc *service.MFAService) {
+	mr := r.PathPrefix("/users/me/mfa").Subrouter()
+	mr.Use(AuthMiddleware, RequireSession)
+
+	mr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		st, err := svc.Status(userID)
+		if err != nil {
+			writeMFAError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+	}).Methods(http.MethodGet)
+
+	mr.HandleFunc("/totp", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		e, err := svc.Enroll(userID)
+		if err != nil {
+			writeMFAError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, e)
+	}).Methods(http.MethodPost)
+
+	mr.HandleFunc("/totp/activate", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		code, err := decodeCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		codes, err := svc.Activate(userID, code)
+		if err != nil {
+			writeMFAError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+	}).Methods(http.MethodPost)
+
+	mr.HandleFunc("/totp", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		code, err := decodeCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.Disable(userID, code); err != nil {
+			writeMFAError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+
+	mr.HandleFunc("/recovery-codes", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		code, err := decodeCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		codes, err := svc.RegenerateRecoveryCodes(userID, code)
+		if err != nil {
+			writeMFAError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+	}).Methods(http.MethodPost)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// RegisterNotificationRoutes はアプリ内通知のルートを登録します（認証ユーザーのみ）
+//
+//	GET  /users/me/notifications?unread=true&limit=
+//	POST /users/me/notifications/{id}/read
+func RegisterNotificationRoutes(r *mux.Router, svc *service.NotificationService) {
+	nr := r.PathPrefix("/users/me/notifications").Subrouter()
+	nr.Use(AuthMiddleware, RequirePermission(rbac.NotificationRead))
+
+	nr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		unread, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+		list, err := svc.List(userID, unread, parseLimit(r.URL.Query()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}).Methods(http.MethodGet)
+
+	nr.HandleFunc("/{id:[0-9]+}/read", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		if err := svc.MarkRead(userID, uint(id)); err != nil {
+			if errors.Is(err, service.ErrNotificationNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+}
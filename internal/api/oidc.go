@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/config"
+	"github.com/ksj/car-auction/internal/oidc"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// oidcStateCookie はログイン開始からコールバックまで state トークンを保持する Cookie です
+const oidcStateCookie = "oidc_state"
+
+// writeOIDCError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeOIDCError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, oidc.ErrExchange):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrOIDCNotProvisioned), errors.Is(err, service.ErrAccountSuspended):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, oidc.ErrProvider):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterOIDCRoutes は外部の IdP (OpenID Connect) によるログインのルートを登録します
+//
+//	GET /auth/oidc/login     IdP の認可画面へリダイレクト（state・nonce・PKCE の検証用に HttpOnly の Cookie を設定）
+//	GET /auth/oidc/callback  IdP からのコールバック（認可コードを交換し、フロントエンドの /oidc/callback へリダイレクト）
+//	                         トークンは URL のフラグメントで渡します
+//	                         （#token=...&refresh_token=...&expires_in=..., 二要素認証が有効なら #mfa_token=...）
+func RegisterOIDCRoutes(r *mux.Router, svc *service.OIDCService) {
+	or := r.PathPrefix("/auth/oidc").Subrouter()
+	or.Use(RateLimit(RateLimitAuth))
+
+	or.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		login, err := svc.Begin(r.Context())
+		if err != nil {
+			writeOIDCError(w, err)
+			return
+		}
+		// IdP からのトップレベルの GET リダイレクトで送られるよう SameSite=Lax とします
+		// パスを接頭辞で書き換えるプロキシ（開発時の /api など）の背後でも送られるよう Path は "/" とします
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    login.StateToken,
+			Path:     "/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, login.AuthURL, http.StatusFound)
+	}).Methods(http.MethodGet)
+
+	or.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		// state トークンは 1 回限り
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+		q := r.URL.Query()
+		if e := q.Get("error"); e != "" {
+			http.Error(w, "identity provider returned "+e, http.StatusBadRequest)
+			return
+		}
+		c, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			writeOIDCError(w, service.ErrInvalidOIDCState)
+			return
+		}
+		res, err := svc.Complete(r.Context(), c.Value, q.Get("state"), q.Get("code"), clientIP(r))
+		if err != nil {
+			writeOIDCError(w, err)
+			return
+		}
+		frag := url.Values{}
+		if res.Tokens == nil {
+			frag.Set("mfa_token", res.MFAToken)
+		} else {
+			frag.Set("token", res.Tokens.AccessToken)
+			frag.Set("refresh_token", res.Tokens.RefreshToken)
+			frag.Set("expires_in", strconv.Itoa(res.Tokens.ExpiresIn))
+		}
+		// フラグメントはサーバーやリファラーに送られないため、トークンをクエリではなくフラグメントで渡します
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, config.Cfg.AppURL+"/oidc/callback#"+frag.Encode(), http.StatusFound)
+	}).Methods(http.MethodGet)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeOrgError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeOrgError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrOrgNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrOrgForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidOrg):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrOrgConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON は v を JSON としてステータスコード付きで書き出します
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// pageParams は page / size クエリを読み取ります
+func pageParams(r *http.Request) (int, int) {
+	q := r.URL.Query()
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err := strconv.Atoi(q.Get("size"))
+	if err != nil || size < 1 {
+		size = 10
+	}
+	return page, min(size, service.MaxPageSize)
+}
+
+// RegisterOrganizationRoutes は組織のルートを登録します
+//
+//	POST   /orgs                          組織の作成（作成者がオーナー, {"name": "..."}）
+//	GET    /orgs/me                       所属組織・メンバー・与信の利用状況
+//	POST   /orgs/me/members               メンバーの追加（オーナー, {"email": "...", "role": "buyer"}）
+//	PUT    /orgs/me/members/{userID}      組織内ロールの変更（オーナー, {"role": "viewer"}）
+//	DELETE /orgs/me/members/{userID}      メンバーの削除（オーナー、または自分自身の脱退）
+//	GET    /orgs/me/bids                  組織としての入札一覧
+//	GET    /orgs/me/auctions              組織の出品一覧
+//	PUT    /admin/orgs/{id}/credit-limit  与信枠の変更（管理者, {"credit_limit": 5000000, "reason": "..."}）
+func RegisterOrganizationRoutes(r *mux.Router, svc *service.OrganizationService) {
+	or := r.PathPrefix("/orgs").Subrouter()
+	or.Use(AuthMiddleware, RequirePermission(rbac.OrgUse))
+
+	or.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		org, err := svc.Create(userID, req.Name)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, org)
+	}).Methods(http.MethodPost)
+
+	or.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		org, err := svc.Mine(userID)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, org)
+	}).Methods(http.MethodGet)
+
+	or.HandleFunc("/me/members", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		org, err := svc.AddMember(userID, req.Email, req.Role)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, org)
+	}).Methods(http.MethodPost)
+
+	or.HandleFunc("/me/members/{userID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		member, _ := strconv.Atoi(mux.Vars(r)["userID"])
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		org, err := svc.UpdateMember(userID, uint(member), req.Role)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, org)
+	}).Methods(http.MethodPut)
+
+	or.HandleFunc("/me/members/{userID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		member, _ := strconv.Atoi(mux.Vars(r)["userID"])
+		if err := svc.RemoveMember(userID, uint(member)); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+
+	or.HandleFunc("/me/bids", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		page, size := pageParams(r)
+		bids, total, err := svc.Bids(userID, page, size)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		resp := PaginatedResponse{Data: make([]any, len(bids)), Page: page, Size: size, TotalCount: total}
+		for i, b := range bids {
+			resp.Data[i] = b
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}).Methods(http.MethodGet)
+
+	or.HandleFunc("/me/auctions", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		page, size := pageParams(r)
+		list, total, err := svc.Auctions(userID, page, size)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		resp := PaginatedResponse{Data: make([]any, len(list)), Page: page, Size: size, TotalCount: total}
+		for i, a := range list {
+			resp.Data[i] = a
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}).Methods(http.MethodGet)
+
+	credit := r.PathPrefix("/admin/orgs").Subrouter()
+	credit.Use(AuthMiddleware, RequirePermission(rbac.OrgCredit))
+	credit.HandleFunc("/{id:[0-9]+}/credit-limit", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			CreditLimit *int   `json:"credit_limit"`
+			Reason      string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.CreditLimit == nil {
+			http.Error(w, "credit_limit is required", http.StatusBadRequest)
+			return
+		}
+		org, err := svc.SetCreditLimit(adminActor(r), pathID(r), *req.CreditLimit, req.Reason)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, org)
+	}).Methods(http.MethodPut)
+}
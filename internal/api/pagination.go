@@ -1,8 +1,66 @@
 package api
 
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/service"
+)
+
 type PaginatedResponse struct {
 	Data       []any `json:"data"`
 	Page       int   `json:"page"`
 	Size       int   `json:"size"`
 	TotalCount int64 `json:"total_count"`
 }
+
+// AuctionListResponse はオークション一覧のレスポンスです
+// facets=true が指定された場合のみ facets が含まれます
+type AuctionListResponse struct {
+	PaginatedResponse
+	Facets *repo.AuctionFacets `json:"facets,omitempty"`
+}
+
+// CursorResponse はキーセット（カーソル）ページネーションのレスポンスです
+// next_cursor / prev_cursor は続きのページが無い場合 null になります
+type CursorResponse struct {
+	Data       []any   `json:"data"`
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+}
+
+// isCursorRequest は cursor または limit が指定されたカーソルページングのリクエストかを判定します
+func isCursorRequest(q url.Values) bool {
+	return q.Has("cursor") || q.Has("limit")
+}
+
+// parseLimit は limit パラメータを 1〜service.MaxPageSize の範囲で返します（既定値 10）
+func parseLimit(q url.Values) int {
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit < 1 {
+		return 10
+	}
+	if limit > service.MaxPageSize {
+		return service.MaxPageSize
+	}
+	return limit
+}
+
+// newCursorResponse は repo.CursorPage を CursorResponse に変換します
+func newCursorResponse[T any](page *repo.CursorPage[T], limit int) CursorResponse {
+	resp := CursorResponse{Data: make([]any, len(page.Items)), Limit: limit}
+	for i, v := range page.Items {
+		resp.Data[i] = v
+	}
+	if page.Next != nil {
+		tok := page.Next.Encode()
+		resp.NextCursor = &tok
+	}
+	if page.Prev != nil {
+		tok := page.Prev.Encode()
+		resp.PrevCursor = &tok
+	}
+	return resp
+}
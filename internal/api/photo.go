@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writePhotoError はサービスのエラーを HTTP ステータスに変換します
+func writePhotoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotAuctionOwner):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrPhotoNotFound), errors.Is(err, service.ErrAuctionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidPhoto):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// photoVars はパスパラメータからオークション ID と写真 ID を取得します
+func photoVars(r *http.Request) (auctionID, photoID uint) {
+	vars := mux.Vars(r)
+	aid, _ := strconv.Atoi(vars["id"])
+	pid, _ := strconv.Atoi(vars["photoID"])
+	return uint(aid), uint(pid)
+}
+
+// RegisterPhotoRoutes はオークション写真ギャラリーのルートを登録します
+// 一覧は誰でも取得でき、変更は出品者本人のみ可能です
+//
+//	GET    /auctions/{id}/photos
+//	POST   /auctions/{id}/photos            (multipart: file, caption, is_cover)
+//	PUT    /auctions/{id}/photos/order      ({"photo_ids": [...]})
+//	PUT    /auctions/{id}/photos/{photoID}  ({"caption": "...", "is_cover": true})
+//	DELETE /auctions/{id}/photos/{photoID}
+func RegisterPhotoRoutes(r *mux.Router, svc *service.PhotoService) {
+	pr := r.PathPrefix("/auctions/{id:[0-9]+}/photos").Subrouter()
+
+	pr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		aid, _ := photoVars(r)
+		photos, err := svc.List(aid)
+		if err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(photos)
+	}).Methods(http.MethodGet)
+
+	owner := pr.Methods(http.MethodPost, http.MethodPut, http.MethodDelete).Subrouter()
+	owner.Use(AuthMiddleware, RequirePermission(rbac.AuctionDocument))
+
+	owner.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, _ := photoVars(r)
+
+		// リクエストボディサイズを最大10MBに制限
+		r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			http.Error(w, "failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		isCover, _ := strconv.ParseBool(r.FormValue("is_cover"))
+
+		p, err := svc.Attach(userID, aid, file, r.FormValue("caption"), isCover)
+		if err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(p)
+	}).Methods(http.MethodPost)
+
+	owner.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, _ := photoVars(r)
+		var req struct {
+			PhotoIDs []uint `json:"photo_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		photos, err := svc.Reorder(userID, aid, req.PhotoIDs)
+		if err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(photos)
+	}).Methods(http.MethodPut)
+
+	owner.HandleFunc("/{photoID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, pid := photoVars(r)
+		var req service.PhotoUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p, err := svc.Update(userID, aid, pid, req)
+		if err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
+	}).Methods(http.MethodPut)
+
+	owner.HandleFunc("/{photoID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		aid, pid := photoVars(r)
+		if err := svc.Delete(userID, aid, pid); err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+}
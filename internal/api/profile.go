@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeProfileError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProfile), errors.Is(err, service.ErrInvalidPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrIncorrectPassword):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrDeletionAlreadyRequested), errors.Is(err, service.ErrNoDeletionRequest):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// profileResponse は /users/me のレスポンスです（ユーザー情報にロール名・権限・確認状況を加えます）
+type profileResponse struct {
+	*model.User
+	Roles         []string `json:"roles"`
+	Permissions   []string `json:"permissions"`
+	EmailVerified bool     `json:"email_verified"`
+	MFAEnabled    bool     `json:"mfa_enabled"`
+}
+
+func writeProfile(w http.ResponseWriter, u *model.User) {
+	roles := u.RoleNames()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileResponse{
+		User:          u,
+		Roles:         roles,
+		Permissions:   rbac.Permissions(roles),
+		EmailVerified: u.EmailVerifiedAt != nil,
+		MFAEnabled:    u.MFAEnabled(),
+	})
+}
+
+// RegisterProfileRoutes は本人のプロフィールとアカウント操作のルートを登録します（要ログイン）
+//
+//	GET    /users/me           ユーザー情報（プロフィール・ロール・権限・メールアドレスの確認状況など）
+//	PATCH  /users/me           プロフィールの変更（display_name, phone, address, company, language, currency のうち指定した項目）
+//	POST   /users/me/password  パスワードの変更（{"current_password": "...", "password": "..."}, 他の端末はログアウトし新しいトークンを返却）
+//	POST   /users/me/deletion  アカウントの削除の申請（{"password": "...", "reason": "..."}, 管理者が確認して削除）
+//	DELETE /users/me/deletion  アカウントの削除の申請の取り消し
+func RegisterProfileRoutes(r *mux.Router, svc *service.UserService) {
+	mr := r.PathPrefix("/users/me").Subrouter()
+	mr.Use(AuthMiddleware, RequireSession)
+	// パスワードを確認するルートは総当たり対策としてアカウント操作のレート制限を適用
+	sensitive := mr.NewRoute().Subrouter()
+	sensitive.Use(RateLimit(RateLimitAuth))
+
+	mr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		u, err := svc.Profile(userID)
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+		writeProfile(w, u)
+	}).Methods(http.MethodGet)
+
+	mr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req service.UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := svc.UpdateProfile(service.Actor{UserID: userID, IP: clientIP(r)}, req)
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+		writeProfile(w, u)
+	}).Methods(http.MethodPatch)
+
+	sensitive.HandleFunc("/password", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			Password        string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pair, u, err := svc.ChangePassword(service.Actor{UserID: userID, IP: clientIP(r)}, req.CurrentPassword, req.Password)
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+		writeSession(w, pair, u)
+	}).Methods(http.MethodPost)
+
+	sensitive.HandleFunc("/deletion", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req struct {
+			Password string `json:"password"`
+			Reason   string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := svc.RequestDeletion(service.Actor{UserID: userID, IP: clientIP(r)}, req.Password, req.Reason)
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+		writeProfile(w, u)
+	}).Methods(http.MethodPost)
+
+	mr.HandleFunc("/deletion", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		u, err := svc.CancelDeletion(service.Actor{UserID: userID, IP: clientIP(r)})
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+		writeProfile(w, u)
+	}).Methods(http.MethodDelete)
+}
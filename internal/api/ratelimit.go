@@ -0,0 +1,176 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+)
+
+// レート制限のルート群
+const (
+	// RateLimitAPI は API 全体（ルーター単位）の制限です
+	RateLimitAPI = "api"
+	// RateLimitAuth はサインアップ・ログイン・パスワード再設定など未認証で呼べるアカウント操作の制限です
+	RateLimitAuth = "auth"
+	// RateLimitBids は入札の制限です
+	RateLimitBids = "bids"
+)
+
+// RateLimitPolicy はルート群ごとのレート制限（トークンバケット）です
+// 最大 Limit 回まで連続して受け付け、その後は Period あたり Limit 回の割合でトークンを補充します
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// rate は 1 秒あたりに補充するトークン数です
+func (p RateLimitPolicy) rate() float64 { return float64(p.Limit) / p.Period.Seconds() }
+
+// take はバケットに経過時間分のトークンを補充し、1 つ消費できれば true を返します
+func (p RateLimitPolicy) take(b *model.RateLimitBucket, now time.Time) bool {
+	if b.RefilledAt == nil {
+		b.Tokens = float64(p.Limit)
+	} else if elapsed := now.Sub(*b.RefilledAt); elapsed > 0 {
+		b.Tokens = min(float64(p.Limit), b.Tokens+elapsed.Seconds()*p.rate())
+	}
+	b.RefilledAt = &now
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
+
+// RateLimitStore はトークンバケットの保存先です
+// サーバー 1 台なら NewMemoryRateLimitStore、複数台で制限を共有する場合は repo.RateLimitRepo を使います
+type RateLimitStore interface {
+	// Take は subject のバケットを排他的に読み込み、take で補充・消費した結果を保存して返します
+	// 記録の無いバケットは RefilledAt が nil の状態で take に渡します
+	Take(subject string, take func(b *model.RateLimitBucket) bool) (*model.RateLimitBucket, bool, error)
+}
+
+var _ RateLimitStore = (*repo.RateLimitRepo)(nil)
+
+// RateLimiter はルート群ごとのレート制限の設定と保存先です
+type RateLimiter struct {
+	store    RateLimitStore
+	policies map[string]RateLimitPolicy
+
+	// Now は現在時刻を返します（テストで差し替え可能）
+	Now func() time.Time
+}
+
+// NewRateLimiter は保存先とルート群ごとの制限から RateLimiter を生成します（Limit が 0 以下のルート群は制限しません）
+func NewRateLimiter(store RateLimitStore, policies ...RateLimitPolicy) *RateLimiter {
+	l := &RateLimiter{store: store, policies: map[string]RateLimitPolicy{}, Now: time.Now}
+	for _, p := range policies {
+		if p.Limit > 0 && p.Period > 0 {
+			l.policies[p.Name] = p
+		}
+	}
+	return l
+}
+
+// rateLimiter は RateLimit ミドルウェアが使う制限です（nil なら制限しません）
+var rateLimiter *RateLimiter
+
+// UseRateLimiter は RateLimit ミドルウェアが使うレート制限を設定します
+func UseRateLimiter(l *RateLimiter) { rateLimiter = l }
+
+// RateLimit はルート群 name のレート制限を行うミドルウェアを返します
+// 認証済み (AuthMiddleware の後) ならユーザー ID ごと、未認証なら接続元 IP ごとに数え、
+// 超過した場合は 429 と Retry-After を返します。応答には常に RateLimit-* ヘッダーを付けます
+// 保存先の障害でサービス全体が止まらないよう、保存先のエラー時は制限せずに通します
+func RateLimit(name string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := rateLimiter
+			if l == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, ok := l.policies[name]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			subject := name + ":ip:" + clientIP(r)
+			if userID, _, ok := FromContext(r); ok {
+				subject = fmt.Sprintf("%s:user:%d", name, userID)
+			}
+			now := l.Now()
+			b, allowed, err := l.store.Take(subject, func(b *model.RateLimitBucket) bool { return p.take(b, now) })
+			if err != nil {
+				log.Printf("rate limit store error: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(p.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(int(b.Tokens)))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds((float64(p.Limit)-b.Tokens)/p.rate())))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit, int(p.Period.Seconds())))
+			if !allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds((1-b.Tokens)/p.rate())))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds は秒数を切り上げた整数にします
+func ceilSeconds(s float64) int { return int(math.Ceil(s)) }
+
+// memoryBucketIdle より長く使われていないバケットは、バケットの数が memoryBucketPurgeSize を超えたときに削除します
+// （削除されたバケットは満杯として数え直すため、Period がこれより長い制限は正確ではなくなります）
+const (
+	memoryBucketIdle      = time.Hour
+	memoryBucketPurgeSize = 10000
+)
+
+// MemoryRateLimitStore はプロセス内のメモリにトークンバケットを保存します
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]model.RateLimitBucket
+
+	// Now は現在時刻を返します（テストで差し替え可能）
+	Now func() time.Time
+}
+
+// NewMemoryRateLimitStore は空の MemoryRateLimitStore を生成します
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]model.RateLimitBucket{}, Now: time.Now}
+}
+
+// Take は subject のバケットを補充・消費して返します
+func (m *MemoryRateLimitStore) Take(subject string, take func(b *model.RateLimitBucket) bool) (*model.RateLimitBucket, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.buckets) >= memoryBucketPurgeSize {
+		cutoff := m.Now().Add(-memoryBucketIdle)
+		for k, b := range m.buckets {
+			if b.RefilledAt != nil && b.RefilledAt.Before(cutoff) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+	b, ok := m.buckets[subject]
+	if !ok {
+		b = model.RateLimitBucket{Subject: subject}
+	}
+	allowed := take(&b)
+	m.buckets[subject] = b
+	return &b, allowed, nil
+}
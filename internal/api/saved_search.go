@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeSavedSearchError はサービスのエラーを HTTP ステータスに変換します
+func writeSavedSearchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrSavedSearchNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidFilter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterSavedSearchRoutes は保存済み検索条件のルートを登録します（認証ユーザーのみ）
+//
+//	GET    /users/me/saved-searches
+//	POST   /users/me/saved-searches
+//	PUT    /users/me/saved-searches/{id}
+//	POST   /users/me/saved-searches/{id}/pause
+//	POST   /users/me/saved-searches/{id}/resume
+//	DELETE /users/me/saved-searches/{id}
+func RegisterSavedSearchRoutes(r *mux.Router, svc *service.SavedSearchService) {
+	sr := r.PathPrefix("/users/me/saved-searches").Subrouter()
+	sr.Use(AuthMiddleware, RequirePermission(rbac.SearchSave))
+
+	sr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		list, err := svc.List(userID)
+		if err != nil {
+			writeSavedSearchError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}).Methods(http.MethodGet)
+
+	sr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req service.SavedSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v, err := svc.Create(userID, req)
+		if err != nil {
+			writeSavedSearchError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(v)
+	}).Methods(http.MethodPost)
+
+	sr.HandleFunc("/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		var req service.SavedSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v, err := svc.Update(userID, uint(id), req)
+		if err != nil {
+			writeSavedSearchError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}).Methods(http.MethodPut)
+
+	for action, paused := range map[string]bool{"pause": true, "resume": false} {
+		paused := paused
+		sr.HandleFunc("/{id:[0-9]+}/"+action, func(w http.ResponseWriter, r *http.Request) {
+			userID, _, _ := FromContext(r)
+			id, _ := strconv.Atoi(mux.Vars(r)["id"])
+			v, err := svc.SetPaused(userID, uint(id), paused)
+			if err != nil {
+				writeSavedSearchError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v)
+		}).Methods(http.MethodPost)
+	}
+
+	sr.HandleFunc("/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		if err := svc.Delete(userID, uint(id)); err != nil {
+			writeSavedSearchError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+}
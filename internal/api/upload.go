@@ -2,56 +2,82 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/ksj/car-auction/internal/storage"
 )
 
-// UploadHandler は "file" フォームフィールドで送信されたファイルを受け取り
-func UploadHandler(w http.ResponseWriter, r *http.Request) {
-	// リクエストボディサイズを最大10MBに制限
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+// UploadHandler は "file" フォームフィールドで送信された画像を画像パイプラインで処理して保存し、
+// 配信用 URL・srcset・サイズを返します
+// 画像以外のファイル（HTML, SVG など）は 400 で拒否されます
+func UploadHandler(svc *service.PhotoService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストボディサイズを最大10MBに制限
+		r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
 
-	// multipart フォームのパース
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
-		return
-	}
+		// multipart フォームのパース
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			http.Error(w, "failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	// フォームから "file" フィールドを取得
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-	defer file.Close()
+		// フォームから "file" フィールドを取得
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
 
-	// uploads ディレクトリが存在しない場合は作成
-	if err := os.MkdirAll("uploads", 0755); err != nil {
-		http.Error(w, "could not create uploads dir: "+err.Error(), http.StatusInternalServerError)
-		return
+		img, err := svc.Upload(file)
+		if err != nil {
+			writePhotoError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(img)
 	}
+}
 
-	// 保存先ファイルを作成
-	dstPath := filepath.Join("uploads", filepath.Base(header.Filename))
-	out, err := os.Create(dstPath)
-	if err != nil {
-		http.Error(w, "could not save file: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer out.Close()
-
-	// アップロードされたデータをコピー
-	if _, err := io.Copy(out, file); err != nil {
-		http.Error(w, "could not write file: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
+// StaticHandler は BlobStore に保存されたファイルを /static/ 配下で配信します
+// signer が nil でなければ署名と有効期限を検証し、不正・期限切れの URL は 403 を返します
+// ブラウザによる Content-Type の推測を禁止し、アップロードされたファイルがスクリプトとして解釈されないようにします
+func StaticHandler(store storage.BlobStore, signer *storage.Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/static/")
+		if signer != nil {
+			if err := signer.Verify(key, r.URL.Query()); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		body, obj, err := store.Get(key)
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
 
-	// アクセス用 URL を返却
-	url := fmt.Sprintf("/static/%s", filepath.Base(header.Filename))
-	w.Header().Set("Content-Type", "application/json")
-	resp := map[string]string{"url": url}
-	json.NewEncoder(w).Encode(resp)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Type", obj.ContentType)
+		// キーは内容から決まるため不変だが、URL の有効期限を越えてキャッシュされないよう private にする
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		if obj.Size > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = io.Copy(w, body)
+	})
 }
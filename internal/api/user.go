@@ -2,9 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
 	"github.com/ksj/car-auction/internal/service"
 )
 
@@ -12,9 +17,10 @@ import (
 func signupHandler(svc *service.UserService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
-			Role     string `json:"role"`
+			Email    string   `json:"email"`
+			Password string   `json:"password"`
+			Role     string   `json:"role"`
+			Roles    []string `json:"roles"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -22,27 +28,60 @@ func signupHandler(svc *service.UserService) http.HandlerFunc {
 		}
 
 		// サービス呼び出し: ユーザー作成とトークン生成
-		token, err := svc.Signup(service.CreateUserRequest{
+		pair, err := svc.Signup(service.CreateUserRequest{
 			Email:    req.Email,
 			Password: req.Password,
 			Role:     req.Role,
+			Roles:    req.Roles,
 		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// レスポンス: トークンを JSON で返却
+		// レスポンス: アクセストークンとリフレッシュトークンを JSON で返却
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"token": token,
-		})
+		_ = json.NewEncoder(w).Encode(pair)
+	}
+}
+
+// writeSession はトークンと主ロール・付与ロール・権限を JSON で返却します（ログイン・再発行の共通レスポンス）
+func writeSession(w http.ResponseWriter, pair *service.TokenPair, u *model.User) {
+	w.Header().Set("Content-Type", "application/json")
+	roles := u.RoleNames()
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"role":          u.Role,
+		"roles":         roles,
+		"permissions":   rbac.Permissions(roles),
+	})
+}
+
+// writeLoginThrottled はログインの受付停止を 429 と Retry-After ヘッダーで返します（受付停止でなければ false）
+func writeLoginThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+	return true
 }
 
 // RegisterUserRoutes はユーザー関連のルートを登録します
+//
+//	POST /users/signup   サインアップ
+//	POST /users/login    ログイン（アクセストークンとリフレッシュトークンを発行, 二要素認証が有効なら mfa_token を返却）
+//	                     失敗が続くと待ち時間・一時ロックとなり、429 と Retry-After（秒）を返します
+//	POST /users/refresh  トークンの再発行（{"refresh_token": "..."}, リフレッシュトークンも新しいものに置き換え）
+//	POST /users/login/mfa  ログインの 2 段階目（{"mfa_token": "...", "code": "123456"}, リカバリーコードも可）
+//	POST /users/logout   ログアウト（{"refresh_token": "...", "all": false}, all=true で全端末からログアウト）
 func RegisterUserRoutes(r *mux.Router, svc *service.UserService) {
 	ur := r.PathPrefix("/users").Subrouter()
+	ur.Use(RateLimit(RateLimitAuth))
 	ur.HandleFunc("/signup", signupHandler(svc)).Methods(http.MethodPost)
 	ur.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
 		var req struct{ Email, Password string }
@@ -50,17 +89,99 @@ func RegisterUserRoutes(r *mux.Router, svc *service.UserService) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		// サービス呼び出し: ログイン -> token, role, err
-		tok, role, err := svc.Login(req.Email, req.Password)
+		// サービス呼び出し: ログイン -> result, err
+		res, err := svc.Login(req.Email, req.Password, clientIP(r))
+		if writeLoginThrottled(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrAccountSuspended) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if err != nil {
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
-		// レスポンス: token と role を JSON で返却
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"token": tok,
-			"role":  role,
-		})
+		if res.Tokens == nil {
+			// 二要素認証が有効: トークンは /users/login/mfa で発行
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"mfa_required": true,
+				"mfa_token":    res.MFAToken,
+			})
+			return
+		}
+		writeSession(w, res.Tokens, res.User)
 	}).Methods("POST")
+
+	ur.HandleFunc("/login/mfa", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pair, u, err := svc.LoginMFA(req.MFAToken, req.Code, clientIP(r))
+		if writeLoginThrottled(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrAccountSuspended):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case errors.Is(err, service.ErrInvalidMFAToken), errors.Is(err, service.ErrInvalidMFACode):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeSession(w, pair, u)
+	}).Methods(http.MethodPost)
+
+	ur.HandleFunc("/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "refresh_token is required", http.StatusBadRequest)
+			return
+		}
+		pair, u, err := svc.Refresh(req.RefreshToken)
+		switch {
+		case errors.Is(err, service.ErrAccountSuspended):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case errors.Is(err, service.ErrInvalidRefreshToken), errors.Is(err, service.ErrRefreshTokenReused):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeSession(w, pair, u)
+	}).Methods(http.MethodPost)
+
+	ur.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+			All          bool   `json:"all"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "refresh_token is required", http.StatusBadRequest)
+			return
+		}
+		err := svc.Logout(req.RefreshToken, req.All)
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
 }
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/service"
+)
+
+// writeVerificationError はサービスのエラーを HTTP ステータスに変換して書き込みます
+func writeVerificationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVerification):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrVerificationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrVerificationConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RegisterVerificationRoutes は業者の本人確認（KYC）のルートを登録します
+// 申請は本人（要ログイン）、審査は本人確認の審査権限を持つ管理者が行います
+//
+//	GET    /users/me/verification                      本人の最新の申請と提出書類
+//	POST   /users/me/verification/documents            書類の添付（multipart: file, kind, 画像または PDF）
+//	DELETE /users/me/verification/documents/{docID}    書類の削除（提出前のみ）
+//	POST   /users/me/verification/submit               申請の提出（{"company_name": "...", "licence_number": "...", "corporate_number": "..."}）
+//	GET    /admin/verifications                        申請の一覧（status, page, size, 提出の古い順）
+//	GET    /admin/verifications/{id}                   申請の詳細
+//	POST   /admin/verifications/{id}/approve           承認（{"reason": "..."} 任意）
+//	POST   /admin/verifications/{id}/reject            却下（{"reason": "..."} 必須）
+//	POST   /admin/verifications/{id}/revoke            承認の取り消し（{"reason": "..."} 必須）
+func RegisterVerificationRoutes(r *mux.Router, svc *service.VerificationService) {
+	mr := r.PathPrefix("/users/me/verification").Subrouter()
+	mr.Use(AuthMiddleware, RequireSession)
+
+	mr.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		v, err := svc.Mine(userID)
+		if err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}).Methods(http.MethodGet)
+
+	mr.HandleFunc("/documents", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+
+		// リクエストボディサイズを最大10MBに制限
+		r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			http.Error(w, "failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		d, err := svc.AddDocument(userID, r.FormValue("kind"), file)
+		if err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, d)
+	}).Methods(http.MethodPost)
+
+	mr.HandleFunc("/documents/{docID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		docID, _ := strconv.ParseUint(mux.Vars(r)["docID"], 10, 32)
+		if err := svc.RemoveDocument(userID, uint(docID)); err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+
+	mr.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := FromContext(r)
+		var req service.VerificationSubmitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v, err := svc.Submit(service.Actor{UserID: userID, IP: clientIP(r)}, req)
+		if err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}).Methods(http.MethodPost)
+
+	ar := r.PathPrefix("/admin/verifications").Subrouter()
+	ar.Use(AuthMiddleware, RequirePermission(rbac.UserVerify))
+
+	ar.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		page, size := pageParams(r)
+		list, total, err := svc.List(r.URL.Query().Get("status"), page, size)
+		if err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		resp := PaginatedResponse{Data: make([]any, len(list)), Page: page, Size: size, TotalCount: total}
+		for i, v := range list {
+			resp.Data[i] = v
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}).Methods(http.MethodGet)
+
+	ar.HandleFunc("/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		v, err := svc.Get(pathID(r))
+		if err != nil {
+			writeVerificationError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}).Methods(http.MethodGet)
+
+	// 審査: ボディの reason を読み取り、審査後の申請を返します
+	reviews := map[string]func(actor service.Actor, id uint, reason string) (*model.DealerVerification, error){
+		"approve": svc.Approve,
+		"reject":  svc.Reject,
+		"revoke":  svc.Revoke,
+	}
+	for name, fn := range reviews {
+		ar.HandleFunc("/{id:[0-9]+}/"+name, func(w http.ResponseWriter, r *http.Request) {
+			reason, err := decodeReason(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			v, err := fn(adminActor(r), pathID(r), reason)
+			if err != nil {
+				writeVerificationError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, v)
+		}).Methods(http.MethodPost)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/ksj/car-auction/internal/rbac"
 	"github.com/ksj/car-auction/internal/ws"
 )
 
@@ -17,6 +19,7 @@ var upgrader = websocket.Upgrader{
 
 // RegisterWSRoutes は WebSocket エンドポイントを登録します。
 // /ws/auctions/{id} に接続されたクライアントを指定のオークションハブに登録します。
+// ?token= クエリまたは Authorization ヘッダーで JWT が渡された場合は認証済みの閲覧者として扱います。
 func RegisterWSRoutes(r *mux.Router, hub *ws.Hub) {
 	r.HandleFunc("/ws/auctions/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
 		// パスパラメータからオークション ID を取得
@@ -32,6 +35,7 @@ func RegisterWSRoutes(r *mux.Router, hub *ws.Hub) {
 		}
 		log.Printf("WS: client connected to auction %d", aid)
 		client := &ws.Client{Conn: conn, Send: make(chan []byte, 16)}
+		client.UserID, client.Bidder = wsIdentity(r)
 		hub.Register(uint(aid), client)
 
 		// 読み取りゴルーチン: クライアントからのメッセージを読み捨て、切断時にクリーンアップ
@@ -55,3 +59,29 @@ func RegisterWSRoutes(r *mux.Router, hub *ws.Hub) {
 		}()
 	})
 }
+
+// wsIdentity は WebSocket 接続要求から任意の JWT を取り出して検証します。
+// ブラウザの WebSocket API はヘッダーを設定できないため ?token= クエリも受け付けます。
+// トークンが無い、無効、またはアカウントが停止・削除済みか失効済みの世代の場合は匿名 (0, false) として扱います。
+// 2 つ目の戻り値は入札権限を持つかどうかです（閲覧中の入札者数の集計に使います）。
+func wsIdentity(r *http.Request) (uint, bool) {
+	raw := r.URL.Query().Get("token")
+	if raw == "" {
+		raw = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if raw == "" {
+		return 0, false
+	}
+	userID, roles, version, err := parseToken(raw)
+	if err != nil {
+		log.Printf("WS: ignoring invalid token: %v", err)
+		return 0, false
+	}
+	if accountCheck != nil {
+		if err := accountCheck(userID, version); err != nil {
+			log.Printf("WS: ignoring token for user %d: %v", userID, err)
+			return 0, false
+		}
+	}
+	return userID, rbac.Can(roles, rbac.BidPlace)
+}
@@ -5,9 +5,11 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/ksj/car-auction/internal/kdf"
 )
 
 type Config struct {
@@ -15,6 +17,101 @@ type Config struct {
 	DSN        string
 	JwtSecret  []byte
 	AuctionTTL time.Duration
+
+	// JWT の署名: JwtAlg は HS256 (JWT_SECRET で署名)・RS256・EdDSA のいずれかで、検証でもこのアルゴリズムのみを受け付けます
+	// RS256 / EdDSA の鍵は JwtKeyStore ("memory" または "db") に保存し、JwtKeyRotation ごとに新しい鍵に切り替えます（0 はローテーションしない）
+	// JwtIssuer・JwtAudience は発行するトークンの iss・aud で、検証ではこの値との一致を必須とします
+	JwtAlg         string
+	JwtIssuer      string
+	JwtAudience    string
+	JwtKeyStore    string
+	JwtKeyRotation time.Duration
+
+	// AccessTokenTTL はアクセストークン (JWT) の有効期限、RefreshTokenTTL はリフレッシュトークンの有効期限です
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// WSPresenceInterval は viewer_count イベントの配信間隔です
+	WSPresenceInterval time.Duration
+
+	// SMTP 設定 (SMTPHost が空の場合はメールをログ出力のみ)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	// MailDir は SMTP 未設定時にメールを .eml ファイルとして保存するディレクトリです（空ならログ出力のみ）
+	MailDir string
+
+	// AppURL はメール本文のリンク（メールアドレス確認・パスワード再設定）に使うフロントエンドの URL です
+	AppURL string
+	// RequireEmailVerification が true の場合、メールアドレスが未確認のユーザーは入札できません
+	RequireEmailVerification bool
+
+	// アップロードファイルの保存先 ("local" または "s3")
+	StorageBackend string
+	StorageDir     string
+	// StorageSigningKey はダウンロード URL の署名鍵です
+	// 未設定なら JWT_SECRET から用途ラベル付きの HKDF で導出し、JWT の鍵と同じ値は使いません
+	StorageSigningKey []byte
+	StorageURLTTL     time.Duration
+
+	// S3 互換ストレージ設定 (STORAGE_BACKEND=s3 の場合)
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+
+	// 二要素認証 (TOTP): 認証アプリに表示する発行者名と、二要素認証を必須とする操作
+	// MFABidThreshold 以上の入札（0 は制限なし）と、MFARequiredForSelling が true の場合の出品には二要素認証の有効化が必要です
+	MFAIssuer             string
+	MFABidThreshold       int
+	MFARequiredForSelling bool
+
+	// 業者の本人確認 (KYC): KYCRequiredForBidding が true の場合はすべての入札、
+	// そうでなければ KYCBidThreshold 以上の入札（0 は制限なし）に本人確認の承認が必要です
+	KYCRequiredForBidding bool
+	KYCBidThreshold       int
+
+	// ログインの総当たり対策: LoginAttemptStore は失敗回数の保存先 ("memory" または "db")
+	// LoginFreeAttempts 回を超える連続失敗には段階的な待ち時間をかけ、アカウントは LoginAccountLockThreshold 回、
+	// 接続元 IP は LoginIPLockThreshold 回の連続失敗で LoginLockoutDuration の間ロックします（0 はロックしない）
+	LoginAttemptStore         string
+	LoginFreeAttempts         int
+	LoginAccountLockThreshold int
+	LoginIPLockThreshold      int
+	LoginLockoutDuration      time.Duration
+
+	// レート制限: RateLimitStore はトークンバケットの保存先 ("memory" または "db")
+	// API 全体は接続元 IP ごと、アカウント操作 (サインアップ・ログインなど) は接続元 IP ごと、
+	// 入札はユーザーごとに 1 分あたりの回数で制限します（0 は制限しない）
+	RateLimitStore         string
+	RateLimitAPIPerMinute  int
+	RateLimitAuthPerMinute int
+	RateLimitBidsPerMinute int
+
+	// 外部の IdP (OpenID Connect) によるログイン（OIDCIssuer が空なら無効）
+	// OIDCRedirectURL は IdP に登録したコールバック URL (<API の URL>/auth/oidc/callback) です
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	// OIDCRoleClaim の値を OIDCRoleMap ("group=role,...") でロールに対応付けます
+	OIDCRoleClaim string
+	OIDCRoleMap   string
+	// OIDCAutoProvision が true なら、対応するユーザーが無い IdP のアカウントのユーザーを OIDCDefaultRole で作成します
+	OIDCAutoProvision bool
+	OIDCDefaultRole   string
+
+	// OrgDefaultCreditLimit は新しい組織の与信枠です（0 は無制限）
+	OrgDefaultCreditLimit int
+
+	// 起動時に用意する管理者アカウント（ADMIN_EMAIL が空なら作成しません）
+	AdminEmail    string
+	AdminPassword string
 }
 
 var Cfg *Config
@@ -35,15 +132,178 @@ func Load() {
 	if secret == "" {
 		log.Fatal("JWT_SECRET is required")
 	}
+	storageKey := []byte(os.Getenv("STORAGE_SIGNING_KEY"))
+	if len(storageKey) == 0 {
+		storageKey = deriveKey(secret, "car-auction storage url signing")
+	}
 	ttl, err := strconv.Atoi(os.Getenv("AUCTION_TTL_MINUTES"))
 	if err != nil || ttl <= 0 {
 		ttl = 60
 	}
 
+	keyRotation, err := strconv.Atoi(os.Getenv("JWT_KEY_ROTATION_HOURS"))
+	if err != nil || keyRotation < 0 {
+		keyRotation = 24 * 30
+	}
+
+	accessTTL, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES"))
+	if err != nil || accessTTL <= 0 {
+		accessTTL = 15
+	}
+	refreshTTL, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_HOURS"))
+	if err != nil || refreshTTL <= 0 {
+		refreshTTL = 24 * 30
+	}
+
+	presence, err := strconv.Atoi(os.Getenv("WS_PRESENCE_INTERVAL_SECONDS"))
+	if err != nil || presence <= 0 {
+		presence = 10
+	}
+
+	urlTTL, err := strconv.Atoi(os.Getenv("STORAGE_URL_TTL_MINUTES"))
+	if err != nil || urlTTL <= 0 {
+		urlTTL = 60
+	}
+	pathStyle, err := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+	if err != nil {
+		pathStyle = true
+	}
+	requireVerified, err := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))
+	if err != nil {
+		requireVerified = true
+	}
+	mfaBid, err := strconv.Atoi(os.Getenv("MFA_BID_THRESHOLD"))
+	if err != nil || mfaBid < 0 {
+		mfaBid = 0
+	}
+	mfaSelling, _ := strconv.ParseBool(os.Getenv("MFA_REQUIRED_FOR_SELLING"))
+	kycBidding, _ := strconv.ParseBool(os.Getenv("KYC_REQUIRED_FOR_BIDDING"))
+	kycBid, err := strconv.Atoi(os.Getenv("KYC_BID_THRESHOLD"))
+	if err != nil || kycBid < 0 {
+		kycBid = 0
+	}
+	loginFree, err := strconv.Atoi(os.Getenv("LOGIN_FREE_ATTEMPTS"))
+	if err != nil || loginFree < 0 {
+		loginFree = 3
+	}
+	accountLock, err := strconv.Atoi(os.Getenv("LOGIN_ACCOUNT_LOCK_THRESHOLD"))
+	if err != nil || accountLock < 0 {
+		accountLock = 10
+	}
+	ipLock, err := strconv.Atoi(os.Getenv("LOGIN_IP_LOCK_THRESHOLD"))
+	if err != nil || ipLock < 0 {
+		ipLock = 100
+	}
+	lockout, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_MINUTES"))
+	if err != nil || lockout <= 0 {
+		lockout = 15
+	}
+	rateAPI, err := strconv.Atoi(os.Getenv("RATE_LIMIT_API_PER_MINUTE"))
+	if err != nil || rateAPI < 0 {
+		rateAPI = 600
+	}
+	rateAuth, err := strconv.Atoi(os.Getenv("RATE_LIMIT_AUTH_PER_MINUTE"))
+	if err != nil || rateAuth < 0 {
+		rateAuth = 30
+	}
+	rateBids, err := strconv.Atoi(os.Getenv("RATE_LIMIT_BIDS_PER_MINUTE"))
+	if err != nil || rateBids < 0 {
+		rateBids = 30
+	}
+	oidcAutoProvision, _ := strconv.ParseBool(os.Getenv("OIDC_AUTO_PROVISION"))
+	orgCredit, err := strconv.Atoi(os.Getenv("ORG_DEFAULT_CREDIT_LIMIT"))
+	if err != nil || orgCredit < 0 {
+		orgCredit = 0
+	}
+
 	Cfg = &Config{
 		Port:       port,
 		DSN:        dsn,
 		JwtSecret:  []byte(secret),
 		AuctionTTL: time.Duration(ttl) * time.Minute,
+
+		JwtAlg:         getenv("JWT_ALG", "HS256"),
+		JwtIssuer:      getenv("JWT_ISSUER", "car-auction"),
+		JwtAudience:    getenv("JWT_AUDIENCE", "car-auction-api"),
+		JwtKeyStore:    getenv("JWT_KEY_STORE", "db"),
+		JwtKeyRotation: time.Duration(keyRotation) * time.Hour,
+
+		AccessTokenTTL:  time.Duration(accessTTL) * time.Minute,
+		RefreshTokenTTL: time.Duration(refreshTTL) * time.Hour,
+
+		WSPresenceInterval: time.Duration(presence) * time.Second,
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getenv("SMTP_PORT", "587"),
+		SMTPUser:     os.Getenv("SMTP_USER"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     getenv("MAIL_FROM", "no-reply@car-auction.local"),
+		MailDir:      os.Getenv("MAIL_DIR"),
+
+		AppURL:                   strings.TrimRight(getenv("APP_URL", "http://localhost:5173"), "/"),
+		RequireEmailVerification: requireVerified,
+
+		StorageBackend:    getenv("STORAGE_BACKEND", "local"),
+		StorageDir:        getenv("STORAGE_DIR", "uploads"),
+		StorageSigningKey: storageKey,
+		StorageURLTTL:     time.Duration(urlTTL) * time.Minute,
+
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    getenv("S3_REGION", "us-east-1"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle: pathStyle,
+
+		MFAIssuer:             getenv("MFA_ISSUER", "car-auction"),
+		MFABidThreshold:       mfaBid,
+		MFARequiredForSelling: mfaSelling,
+
+		KYCRequiredForBidding: kycBidding,
+		KYCBidThreshold:       kycBid,
+
+		LoginAttemptStore:         getenv("LOGIN_ATTEMPT_STORE", "memory"),
+		LoginFreeAttempts:         loginFree,
+		LoginAccountLockThreshold: accountLock,
+		LoginIPLockThreshold:      ipLock,
+		LoginLockoutDuration:      time.Duration(lockout) * time.Minute,
+
+		RateLimitStore:         getenv("RATE_LIMIT_STORE", "memory"),
+		RateLimitAPIPerMinute:  rateAPI,
+		RateLimitAuthPerMinute: rateAuth,
+		RateLimitBidsPerMinute: rateBids,
+
+		OIDCIssuer:        os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:        strings.Fields(getenv("OIDC_SCOPES", "openid email profile")),
+		OIDCRoleClaim:     getenv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMap:       os.Getenv("OIDC_ROLE_MAP"),
+		OIDCAutoProvision: oidcAutoProvision,
+		OIDCDefaultRole:   getenv("OIDC_DEFAULT_ROLE", "bidder"),
+
+		OrgDefaultCreditLimit: orgCredit,
+
+		AdminEmail:    os.Getenv("ADMIN_EMAIL"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
+	}
+}
+
+// deriveKey は secret から purpose 用の 32 バイトの鍵を HKDF-SHA256 で導出します
+// 一つのシークレットを複数の用途に使う場合でも、用途ごとに独立した鍵になります
+func deriveKey(secret, purpose string) []byte {
+	key, err := kdf.Key([]byte(secret), purpose)
+	if err != nil {
+		log.Fatalf("derive %s key: %v", purpose, err)
+	}
+	return key
+}
+
+// getenv は環境変数が空の場合に既定値を返します
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return def
 }
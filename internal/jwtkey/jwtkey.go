@@ -0,0 +1,468 @@
+// Package jwtkey はアプリが発行する JWT の署名鍵の管理と、署名・検証・公開鍵 (JWKS) の提供を扱います。
+// HS256 は JWT_SECRET を鍵とし、RS256 / EdDSA は kid で識別する複数の鍵を保存先で共有して定期的にローテーションします。
+package jwtkey
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ksj/car-auction/internal/kdf"
+	"github.com/ksj/car-auction/internal/model"
+)
+
+// 署名アルゴリズム
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	// ErrInvalidToken は署名・アルゴリズム・発行者 (iss)・対象 (aud)・有効期限の検証に失敗した場合に返されます
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrNoSigningKey は署名鍵が設定されていない・使用開始日時を過ぎた鍵が無い場合に返されます
+	ErrNoSigningKey = errors.New("no signing key")
+)
+
+const (
+	// activationDelay はローテーションで作成した鍵を JWKS に公開してから署名に使い始めるまでの時間です
+	// 他のサービスがキャッシュした JWKS を取得し直す前に、新しい鍵のトークンが届かないようにします
+	activationDelay = 10 * time.Minute
+	// JWKSMaxAge は JWKS の応答をキャッシュしてよい時間です（activationDelay より短くします）
+	JWKSMaxAge = 5 * time.Minute
+	// rsaKeyBits は RS256 の鍵長です
+	rsaKeyBits = 2048
+)
+
+// Store は RS256 / EdDSA の署名鍵の保存先です（複数のサーバーで共有する場合は DB）
+type Store interface {
+	// List はすべての鍵を使用開始日時の古い順に返します
+	List() ([]model.SigningKey, error)
+	Create(k *model.SigningKey) error
+	Delete(kids []string) error
+}
+
+// Options は署名鍵と検証の設定です
+type Options struct {
+	// Alg は署名アルゴリズム (HS256 / RS256 / EdDSA) で、検証でもこのアルゴリズムのトークンのみを受け付けます
+	Alg string
+	// Issuer・Audience は発行するトークンの iss・aud クレームで、検証ではこの値と一致することを必須とします
+	Issuer   string
+	Audience string
+	// Secret は HS256 の鍵で、RS256 / EdDSA では保存する秘密鍵の暗号化に使います
+	Secret []byte
+	// RotationInterval ごとに新しい鍵に切り替えます（0 ならローテーションしません, HS256 では使用しません）
+	RotationInterval time.Duration
+	// Retention は次の鍵に切り替えた後も古い鍵で検証を続ける期間です（発行するトークンの最長の有効期限以上にします）
+	Retention time.Duration
+}
+
+// signingKey は読み込んだ署名鍵です
+type signingKey struct {
+	kid         string
+	alg         string
+	private     crypto.Signer
+	activatesAt time.Time
+}
+
+// Set はアプリの JWT の署名鍵の組です
+// 署名には使用開始日時を過ぎた最新の鍵を使い、検証には保存先にあるすべての鍵を kid で選んで使います
+type Set struct {
+	opts  Options
+	store Store
+
+	mu   sync.RWMutex
+	keys []*signingKey
+}
+
+// New は署名鍵の組を生成します
+// RS256 / EdDSA では Maintain で保存先から鍵を読み込む（無ければ作成する）まで署名できません
+func New(store Store, opts Options) (*Set, error) {
+	switch opts.Alg {
+	case AlgHS256:
+		if len(opts.Secret) == 0 {
+			return nil, errors.New("jwtkey: HS256 requires a secret")
+		}
+	case AlgRS256, AlgEdDSA:
+		if store == nil || len(opts.Secret) == 0 {
+			return nil, fmt.Errorf("jwtkey: %s requires a key store and a secret", opts.Alg)
+		}
+	default:
+		return nil, fmt.Errorf("jwtkey: unsupported algorithm %q", opts.Alg)
+	}
+	return &Set{opts: opts, store: store}, nil
+}
+
+// Alg は署名アルゴリズムを返します
+func (s *Set) Alg() string { return s.opts.Alg }
+
+// Sign は iss・aud・iat を加えて claims に署名します（RS256 / EdDSA ではヘッダーに kid を付けます）
+// s が nil なら ErrNoSigningKey を返します
+func (s *Set) Sign(claims jwt.MapClaims) (string, error) {
+	if s == nil {
+		return "", ErrNoSigningKey
+	}
+	c := jwt.MapClaims{"iss": s.opts.Issuer, "aud": s.opts.Audience, "iat": time.Now().Unix()}
+	for k, v := range claims {
+		c[k] = v
+	}
+	if s.opts.Alg == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(s.opts.Secret)
+	}
+	k := s.current(time.Now())
+	if k == nil {
+		return "", ErrNoSigningKey
+	}
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(k.alg), c)
+	t.Header["kid"] = k.kid
+	return t.SignedString(k.private)
+}
+
+// current は使用開始日時を過ぎた最新の鍵を返します
+func (s *Set) current(now time.Time) *signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].activatesAt.After(now) {
+			return s.keys[i]
+		}
+	}
+	return nil
+}
+
+// Parse はトークンを検証してクレームを返します
+// 設定したアルゴリズム以外（alg=none や、公開鍵を HMAC の鍵とする取り違えを含む）・iss・aud の不一致・exp の無いトークンは拒否します
+// s が nil ならすべてのトークンを拒否します
+func (s *Set) Parse(raw string) (jwt.MapClaims, error) {
+	if s == nil {
+		return nil, ErrInvalidToken
+	}
+	token, err := jwt.Parse(raw, s.keyFunc,
+		jwt.WithValidMethods([]string{s.opts.Alg}),
+		jwt.WithIssuer(s.opts.Issuer),
+		jwt.WithAudience(s.opts.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (s *Set) keyFunc(t *jwt.Token) (any, error) {
+	if s.opts.Alg == AlgHS256 {
+		return s.opts.Secret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.kid == kid && k.alg == t.Method.Alg() {
+			return k.private.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWK は JWKS で公開する公開鍵 1 件です (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS は検証に使う公開鍵の一覧です（使用開始前の鍵を含み、HS256 では空です）
+func (s *Set) JWKS() []JWK {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []JWK{}
+	for _, k := range s.keys {
+		j := JWK{Kid: k.kid, Use: "sig", Alg: k.alg}
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			j.Kty = "RSA"
+			j.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			j.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			j.Kty, j.Crv = "OKP", "Ed25519"
+			j.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		out = append(out, j)
+	}
+	return out
+}
+
+// Maintain は保存先から鍵を読み込み、必要ならローテーションと古い鍵の削除を行います（HS256 では何もしません）
+//   - 鍵が無ければ作成し、すぐに使い始めます
+//   - 最新の鍵の使用開始から RotationInterval が経つ前に次の鍵を作成し、activationDelay の後に切り替えます
+//   - 次の鍵に切り替えてから Retention が過ぎた鍵は削除します
+//
+// 複数のサーバーが同時にローテーションしても、鍵が 1 つ余分に増えるだけで検証には影響しません
+func (s *Set) Maintain(now time.Time) error {
+	if s.opts.Alg == AlgHS256 {
+		return nil
+	}
+	all, err := s.store.List()
+	if err != nil {
+		return err
+	}
+	// アルゴリズムを変更した場合、以前のアルゴリズムの鍵は検証でも受け付けないため削除します
+	var stored []model.SigningKey
+	var expired []string
+	for _, k := range all {
+		if k.Alg == s.opts.Alg {
+			stored = append(stored, k)
+		} else {
+			expired = append(expired, k.KID)
+		}
+	}
+	switch {
+	case len(stored) == 0:
+		k, err := s.create(now)
+		if err != nil {
+			return err
+		}
+		stored = append(stored, *k)
+	case s.opts.RotationInterval > 0:
+		latest := stored[len(stored)-1].ActivatesAt
+		if next := latest.Add(s.opts.RotationInterval); !now.Before(next.Add(-activationDelay)) {
+			k, err := s.create(maxTime(next, now.Add(activationDelay)))
+			if err != nil {
+				return err
+			}
+			stored = append(stored, *k)
+		}
+	}
+	// 次の鍵に切り替わってから Retention を過ぎた鍵を削除
+	keep := 0
+	for keep+1 < len(stored) && stored[keep+1].ActivatesAt.Add(s.opts.Retention).Before(now) {
+		expired = append(expired, stored[keep].KID)
+		keep++
+	}
+	if err := s.store.Delete(expired); err != nil {
+		return err
+	}
+	return s.load(stored[keep:])
+}
+
+// Run は interval ごとに Maintain を実行します（ctx が終了するまで）
+func (s *Set) Run(ctx context.Context, interval time.Duration) {
+	if s.opts.Alg == AlgHS256 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.Maintain(now); err != nil {
+				log.Printf("JWT KEYS: maintenance failed: %v", err)
+			}
+		}
+	}
+}
+
+// create は新しい鍵を生成して保存します
+func (s *Set) create(activatesAt time.Time) (*model.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	if s.opts.Alg == AlgRS256 {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal(der)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	k := &model.SigningKey{
+		KID:         hex.EncodeToString(id),
+		Alg:         s.opts.Alg,
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.store.Create(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// load は保存された鍵を読み込んで入れ替えます（読み込み済みの鍵は復号し直しません）
+func (s *Set) load(stored []model.SigningKey) error {
+	s.mu.RLock()
+	loaded := make(map[string]*signingKey, len(s.keys))
+	for _, k := range s.keys {
+		loaded[k.kid] = k
+	}
+	s.mu.RUnlock()
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, sk := range stored {
+		if k, ok := loaded[sk.KID]; ok {
+			keys = append(keys, &signingKey{kid: k.kid, alg: k.alg, private: k.private, activatesAt: sk.ActivatesAt})
+			continue
+		}
+		der, err := s.open(sk.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("jwtkey: decrypt key %s: %w", sk.KID, err)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return fmt.Errorf("jwtkey: parse key %s: %w", sk.KID, err)
+		}
+		private, ok := parsed.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("jwtkey: unsupported key %s", sk.KID)
+		}
+		keys = append(keys, &signingKey{kid: sk.KID, alg: sk.Alg, private: private, activatesAt: sk.ActivatesAt})
+	}
+	slices.SortStableFunc(keys, func(a, b *signingKey) int { return a.activatesAt.Compare(b.activatesAt) })
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// sealPurpose は秘密鍵の暗号化に使う鍵を Secret から導出する際の用途ラベルです
+const sealPurpose = "car-auction jwt private key sealing"
+
+// aead は Secret から導いた鍵の AES-256-GCM です
+func (s *Set) aead() (cipher.AEAD, error) {
+	key, err := kdf.Key(s.opts.Secret, sealPurpose)
+	if err != nil {
+		return nil, err
+	}
+	return newGCM(key)
+}
+
+// legacyAEAD は HKDF 導入前に保存した鍵を復号するための AES-256-GCM です
+// 当時の鍵がローテーションで削除されるまでの間だけ open で使います
+func (s *Set) legacyAEAD() (cipher.AEAD, error) {
+	sum := sha256.Sum256(append([]byte("jwt-signing-key:"), s.opts.Secret...))
+	return newGCM(sum[:])
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal は秘密鍵を暗号化します（nonce を先頭に付けます）
+func (s *Set) seal(plain []byte) ([]byte, error) {
+	gcm, err := s.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// open は seal で暗号化した秘密鍵を復号します（復号できなければ HKDF 導入前の鍵でも試します）
+func (s *Set) open(sealed []byte) ([]byte, error) {
+	var plain []byte
+	var err error
+	for _, aead := range []func() (cipher.AEAD, error){s.aead, s.legacyAEAD} {
+		var gcm cipher.AEAD
+		if gcm, err = aead(); err != nil {
+			return nil, err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return nil, errors.New("ciphertext too short")
+		}
+		if plain, err = gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, err
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// MemoryStore は署名鍵をメモリに保持する Store です（単一サーバー向け, 再起動で鍵が変わり発行済みのアクセストークンは無効になります）
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys []model.SigningKey
+}
+
+// NewMemoryStore は空の MemoryStore を生成します
+func NewMemoryStore() *MemoryStore { return &MemoryStore{} }
+
+// List はすべての鍵を使用開始日時の古い順に返します
+func (m *MemoryStore) List() ([]model.SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := slices.Clone(m.keys)
+	slices.SortStableFunc(out, func(a, b model.SigningKey) int { return a.ActivatesAt.Compare(b.ActivatesAt) })
+	return out, nil
+}
+
+// Create は鍵を追加します
+func (m *MemoryStore) Create(k *model.SigningKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = append(m.keys, *k)
+	return nil
+}
+
+// Delete は鍵を削除します
+func (m *MemoryStore) Delete(kids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = slices.DeleteFunc(m.keys, func(k model.SigningKey) bool { return slices.Contains(kids, k.KID) })
+	return nil
+}
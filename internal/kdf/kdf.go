@@ -0,0 +1,13 @@
+// Package kdf は一つのシークレットから用途ごとに独立した鍵を導出します。
+// JWT_SECRET のように複数の用途に使うシークレットは、用途ごとの purpose を付けてこのパッケージで分けます。
+package kdf
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+)
+
+// Key は secret から purpose 用の 32 バイトの鍵を HKDF-SHA256 で導出します
+func Key(secret []byte, purpose string) ([]byte, error) {
+	return hkdf.Key(sha256.New, secret, nil, purpose, 32)
+}
@@ -0,0 +1,94 @@
+// Package mail はメール送信を抽象化します。
+// SMTP が設定されていない環境では送信内容をファイルまたはログに出力するだけの実装を使用します。
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Sender はメール送信の抽象インターフェースです
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// New は host が設定されていれば SMTPSender を、dir が設定されていれば FileSender を、
+// どちらも無ければ LogSender を返します
+func New(host, port, user, password, from, dir string) Sender {
+	if host == "" {
+		if dir != "" {
+			return &FileSender{Dir: dir, From: from}
+		}
+		return LogSender{}
+	}
+	s := &SMTPSender{Addr: net.JoinHostPort(host, port), From: from}
+	if user != "" {
+		s.Auth = smtp.PlainAuth("", user, password, host)
+	}
+	return s
+}
+
+// SMTPSender は SMTP サーバー経由でプレーンテキストのメールを送信します
+type SMTPSender struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// Send は UTF-8 のプレーンテキストメールを送信します
+func (s *SMTPSender) Send(to, subject, body string) error {
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{to}, Message(s.From, to, subject, body))
+}
+
+// Message は件名を MIME エンコードした RFC 5322 形式のメッセージを組み立てます
+func Message(from, to, subject, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(body)
+	return buf.Bytes()
+}
+
+// LogSender はメールを送信せず内容をログに出力します（開発用）
+type LogSender struct{}
+
+// Send は送信内容をログに出力します
+func (LogSender) Send(to, subject, body string) error {
+	log.Printf("MAIL: to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+// FileSender はメールを送信せず、1 通ずつ .eml ファイルとして保存します（開発用）
+// 確認メールのリンクなどをメールサーバー無しで確認できます
+type FileSender struct {
+	Dir  string
+	From string
+	seq  atomic.Uint64
+}
+
+// Send はメッセージを Dir/<日時>-<連番>.eml に書き出します
+func (s *FileSender) Send(to, subject, body string) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().Format("20060102-150405"), s.seq.Add(1))
+	path := filepath.Join(s.Dir, name)
+	if err := os.WriteFile(path, Message(s.From, to, subject, body), 0o644); err != nil {
+		return err
+	}
+	log.Printf("MAIL: to=%s subject=%q saved to %s", to, subject, path)
+	return nil
+}
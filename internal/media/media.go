@@ -0,0 +1,174 @@
+// Package media はアップロードされた画像を安全に処理するパイプラインです。
+//
+// クライアントが申告したファイル名や Content-Type は信用せず、先頭バイトから実際の形式を判定し、
+// JPEG / PNG / WebP のみを受け付けます。画像は一度デコードしてから再エンコードするため、
+// EXIF（GPS 位置情報を含む）などのメタデータや画像以外のデータは出力に残りません。
+// 出力ファイル名は内容の SHA-256 から生成し、複数サイズのサムネイルも作成します。
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+var (
+	// ErrUnsupportedImage は JPEG / PNG / WebP 以外のデータが渡された場合に返されます
+	ErrUnsupportedImage = errors.New("unsupported image type (JPEG, PNG or WebP only)")
+	// ErrImageTooLarge はファイルサイズまたは画素数が上限を超えた場合に返されます
+	ErrImageTooLarge = errors.New("image too large")
+)
+
+const (
+	// MaxBytes はアップロードを受け付ける最大ファイルサイズです
+	MaxBytes = 10 << 20
+	// MaxPixels はデコードを許可する最大画素数です（解凍爆弾対策）
+	MaxPixels = 40_000_000
+	// JPEGQuality は再エンコード時の JPEG 品質です
+	JPEGQuality = 85
+)
+
+// ThumbnailWidths は生成するサムネイルの幅 (px) です
+// 元画像より小さい幅のみ生成します
+var ThumbnailWidths = []int{320, 640, 1280}
+
+// Variant は再エンコードされた 1 サイズ分の画像です
+type Variant struct {
+	Name        string // 保存時のファイル名 (例: "<sha256>_w320.jpg")
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// Result は Process の結果です
+type Result struct {
+	Hash       string    // 再エンコード後の原寸画像の SHA-256 (hex)
+	Original   Variant   // 原寸（向き補正・メタデータ除去済み）
+	Thumbnails []Variant // 幅の小さい順
+}
+
+// Process は r から画像を読み込み、形式の判定・デコード・向き補正・再エンコード・サムネイル生成を行います
+func Process(r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	// 1) 実際の形式を先頭バイトから判定（拡張子やクライアントの申告は使わない）
+	var decode func(io.Reader) (image.Image, error)
+	var decodeConfig func(io.Reader) (image.Config, error)
+	sniffed := http.DetectContentType(data)
+	switch sniffed {
+	case "image/jpeg":
+		decode, decodeConfig = jpeg.Decode, jpeg.DecodeConfig
+	case "image/png":
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	case "image/webp":
+		decode, decodeConfig = webp.Decode, webp.DecodeConfig
+	default:
+		return nil, fmt.Errorf("%w: detected %s", ErrUnsupportedImage, sniffed)
+	}
+
+	// 2) デコード前に画素数を確認
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	// 3) EXIF の向き情報は再エンコードで失われるため、先に画素へ反映
+	if sniffed == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	// 4) 再エンコード: 透過を持ちうる PNG は PNG のまま、それ以外は JPEG
+	enc := encodeJPEG
+	if sniffed == "image/png" {
+		enc = encodePNG
+	}
+	orig, err := enc(img)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(orig.Data)
+	res := &Result{Hash: hex.EncodeToString(sum[:])}
+	b := img.Bounds()
+	orig.Width, orig.Height = b.Dx(), b.Dy()
+	orig.Name = res.Hash + extension(orig.ContentType)
+	res.Original = orig
+
+	// 5) サムネイル生成
+	for _, w := range ThumbnailWidths {
+		if w >= b.Dx() {
+			break
+		}
+		h := b.Dy() * w / b.Dx()
+		if h < 1 {
+			h = 1
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+		v, err := enc(dst)
+		if err != nil {
+			return nil, err
+		}
+		v.Width, v.Height = w, h
+		v.Name = fmt.Sprintf("%s_w%d%s", res.Hash, w, extension(v.ContentType))
+		res.Thumbnails = append(res.Thumbnails, v)
+	}
+	return res, nil
+}
+
+func encodeJPEG(img image.Image) (Variant, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return Variant{}, err
+	}
+	return Variant{ContentType: "image/jpeg", Data: buf.Bytes()}, nil
+}
+
+func encodePNG(img image.Image) (Variant, error) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return Variant{}, err
+	}
+	return Variant{ContentType: "image/png", Data: buf.Bytes()}, nil
+}
+
+// extension は出力形式の拡張子を返します
+func extension(contentType string) string {
+	if contentType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// ThumbnailKey は原寸画像のストレージキーから幅 w のサムネイルのキーを求めます
+// 例: "auctions/1/<sha256>.jpg" → "auctions/1/<sha256>_w320.jpg"
+func ThumbnailKey(key string, w int) string {
+	ext := path.Ext(key)
+	return fmt.Sprintf("%s_w%d%s", strings.TrimSuffix(key, ext), w, ext)
+}
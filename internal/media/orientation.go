@@ -0,0 +1,104 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// jpegOrientation は JPEG の APP1 (EXIF) セグメントから Orientation タグ (0x0112) を読み取ります
+// 見つからない、または解析できない場合は 1（回転なし）を返します
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xD9 || marker == 0xDA { // EOI / SOS 以降に EXIF は無い
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation は TIFF ヘッダー以降の IFD0 から Orientation を探します
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(tiff[4:]))
+	if off+2 > len(tiff) {
+		return 1
+	}
+	n := int(bo.Uint16(tiff[off:]))
+	for k := 0; k < n; k++ {
+		e := off + 2 + k*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 {
+			if v := int(bo.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation は EXIF Orientation (1〜8) に従って画像を回転・反転します
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // 5〜8 は縦横が入れ替わる
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 左右反転
+				dx, dy = w-1-x, y
+			case 3: // 180 度回転
+				dx, dy = w-1-x, h-1-y
+			case 4: // 上下反転
+				dx, dy = x, h-1-y
+			case 5: // 転置
+				dx, dy = y, x
+			case 6: // 時計回りに 90 度
+				dx, dy = h-1-y, x
+			case 7: // 反転転置
+				dx, dy = h-1-y, w-1-x
+			case 8: // 反時計回りに 90 度
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
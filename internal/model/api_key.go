@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// APIKey は販売店管理システムなどの機械的な連携に使う API キーです
+// キー本体は保存せず SHA-256 ハッシュのみを保存し、一覧では識別用の Prefix のみを表示します
+type APIKey struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"size:100;not null" json:"name"`
+	// Prefix はキーの先頭部分（"cak_1a2b3c4d" 形式）で、キーの照合と一覧での識別に使います
+	Prefix  string `gorm:"size:16;not null;uniqueIndex" json:"prefix"`
+	KeyHash string `gorm:"size:64;not null" json:"-"`
+	// Scopes はキーで使える権限です（作成時に所有者が持つ権限の範囲に限ります）
+	Scopes []string `gorm:"serializer:json;type:text" json:"scopes"`
+	// AllowedIPs は接続元 IP アドレス・CIDR の許可リストです（空ならすべて許可）
+	AllowedIPs []string   `gorm:"serializer:json;type:text" json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -8,16 +8,44 @@ type Auction struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	StartPrice  int       `json:"start_price"`
-	CreatedAt   time.Time `json:"created_at"`
-	EndAt       time.Time `json:"end_at"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+	EndAt       time.Time `gorm:"index" json:"end_at"`
 
-	SellerID uint  `gorm:"not null" json:"seller_id"`
+	// CurrentPrice は現在の最高入札額です（入札が無い場合は開始価格）
+	// 価格帯での検索・並び替えのため入札時に非正規化して保持します
+	CurrentPrice int `gorm:"index" json:"current_price"`
+
+	SellerID uint  `gorm:"not null;index" json:"seller_id"`
 	Seller   *User `gorm:"foreignKey:SellerID"`
-	Bids     []Bid `gorm:"constraint:OnDelete:CASCADE;"`
+	// OrgID は出品者が所属する組織です（個人の出品なら nil）
+	OrgID *uint `gorm:"index" json:"org_id,omitempty"`
+	Bids  []Bid `gorm:"constraint:OnDelete:CASCADE;"`
+
+	// Photos は Position 順のギャラリーです
+	Photos []AuctionPhoto `gorm:"foreignKey:AuctionID;constraint:OnDelete:CASCADE;" json:"photos"`
+
+	// CancelledAt は管理者がオークションを取り消した日時です（取り消し時に終了扱いになります）
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason string     `gorm:"size:255" json:"cancel_reason,omitempty"`
+
+	// InspectionStatus は第三者検査の状況です（"pending" の間は入札できません）
+	InspectionStatus string `gorm:"size:20;index" json:"inspection_status,omitempty"`
+
+	// ConditionReport は車両状態表です（未提出なら nil）
+	ConditionReport *ConditionReport `gorm:"foreignKey:AuctionID;constraint:OnDelete:CASCADE;" json:"condition_report,omitempty"`
 
-	Maker     string `json:"maker"`
-	ModelName string `json:"model_name"`
-	Mileage   int    `json:"mileage"`
-	Year      int    `json:"year"`
-	PhotoURL  string `json:"photo_url"`
+	Maker     string `gorm:"size:100;index:idx_auctions_maker_model,priority:1" json:"maker"`
+	ModelName string `gorm:"size:100;index:idx_auctions_maker_model,priority:2" json:"model_name"`
+	Mileage   int    `gorm:"index" json:"mileage"`
+	Year      int    `gorm:"index" json:"year"`
+	// VIN は ISO 3779 の車両識別番号、ChassisNumber は国内の車台番号です（どちらも任意）
+	VIN           string `gorm:"size:17;index" json:"vin,omitempty"`
+	ChassisNumber string `gorm:"size:32;index" json:"chassis_number,omitempty"`
+	// SpecMismatch は VIN・車台番号からデコードした値と出品者の入力が矛盾する項目です（カンマ区切り, 例: "maker,year"）
+	SpecMismatch string `gorm:"size:100" json:"spec_mismatch,omitempty"`
+	// PhotoKey はカバー写真のストレージキーです（ギャラリーのカバー変更時に同期されます）
+	PhotoKey string `gorm:"size:255" json:"photo_key,omitempty"`
+	// PhotoURL はカバー写真の配信用 URL です
+	// PhotoKey がある場合は取得時に署名付き URL が設定され、DB には外部 URL のみ保存されます
+	PhotoURL string `json:"photo_url"`
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// AuctionPhoto はオークションに添付された写真です
+// Position の昇順がギャラリーの表示順で、IsCover の写真が一覧のサムネイルになります
+type AuctionPhoto struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	AuctionID  uint      `gorm:"not null;index:idx_auction_photos_order,priority:1" json:"auction_id"`
+	StorageKey string    `gorm:"size:255;not null" json:"storage_key"`
+	Position   int       `gorm:"index:idx_auction_photos_order,priority:2" json:"position"`
+	Caption    string    `gorm:"size:255" json:"caption"`
+	IsCover    bool      `json:"is_cover"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// ThumbWidths は生成済みサムネイルの幅をカンマ区切りで保持します (例: "320,640")
+	ThumbWidths string `gorm:"size:100" json:"-"`
+
+	// URL / Srcset はストレージから算出する配信用 URL です（DB には保存しません）
+	URL    string `gorm:"-" json:"url"`
+	Srcset string `gorm:"-" json:"srcset"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// AuditLog は管理操作などの監査ログです（追記のみで更新・削除しません）
+type AuditLog struct {
+	ID      uint `gorm:"primaryKey" json:"id"`
+	ActorID uint `gorm:"index" json:"actor_id"`
+	// Action は "user.suspend" のような操作名です
+	Action     string `gorm:"size:50;index" json:"action"`
+	TargetType string `gorm:"size:30;index:idx_audit_logs_target,priority:1" json:"target_type"`
+	TargetID   uint   `gorm:"index:idx_audit_logs_target,priority:2" json:"target_id"`
+	Reason     string `gorm:"size:255" json:"reason,omitempty"`
+	// Detail は操作前後の値などを JSON で保持します
+	Detail    string    `gorm:"type:text" json:"detail,omitempty"`
+	IP        string    `gorm:"size:45" json:"ip,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -8,4 +8,9 @@ type Bid struct {
 	UserID    uint      `json:"user_id"`
 	Amount    int       `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// OrgID は入札者が組織として入札した場合の組織です（個人の入札なら nil）
+	OrgID *uint `gorm:"index" json:"org_id,omitempty"`
+	// VoidedAt は管理者が無効にした日時です。無効な入札は現在価格の計算から除外されます
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
+	VoidReason string     `gorm:"size:255" json:"void_reason,omitempty"`
 }
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// ConditionReport はオークション車両の仕様と状態を構造化した車両状態表です（オークションごとに 1 件）
+// 出品者または検査員が提出し、再提出すると内容が置き換わります
+type ConditionReport struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	AuctionID uint `gorm:"not null;uniqueIndex" json:"auction_id"`
+
+	Transmission string `gorm:"size:20;index" json:"transmission"`
+	FuelType     string `gorm:"size:20;index" json:"fuel_type"`
+	BodyType     string `gorm:"size:20;index" json:"body_type"`
+	Drivetrain   string `gorm:"size:10;index" json:"drivetrain"`
+	// EngineDisplacement は排気量 (cc) です。電気自動車は 0
+	EngineDisplacement int    `gorm:"index" json:"engine_displacement"`
+	Colour             string `gorm:"size:20;index" json:"colour"`
+	Owners             int    `gorm:"index" json:"owners"`
+	// AccidentHistory は修復歴の有無です
+	AccidentHistory bool `gorm:"index" json:"accident_history"`
+
+	// Grade はオークション評価点 ("S", "6"〜"1", 修復歴車は "R"/"RA") です
+	Grade string `gorm:"size:4" json:"grade"`
+	// GradeScore は Grade を数値化した値で、評価点による絞り込みに使います
+	GradeScore float64 `gorm:"index" json:"-"`
+
+	// Damages はパネルごとの損傷箇所です
+	Damages []PanelDamage `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE;" json:"damages"`
+
+	SubmittedBy uint      `gorm:"not null" json:"submitted_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PanelDamage は車両状態表の展開図上の 1 パネルの損傷です
+// Code は業界標準の記号と程度 (例: "A2" = 中程度の傷, "U1" = 小さな凹み, "X" = 要交換) です
+type PanelDamage struct {
+	ID       uint   `gorm:"primaryKey" json:"-"`
+	ReportID uint   `gorm:"not null;index" json:"-"`
+	Panel    string `gorm:"size:30" json:"panel"`
+	Code     string `gorm:"size:4" json:"code"`
+	Note     string `gorm:"size:255" json:"note,omitempty"`
+}
+
+// 車両状態表の列挙値
+var (
+	Transmissions = []string{"at", "mt", "cvt", "dct"}
+	FuelTypes     = []string{"gasoline", "diesel", "hybrid", "plugin_hybrid", "electric", "hydrogen", "lpg"}
+	BodyTypes     = []string{"sedan", "hatchback", "wagon", "suv", "minivan", "coupe", "convertible", "pickup", "truck", "van", "kei"}
+	Drivetrains   = []string{"fwd", "rwd", "awd", "4wd"}
+	Colours       = []string{"white", "pearl", "black", "silver", "gray", "red", "blue", "green", "yellow", "orange", "brown", "beige", "gold", "purple", "other"}
+
+	// Panels は展開図のパネル名です
+	Panels = []string{
+		"front_bumper", "hood", "roof", "trunk", "rear_bumper",
+		"front_left_fender", "front_right_fender", "rear_left_fender", "rear_right_fender",
+		"front_left_door", "front_right_door", "rear_left_door", "rear_right_door",
+		"left_sill", "right_sill", "windshield", "rear_window",
+	}
+	// DamageCodes は損傷記号です（A: 傷, U: 凹み, W: 波, S: 錆, C: 腐食, P: 塗装, Y: 割れ, X: 要交換, XX: 交換済み, G: 飛び石）
+	DamageCodes = []string{
+		"A1", "A2", "A3", "U1", "U2", "U3", "W1", "W2", "W3",
+		"S1", "S2", "C1", "C2", "P", "Y1", "Y2", "X", "XX", "G",
+	}
+
+	// Grades は評価点と絞り込み用の数値です（修復歴車 R/RA は最低評価扱い）
+	Grades = map[string]float64{
+		"S": 7, "6": 6, "5": 5, "4.5": 4.5, "4": 4, "3.5": 3.5, "3": 3, "2": 2, "1": 1,
+		"RA": 0.5, "R": 0,
+	}
+)
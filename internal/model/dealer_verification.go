@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// 業者の本人確認（KYC）の申請の状態
+//
+//	draft ─submit→ pending ─approve→ approved ─revoke→ revoked
+//	                  └─reject→ rejected
+//	rejected / revoked の後は新しい申請 (draft) を作り直します
+const (
+	VerificationDraft    = "draft"
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+	VerificationRevoked  = "revoked"
+)
+
+// 本人確認の提出書類の種類
+const (
+	// DocAntiqueDealerLicence は古物商許可証です
+	DocAntiqueDealerLicence = "antique_dealer_licence"
+	// DocCompanyRegistration は登記事項証明書（法人）または開業届（個人事業主）です
+	DocCompanyRegistration = "company_registration"
+	// DocIdentity は代表者の本人確認書類（運転免許証など）です
+	DocIdentity = "identity"
+)
+
+var (
+	// VerificationDocumentKinds は提出できる書類の種類です
+	VerificationDocumentKinds = []string{DocAntiqueDealerLicence, DocCompanyRegistration, DocIdentity}
+	// RequiredVerificationDocuments は申請に必須の書類です
+	RequiredVerificationDocuments = []string{DocAntiqueDealerLicence, DocCompanyRegistration}
+)
+
+// DealerVerification は業者が提出する本人確認の申請です
+// 書類を添付して提出し、管理者が審査して承認・却下します（承認されると User.DealerVerifiedAt が設定されます）
+type DealerVerification struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Status string `gorm:"size:20;not null;index" json:"status"`
+	// CompanyName は商号（屋号）、LicenceNumber は古物商許可番号、CorporateNumber は法人番号（個人事業主は空）です
+	CompanyName     string `gorm:"size:100" json:"company_name"`
+	LicenceNumber   string `gorm:"size:12" json:"licence_number"`
+	CorporateNumber string `gorm:"size:13" json:"corporate_number,omitempty"`
+
+	Documents []VerificationDocument `gorm:"foreignKey:VerificationID;constraint:OnDelete:CASCADE;" json:"documents"`
+
+	// ReviewerID・ReviewReason は最後に審査（承認・却下・取り消し）した管理者とその理由です
+	ReviewerID   *uint      `json:"reviewer_id,omitempty"`
+	ReviewReason string     `gorm:"size:255" json:"review_reason,omitempty"`
+	SubmittedAt  *time.Time `json:"submitted_at,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// VerificationDocument は本人確認の申請に添付した書類です（画像または PDF）
+type VerificationDocument struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	VerificationID uint      `gorm:"not null;index" json:"verification_id"`
+	Kind           string    `gorm:"size:30;not null" json:"kind"`
+	StorageKey     string    `gorm:"size:255;not null" json:"-"`
+	ContentType    string    `gorm:"size:50" json:"content_type"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// ThumbWidths は画像の場合に生成したサムネイルの幅をカンマ区切りで保持します（PDF は空です）
+	ThumbWidths string `gorm:"size:100" json:"thumb_widths,omitempty"`
+
+	// URL はストレージから算出する署名付きの配信用 URL です（DB には保存しません）
+	URL string `gorm:"-" json:"url"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// 検査の状態
+//
+//	requested → assigned → completed
+//	    ↑          │
+//	    └ decline ─┘      requested / assigned からは出品者が cancelled にできます
+const (
+	InspectionRequested = "requested"
+	InspectionAssigned  = "assigned"
+	InspectionCompleted = "completed"
+	InspectionCancelled = "cancelled"
+)
+
+// オークションの検査状況 (Auction.InspectionStatus)
+const (
+	// AuctionInspectionNone は検査を依頼していない状態で、出品後すぐに入札できます
+	AuctionInspectionNone = ""
+	// AuctionInspectionPending は検査待ちの状態で、評価点が確定するまで入札できません
+	AuctionInspectionPending = "pending"
+	// AuctionInspectionPassed は検査員が評価点を確定した状態です
+	AuctionInspectionPassed = "passed"
+)
+
+// Inspection は出品者が依頼する第三者検査です
+type Inspection struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	AuctionID   uint   `gorm:"not null;index" json:"auction_id"`
+	SellerID    uint   `gorm:"not null;index" json:"seller_id"`
+	InspectorID *uint  `gorm:"index" json:"inspector_id"`
+	Status      string `gorm:"size:20;not null;index" json:"status"`
+	// Grade は検査員が確定した評価点です
+	Grade string `gorm:"size:4" json:"grade,omitempty"`
+	Notes string `gorm:"type:text" json:"notes,omitempty"`
+
+	AssignedAt  *time.Time `json:"assigned_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
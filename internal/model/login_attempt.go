@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// LoginAttempt はアカウント・接続元 IP ごとのログイン失敗の状況です（総当たり攻撃の対策に使います）
+// Subject は "account:<メールアドレス>" や "ip:<IP アドレス>" のような識別子です
+type LoginAttempt struct {
+	Subject string `gorm:"primaryKey;size:191" json:"subject"`
+	// Failures は最後の成功（または失敗の記録の期限切れ）以降に続いた失敗回数です
+	Failures    int       `gorm:"not null;default:0" json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	// BlockedUntil まではログインを受け付けません（段階的な待ち時間と一時的なロック）
+	BlockedUntil time.Time `gorm:"index" json:"blocked_until"`
+	// Locked は BlockedUntil が失敗回数の上限到達によるロックであることを示します
+	Locked    bool      `gorm:"not null;default:false" json:"locked"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Notification はユーザーへのアプリ内通知です
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Kind      string     `gorm:"size:50" json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	AuctionID *uint      `json:"auction_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
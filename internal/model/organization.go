@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 組織内のロール
+const (
+	// OrgRoleOwner はメンバー管理ができる組織の管理者です
+	OrgRoleOwner = "owner"
+	// OrgRoleBuyer は組織として入札できるメンバーです
+	OrgRoleBuyer = "buyer"
+	// OrgRoleViewer は組織の入札・出品を閲覧のみできるメンバーです（入札できません）
+	OrgRoleViewer = "viewer"
+)
+
+// OrgRoles は組織内で指定できるロールです
+var OrgRoles = []string{OrgRoleOwner, OrgRoleBuyer, OrgRoleViewer}
+
+// Organization は複数のユーザーが所属する販売店などの組織です
+// メンバーの入札・出品は組織に帰属し、与信枠 (CreditLimit) を組織全体で共有します
+type Organization struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"size:255;uniqueIndex" json:"name"`
+	// CreditLimit は開催中のオークションで組織が最高入札となっている金額の合計の上限です（0 は無制限）
+	CreditLimit int         `json:"credit_limit"`
+	Members     []OrgMember `gorm:"foreignKey:OrgID;constraint:OnDelete:CASCADE;" json:"members,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// OrgMember は組織への所属です（ユーザーは 1 つの組織にのみ所属できます）
+type OrgMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OrgID     uint      `gorm:"not null;index" json:"org_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	User      *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role      string    `gorm:"size:20;not null" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RateLimitBucket はレート制限のトークンバケットです（複数のサーバーで制限を共有する場合に DB へ保存します）
+// Subject は "bids:user:12" や "api:ip:203.0.113.5" のようなルート群と利用者の識別子です
+type RateLimitBucket struct {
+	Subject string `gorm:"primaryKey;size:191" json:"subject"`
+	// Tokens は RefilledAt 時点の残りトークン数です（経過時間に応じて補充します）
+	Tokens float64 `gorm:"not null;default:0" json:"tokens"`
+	// RefilledAt が nil のバケットは未使用で、トークンが満杯の状態として扱います
+	RefilledAt *time.Time `json:"refilled_at"`
+}
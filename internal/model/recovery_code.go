@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RecoveryCode は認証アプリを使えない場合に TOTP の代わりに使う一回限りのリカバリーコードです
+// コード本体は保存せず SHA-256 ハッシュのみを保存します
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
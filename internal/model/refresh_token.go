@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RefreshToken はアクセストークンの再発行に使うリフレッシュトークンです
+// トークン本体は保存せず SHA-256 ハッシュのみを保存します
+// 再発行のたびに新しいトークンへ置き換え (ローテーション)、同じログインから続くトークンは FamilyID を共有します
+type RefreshToken struct {
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// FamilyID はログイン 1 回ごとの識別子です（ログアウト・再利用検知時はファミリー単位で失効させます）
+	FamilyID  string    `gorm:"size:32;not null;index" json:"family_id"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	// RotatedAt は新しいトークンに置き換えられた日時です（以降にこのトークンが使われたら再利用とみなします）
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// SavedSearch はユーザーが保存したオークションの検索条件です
+// 新規出品が条件に一致すると通知が作成されます
+type SavedSearch struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"size:100" json:"name"`
+	// Filter は repo.AuctionFilter を JSON で保存したものです
+	Filter        string     `gorm:"type:text" json:"-"`
+	NotifyEmail   bool       `json:"notify_email"`
+	Paused        bool       `gorm:"index" json:"paused"`
+	LastMatchedAt *time.Time `json:"last_matched_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
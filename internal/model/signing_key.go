@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// SigningKey はアプリが発行する JWT の署名鍵です（RS256 / EdDSA, HS256 は JWT_SECRET を使うため保存しません）
+// 複数のサーバーで同じ鍵を使えるよう DB に保存し、秘密鍵は JWT_SECRET から導いた鍵で暗号化します
+type SigningKey struct {
+	// KID はトークンのヘッダーと JWKS の kid です
+	KID string `gorm:"column:kid;primaryKey;size:64" json:"kid"`
+	Alg string `gorm:"size:10;not null" json:"alg"`
+	// PrivateKey は PKCS#8 の秘密鍵を AES-GCM で暗号化したものです
+	PrivateKey []byte `gorm:"not null" json:"-"`
+	// ActivatesAt から署名に使います（次の鍵が有効になるまで）
+	// 他のサービスが JWKS を取得し直す時間を空けるため、ローテーションで作成した鍵は少し先の日時から使います
+	ActivatesAt time.Time `gorm:"not null;index" json:"activates_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
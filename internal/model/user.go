@@ -6,12 +6,82 @@ import (
 	"gorm.io/gorm"
 )
 
+// ユーザーのロール
+const (
+	RoleBidder = "bidder"
+	RoleSeller = "seller"
+	// RoleInspector は第三者検査員で、割り当てられたオークションの検査・評価点の確定を行います
+	RoleInspector = "inspector"
+	// RoleAdmin は管理者で、/admin 配下の管理 API を利用できます（サインアップでは指定できません）
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"size:255;uniqueIndex" json:"email"`
-	Password  string         `json:"-"`
-	Role      string         `gorm:"not null" json:"role"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Email    string `gorm:"size:255;uniqueIndex" json:"email"`
+	Password string `json:"-"`
+	// Role は主ロール（サインアップ時の最初のロール）で、表示と旧クライアント向けに残しています
+	// 権限の判定には Roles を使用します
+	Role string `gorm:"not null" json:"role"`
+	// Roles はユーザーに付与されたすべてのロールです（買い手と売り手を兼ねる業者など）
+	Roles []UserRole `gorm:"foreignKey:UserID" json:"roles,omitempty"`
+	// SuspendedAt は管理者がアカウントを停止した日時です（停止中はログイン・API 利用ができません）
+	SuspendedAt   *time.Time `gorm:"index" json:"suspended_at,omitempty"`
+	SuspendReason string     `gorm:"size:255" json:"suspend_reason,omitempty"`
+	// EmailVerifiedAt はメールアドレスの確認が完了した日時です（未確認の間は入札できません）
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// DealerVerifiedAt は業者の本人確認（KYC）が承認された日時です（ポリシーにより未承認の間は入札が制限されます）
+	DealerVerifiedAt *time.Time `gorm:"index" json:"dealer_verified_at,omitempty"`
+	// TOTPSecret は二要素認証 (TOTP) の共有鍵です（登録手続き中も保存し、有効化は TOTPEnabledAt で判定します）
+	TOTPSecret string `gorm:"size:64" json:"-"`
+	// TOTPEnabledAt は二要素認証を有効にした日時です（有効な間はログインにワンタイムコードが必要です）
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty"`
+	// TOTPLastStep は最後に受け付けたコードの時間ステップです（同じコードの再利用を拒否します）
+	TOTPLastStep int64 `gorm:"not null;default:0" json:"-"`
+	// プロフィール（本人が /users/me で変更できる項目）
+	DisplayName string `gorm:"size:50" json:"display_name"`
+	Phone       string `gorm:"size:20" json:"phone"`
+	Address     string `gorm:"size:255" json:"address"`
+	Company     string `gorm:"size:100" json:"company"`
+	// Language・Currency は画面表示・通知に使う言語と、金額を表示する通貨です
+	Language string `gorm:"size:8;not null;default:ja" json:"language"`
+	Currency string `gorm:"size:3;not null;default:JPY" json:"currency"`
+	// DeletionRequestedAt は本人がアカウントの削除を申請した日時です（管理者が確認して削除します）
+	DeletionRequestedAt *time.Time `gorm:"index" json:"deletion_requested_at,omitempty"`
+	DeletionReason      string     `gorm:"size:255" json:"deletion_reason,omitempty"`
+	// TokenVersion はアクセストークンの ver クレームと照合する世代番号です
+	// 全端末からのログアウトやリフレッシュトークンの再利用検知で増やし、発行済みのアクセストークンを無効にします
+	TokenVersion int            `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// UserRole はユーザーに付与されたロールです
+type UserRole struct {
+	UserID    uint      `gorm:"primaryKey" json:"-"`
+	Role      string    `gorm:"primaryKey;size:20" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleNames は付与されたロール名の一覧を返します
+func (u *User) RoleNames() []string {
+	names := make([]string, len(u.Roles))
+	for i, r := range u.Roles {
+		names[i] = r.Role
+	}
+	return names
+}
+
+// HasRole はロールが付与されているかを返します
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAEnabled は二要素認証が有効かを返します
+func (u *User) MFAEnabled() bool { return u.TOTPEnabledAt != nil }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// UserIdentity は外部の IdP (OpenID Connect) のアカウントとローカルのユーザーの紐付けです
+// IdP ごとの利用者 ID (sub) で照合するため、IdP 側でメールアドレスが変わっても同じユーザーとしてログインできます
+type UserIdentity struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Issuer string `gorm:"size:191;not null;uniqueIndex:idx_identity_subject" json:"issuer"`
+	// Subject は IdP の ID トークンの sub クレームです
+	Subject string `gorm:"size:191;not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	// Email は最後のログイン時に IdP から受け取ったメールアドレスです
+	Email       string     `gorm:"size:255" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// UserToken の用途
+const (
+	// TokenEmailVerify はメールアドレス確認用のトークンです
+	TokenEmailVerify = "email_verify"
+	// TokenPasswordReset はパスワード再設定用のトークンです
+	TokenPasswordReset = "password_reset"
+	// TokenEmailChange は新しいメールアドレスの確認（メールアドレス変更）用のトークンです
+	TokenEmailChange = "email_change"
+)
+
+// UserToken はメールで送る一回限りのトークンです（メールアドレス確認・パスワード再設定・メールアドレス変更）
+// トークン本体は保存せず SHA-256 ハッシュのみを保存します
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Purpose   string    `gorm:"size:20;not null" json:"purpose"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	// NewEmail はメールアドレス変更の変更先です（他の用途では空）
+	NewEmail string `gorm:"size:255" json:"-"`
+	// UsedAt は使用済み、または新しいトークンの発行により無効化された日時です
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
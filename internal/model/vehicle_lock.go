@@ -0,0 +1,7 @@
+package model
+
+// VehicleLock は同じ車両の出品を直列化するための行ロック用のレコードです
+// Vehicle は "vin:JT2BF22K1W0123456" や "chassis:ZVW30-1234567" のような車両の識別子です
+type VehicleLock struct {
+	Vehicle string `gorm:"primaryKey;size:64" json:"vehicle"`
+}
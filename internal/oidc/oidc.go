@@ -0,0 +1,354 @@
+// Package oidc は OpenID Connect の認可コードフロー (PKCE) で外部の IdP にログインするためのクライアントです。
+// ディスカバリー・認可 URL の生成・トークン交換・ID トークンの検証 (JWKS の RS256 / ES256) のみを扱います。
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrProvider は IdP のディスカバリー・JWKS・トークンエンドポイントへの接続や応答が不正な場合に返されます
+	ErrProvider = errors.New("oidc provider error")
+	// ErrExchange はトークンエンドポイントが認可コードを拒否した場合に返されます
+	ErrExchange = errors.New("oidc code exchange failed")
+	// ErrInvalidIDToken は ID トークンの署名・発行者・対象・有効期限・nonce の検証に失敗した場合に返されます
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Config は IdP とこのアプリ（クライアント）の設定です
+type Config struct {
+	// Issuer は IdP の発行者 URL です（<Issuer>/.well-known/openid-configuration を参照します）
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL は IdP から認可コードを受け取るコールバック URL です
+	RedirectURL string
+	// Scopes は要求するスコープです（openid は常に含めます）
+	Scopes []string
+}
+
+// metadata はディスカバリーで取得する IdP のエンドポイントです
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Client は 1 つの IdP に対する OIDC クライアントです
+// ディスカバリーは初回利用時に行い、JWKS は未知の鍵 ID (kid) の ID トークンを受け取った時に再取得します
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu   sync.Mutex
+	meta *metadata
+	keys map[string]any
+}
+
+// New は OIDC クライアントを生成します（hc が nil なら 10 秒でタイムアウトする既定のクライアントを使用）
+func New(cfg Config, hc *http.Client) *Client {
+	if hc == nil {
+		hc = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &Client{cfg: cfg, http: hc}
+}
+
+// Issuer は設定した IdP の発行者 URL です（末尾の "/" は除きます）
+func (c *Client) Issuer() string { return c.cfg.Issuer }
+
+// IDToken は検証済みの ID トークンのクレームです
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Claims        jwt.MapClaims
+}
+
+// Strings はクレーム name を文字列の一覧として返します（文字列・文字列の配列以外は無視します）
+func (t *IDToken) Strings(name string) []string {
+	switch v := t.Claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// RandomString は state・nonce・PKCE の code_verifier に使う 32 バイトの乱数を URL セーフな文字列で返します
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge は PKCE の code_verifier から S256 の code_challenge を求めます
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL は利用者をリダイレクトする IdP の認可 URL を返します
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := []string{"openid"}
+	for _, s := range c.cfg.Scopes {
+		if s != "" && s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange は認可コードを code_verifier とともにトークンエンドポイントへ送り、ID トークンを検証して返します
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {c.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		// client_secret_basic (RFC 6749 2.3.1: ID とシークレットはフォームエンコードしてから Basic 認証に使います)
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: token response: %v", ErrProvider, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrProvider)
+	}
+	return c.Verify(ctx, body.IDToken, nonce)
+}
+
+// Verify は ID トークンの署名（JWKS）・発行者・対象 (aud)・有効期限・nonce を検証します
+func (c *Client) Verify(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.key(ctx, meta, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	id := &IDToken{Subject: sub, Claims: claims}
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		// 一部の IdP は文字列で返します
+		id.EmailVerified = v == "true"
+	}
+	return id, nil
+}
+
+// discover は IdP のディスカバリー文書を取得してキャッシュします
+func (c *Client) discover(ctx context.Context) (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.meta != nil {
+		return c.meta, nil
+	}
+	var meta metadata
+	if err := c.getJSON(ctx, c.cfg.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, err
+	}
+	// 発行者の取り違え（ミックスアップ攻撃）を防ぐため、設定した Issuer と一致することを確認します
+	if strings.TrimRight(meta.Issuer, "/") != c.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch %q", ErrProvider, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrProvider)
+	}
+	c.meta = &meta
+	return c.meta, nil
+}
+
+// key は鍵 ID の公開鍵を返します（キャッシュに無ければ JWKS を再取得します）
+func (c *Client) key(ctx context.Context, meta *metadata, kid string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := c.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	c.keys = map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			c.keys[k.Kid] = pub
+		}
+	}
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup はキャッシュから鍵を探します（kid が空で鍵が 1 つだけならその鍵を使います）
+func (c *Client) lookup(kid string) (any, bool) {
+	if k, ok := c.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Client) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s: %s", ErrProvider, u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: GET %s: %v", ErrProvider, u, err)
+	}
+	return nil
+}
+
+// jwk は JWKS の公開鍵 1 件です（RSA と P-256 の EC 鍵のみ扱います）
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			return nil, errors.New("invalid rsa key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("invalid ec key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("invalid ec key")
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
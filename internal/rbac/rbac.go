@@ -0,0 +1,98 @@
+// Package rbac はロールと権限の対応表を提供します
+// ルートは権限 (auction:create など) で保護し、ロールは権限の束として扱います
+package rbac
+
+import (
+	"slices"
+
+	"github.com/ksj/car-auction/internal/model"
+)
+
+// 権限
+const (
+	// AuctionCreate はオークションの出品です
+	AuctionCreate = "auction:create"
+	// AuctionUpdate は自分のオークションの更新・削除です（所有者の確認はサービス層で行います）
+	AuctionUpdate = "auction:update"
+	// AuctionDocument は写真・車両状態表の登録です（出品者または担当検査員であることはサービス層で確認します）
+	AuctionDocument = "auction:document"
+	// AuctionModerate は管理者によるオークションの強制終了・取り消しです
+	AuctionModerate = "auction:moderate"
+
+	// BidPlace は入札です
+	BidPlace = "bid:place"
+	// BidVoid は管理者による入札の無効化です
+	BidVoid = "bid:void"
+
+	// InspectionRequest は検査の依頼・取り消しです
+	InspectionRequest = "inspection:request"
+	// InspectionPerform は検査の引き受け・辞退・評価点の確定です
+	InspectionPerform = "inspection:perform"
+	// InspectionRead は検査履歴の閲覧です（出品者・担当検査員であることはサービス層で確認します）
+	InspectionRead = "inspection:read"
+
+	// SearchSave は検索条件の保存と新着通知の設定です
+	SearchSave = "search:save"
+	// NotificationRead は自分宛て通知の閲覧です
+	NotificationRead = "notification:read"
+	// OrgUse は組織の作成・所属組織の利用です（組織内のロールはサービス層で確認します）
+	OrgUse = "org:use"
+
+	// UserManage はユーザーの検索・停止・ロール変更です
+	UserManage = "user:manage"
+	// UserVerify は業者の本人確認（KYC）の審査です
+	UserVerify = "user:verify"
+	// AuditRead は監査ログの閲覧です
+	AuditRead = "audit:read"
+	// StatsRead はシステム統計の閲覧です
+	StatsRead = "stats:read"
+	// OrgCredit は組織の与信枠の変更です
+	OrgCredit = "org:credit"
+)
+
+// common はすべてのロールに付与する権限です
+var common = []string{SearchSave, NotificationRead, OrgUse}
+
+// rolePermissions はロールごとの権限です
+var rolePermissions = map[string][]string{
+	model.RoleBidder:    {BidPlace},
+	model.RoleSeller:    {AuctionCreate, AuctionUpdate, AuctionDocument, InspectionRequest, InspectionRead},
+	model.RoleInspector: {AuctionDocument, InspectionPerform, InspectionRead},
+	model.RoleAdmin:     {AuctionModerate, BidVoid, UserManage, UserVerify, AuditRead, StatsRead, OrgCredit},
+}
+
+// SignupRoles はサインアップ時に自分で選べるロールです
+// 検査員は評価点を確定できるため、管理者が付与した場合に限ります
+var SignupRoles = []string{model.RoleBidder, model.RoleSeller}
+
+// ValidRole は定義済みのロールかを返します
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Permissions はロールの組に付与される権限を重複なく昇順で返します（未知のロールは無視します）
+func Permissions(roles []string) []string {
+	var perms []string
+	for _, role := range roles {
+		if p, ok := rolePermissions[role]; ok {
+			perms = append(perms, p...)
+			perms = append(perms, common...)
+		}
+	}
+	slices.Sort(perms)
+	return slices.Compact(perms)
+}
+
+// Can はロールの組が権限 perm を持つかを返します
+func Can(roles []string, perm string) bool {
+	for _, role := range roles {
+		if slices.Contains(rolePermissions[role], perm) {
+			return true
+		}
+		if ValidRole(role) && slices.Contains(common, perm) {
+			return true
+		}
+	}
+	return false
+}
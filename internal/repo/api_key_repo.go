@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepo は API キーの永続化を担当するリポジトリです
+type APIKeyRepo struct{ DB *gorm.DB }
+
+// NewAPIKeyRepo は新しい APIKeyRepo を生成します
+func NewAPIKeyRepo(db *gorm.DB) *APIKeyRepo { return &APIKeyRepo{DB: db} }
+
+// Create は API キーを保存します
+func (r *APIKeyRepo) Create(k *model.APIKey) error {
+	return r.DB.Create(k).Error
+}
+
+// FindByPrefix はキーの先頭部分から API キーを取得します
+func (r *APIKeyRepo) FindByPrefix(prefix string) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := r.DB.Where("prefix = ?", prefix).First(&k).Error; err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListByUser はユーザーの API キーを新しい順に取得します（失効済みを含む）
+func (r *APIKeyRepo) ListByUser(userID uint) ([]model.APIKey, error) {
+	var list []model.APIKey
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// CountActive はユーザーの有効な（失効・期限切れでない）API キーの数を返します
+func (r *APIKeyRepo) CountActive(userID uint, now time.Time) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&n).Error
+	return n, err
+}
+
+// Revoke はユーザーの API キーを失効させます（対象が無い・失効済みなら false）
+func (r *APIKeyRepo) Revoke(userID, id uint, now time.Time) (bool, error) {
+	res := r.DB.Model(&model.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+// Touch は API キーの最終利用日時を更新します
+func (r *APIKeyRepo) Touch(id uint, now time.Time) error {
+	return r.DB.Model(&model.APIKey{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
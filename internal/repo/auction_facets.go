@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+)
+
+// FacetLimit はメーカー・車種ファセットで返す値の最大数です（件数の多い順）
+const FacetLimit = 50
+
+// YearBucketSize は年式ファセットのバケット幅（年）です
+const YearBucketSize = 5
+
+// MileageBuckets は走行距離ファセットのバケット境界 (km) です
+// 最後の境界以上は上限なしのバケットになります
+var MileageBuckets = []int{10000, 30000, 50000, 100000}
+
+// FacetCount はファセットの値ごとの件数です
+// 範囲バケットの場合は Min / Max に検索条件としてそのまま使える値が入ります（Max が nil は上限なし）
+type FacetCount struct {
+	Value string `json:"value"`
+	Min   *int   `json:"min,omitempty"`
+	Max   *int   `json:"max,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// AuctionFacets はオークション一覧のファセット集計結果です
+type AuctionFacets struct {
+	Makers     []FacetCount `json:"maker"`
+	ModelNames []FacetCount `json:"model_name"`
+	Years      []FacetCount `json:"year"`
+	Mileages   []FacetCount `json:"mileage"`
+	Status     []FacetCount `json:"status"`
+}
+
+// Facets は検索条件を適用したうえでメーカー・車種・年式・走行距離・状態ごとの件数を集計します
+// 各ファセットは自身の次元の条件だけを外して集計するため、選択中の値以外の候補と件数も得られます
+func (r *AuctionRepo) Facets(f AuctionFilter) (*AuctionFacets, error) {
+	var out AuctionFacets
+	var err error
+
+	without := f
+	without.Makers = nil
+	if out.Makers, err = r.valueFacet(without, "auctions.maker"); err != nil {
+		return nil, err
+	}
+
+	without = f
+	without.ModelNames = nil
+	if out.ModelNames, err = r.valueFacet(without, "auctions.model_name"); err != nil {
+		return nil, err
+	}
+
+	without = f
+	without.YearMin, without.YearMax = 0, 0
+	if out.Years, err = r.yearFacet(without); err != nil {
+		return nil, err
+	}
+
+	without = f
+	without.MileageMin, without.MileageMax = 0, 0
+	if out.Mileages, err = r.mileageFacet(without); err != nil {
+		return nil, err
+	}
+
+	without = f
+	without.Status = ""
+	if out.Status, err = r.statusFacet(without); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// bucketRow は GROUP BY の集計結果 1 行です
+type bucketRow struct {
+	Value string
+	Count int64
+}
+
+// valueFacet は列の値ごとの件数を件数の多い順に返します（空文字は除外）
+func (r *AuctionRepo) valueFacet(f AuctionFilter, column string) ([]FacetCount, error) {
+	var rows []bucketRow
+	if err := r.filtered(f).
+		Select(column + " AS value, COUNT(*) AS count").
+		Where(column + " <> ''").
+		Group(column).
+		Order("count DESC").Order(column + " ASC").
+		Limit(FacetLimit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]FacetCount, len(rows))
+	for i, row := range rows {
+		out[i] = FacetCount{Value: row.Value, Count: row.Count}
+	}
+	return out, nil
+}
+
+// yearFacet は年式を YearBucketSize 年ごとに区切った件数を新しい順に返します（年式不明は除外）
+func (r *AuctionRepo) yearFacet(f AuctionFilter) ([]FacetCount, error) {
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := r.filtered(f).
+		Select(fmt.Sprintf("auctions.year - (auctions.year %% %d) AS bucket, COUNT(*) AS count", YearBucketSize)).
+		Where("auctions.year > 0").
+		Group("bucket").
+		Order("bucket DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]FacetCount, len(rows))
+	for i, row := range rows {
+		lo, hi := row.Bucket, row.Bucket+YearBucketSize-1
+		out[i] = FacetCount{Value: fmt.Sprintf("%d-%d", lo, hi), Min: &lo, Max: &hi, Count: row.Count}
+	}
+	return out, nil
+}
+
+// mileageFacet は走行距離を MileageBuckets で区切った件数を少ない順に返します
+func (r *AuctionRepo) mileageFacet(f AuctionFilter) ([]FacetCount, error) {
+	expr := "CASE"
+	for i, limit := range MileageBuckets {
+		expr += fmt.Sprintf(" WHEN auctions.mileage < %d THEN %d", limit, i)
+	}
+	expr += fmt.Sprintf(" ELSE %d END", len(MileageBuckets))
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := r.filtered(f).
+		Select(expr + " AS bucket, COUNT(*) AS count").
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]FacetCount, len(rows))
+	for i, row := range rows {
+		lo := 0
+		if row.Bucket > 0 {
+			lo = MileageBuckets[row.Bucket-1]
+		}
+		fc := FacetCount{Min: &lo, Count: row.Count}
+		if row.Bucket < len(MileageBuckets) {
+			hi := MileageBuckets[row.Bucket] - 1
+			fc.Max = &hi
+			fc.Value = fmt.Sprintf("%d-%d", lo, hi)
+		} else {
+			fc.Value = fmt.Sprintf("%d-", lo)
+		}
+		out[i] = fc
+	}
+	return out, nil
+}
+
+// statusFacet は状態ごとの件数を返します
+// 検索条件と同じく live は終了前のすべて（ending_soon を含む）を数え、検査待ちのオークションは live / ending_soon に含めません
+func (r *AuctionRepo) statusFacet(f AuctionFilter) ([]FacetCount, error) {
+	now := time.Now()
+	var rows []bucketRow
+	if err := r.filtered(f).
+		Select(`CASE WHEN auctions.end_at <= ? THEN ? WHEN auctions.inspection_status = ? THEN ?
+			WHEN auctions.end_at <= ? THEN ? ELSE ? END AS value, COUNT(*) AS count`,
+			now, StatusEnded, model.AuctionInspectionPending, model.AuctionInspectionPending,
+			now.Add(EndingSoonWindow), StatusEndingSoon, StatusLive).
+		Group("value").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return []FacetCount{
+		{Value: StatusLive, Count: counts[StatusLive] + counts[StatusEndingSoon]},
+		{Value: StatusEndingSoon, Count: counts[StatusEndingSoon]},
+		{Value: StatusEnded, Count: counts[StatusEnded]},
+	}, nil
+}
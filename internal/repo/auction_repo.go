@@ -1,15 +1,26 @@
 package repo
 
 import (
+	"strings"
+	"time"
+
 	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/search"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AuctionRepo はオークションの永続化を担当するリポジトリです
-type AuctionRepo struct{ DB *gorm.DB }
+type AuctionRepo struct {
+	DB     *gorm.DB
+	Search search.Index
+}
 
 // NewAuctionRepo は新しい AuctionRepo を生成します
-func NewAuctionRepo(db *gorm.DB) *AuctionRepo { return &AuctionRepo{DB: db} }
+// 全文検索インデックスは DB の方言に応じて選択されます
+func NewAuctionRepo(db *gorm.DB) *AuctionRepo {
+	return &AuctionRepo{DB: db, Search: search.New(db)}
+}
 
 // FindAll は全オークションを取得します
 func (r *AuctionRepo) FindAll() ([]model.Auction, error) {
@@ -25,38 +36,277 @@ func (r *AuctionRepo) Create(a *model.Auction) error {
 	return r.DB.Create(a).Error
 }
 
-// FindPaginated はオフセット・リミット・タイトルフィルタを使ってオークションをページング取得します
-func (r *AuctionRepo) FindPaginated(offset, limit int, titleFilter string) ([]model.Auction, error) {
-	var auctions []model.Auction
-	q := r.DB.Model(&model.Auction{})
-	if titleFilter != "" {
-		q = q.Where("title LIKE ?", "%"+titleFilter+"%")
+// CreateVehicle は同じ VIN・車台番号で終了前のオークションが無い場合に限り、オークションを保存します
+// 同じ車両の同時出品で両方が保存されないよう、車両ごとのロック行を取ってから確認と保存を行います
+// 戻り値は保存できたかどうかです（重複していれば false）
+func (r *AuctionRepo) CreateVehicle(a *model.Auction, now time.Time) (bool, error) {
+	var keys []string
+	if a.VIN != "" {
+		keys = append(keys, "vin:"+a.VIN)
+	}
+	if a.ChassisNumber != "" {
+		keys = append(keys, "chassis:"+a.ChassisNumber)
+	}
+	created := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, k := range keys {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.VehicleLock{Vehicle: k}).Error; err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			var locks []model.VehicleLock
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("vehicle IN ?", keys).Order("vehicle").Find(&locks).Error; err != nil {
+				return err
+			}
+		}
+		live, err := hasLiveVehicle(tx, a.VIN, a.ChassisNumber, now)
+		if err != nil || live {
+			return err
+		}
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// hasLiveVehicle は同じ VIN または車台番号で終了前のオークションがあるかを返します
+func hasLiveVehicle(tx *gorm.DB, vin, chassisNumber string, now time.Time) (bool, error) {
+	q := tx.Model(&model.Auction{}).Where("end_at > ?", now)
+	switch {
+	case vin != "" && chassisNumber != "":
+		q = q.Where("vin = ? OR chassis_number = ?", vin, chassisNumber)
+	case vin != "":
+		q = q.Where("vin = ?", vin)
+	case chassisNumber != "":
+		q = q.Where("chassis_number = ?", chassisNumber)
+	default:
+		return false, nil
+	}
+	var n int64
+	if err := q.Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// EndingSoonWindow は status=ending_soon とみなす終了までの残り時間です
+const EndingSoonWindow = time.Hour
+
+// オークションの状態（終了時刻から算出します）
+const (
+	StatusLive       = "live"
+	StatusEndingSoon = "ending_soon"
+	StatusEnded      = "ended"
+)
+
+// AuctionSortColumns は sort パラメータで指定できるキーと列の対応です
+// キーの先頭に "-" を付けると降順になります
+var AuctionSortColumns = map[string]string{
+	"end_at":     "end_at",
+	"created_at": "created_at",
+	"price":      "current_price",
+	"mileage":    "mileage",
+	"year":       "year",
+}
+
+// AuctionFilter はオークション一覧の検索条件です
+// ゼロ値のフィールドは条件として扱いません
+type AuctionFilter struct {
+	// Query はタイトル・説明文に対する全文検索の検索語です
+	Query      string   `json:"q,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Makers     []string `json:"makers,omitempty"`
+	ModelNames []string `json:"model_names,omitempty"`
+	YearMin    int      `json:"year_min,omitempty"`
+	YearMax    int      `json:"year_max,omitempty"`
+	MileageMin int      `json:"mileage_min,omitempty"`
+	MileageMax int      `json:"mileage_max,omitempty"`
+	PriceMin   int      `json:"price_min,omitempty"`
+	PriceMax   int      `json:"price_max,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	SellerID   uint     `json:"seller_id,omitempty"`
+
+	// 車両状態表の項目による絞り込み（状態表が未提出のオークションは一致しません）
+	Transmissions   []string `json:"transmissions,omitempty"`
+	FuelTypes       []string `json:"fuel_types,omitempty"`
+	BodyTypes       []string `json:"body_types,omitempty"`
+	Drivetrains     []string `json:"drivetrains,omitempty"`
+	Colours         []string `json:"colours,omitempty"`
+	DisplacementMin int      `json:"displacement_min,omitempty"`
+	DisplacementMax int      `json:"displacement_max,omitempty"`
+	OwnersMax       int      `json:"owners_max,omitempty"`
+	AccidentHistory *bool    `json:"accident_history,omitempty"`
+	GradeMin        string   `json:"grade_min,omitempty"`
+
+	// Sort は "end_at", "-created_at" のような並び順キーの列です
+	Sort []string `json:"sort,omitempty"`
+}
+
+// scope は検索条件を WHERE 句としてクエリに適用します
+func (f AuctionFilter) scope(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		if f.Title != "" {
+			q = q.Where("auctions.title LIKE ?", "%"+f.Title+"%")
+		}
+		if len(f.Makers) > 0 {
+			q = q.Where("auctions.maker IN ?", f.Makers)
+		}
+		if len(f.ModelNames) > 0 {
+			q = q.Where("auctions.model_name IN ?", f.ModelNames)
+		}
+		if f.YearMin > 0 {
+			q = q.Where("auctions.year >= ?", f.YearMin)
+		}
+		if f.YearMax > 0 {
+			q = q.Where("auctions.year <= ?", f.YearMax)
+		}
+		if f.MileageMin > 0 {
+			q = q.Where("auctions.mileage >= ?", f.MileageMin)
+		}
+		if f.MileageMax > 0 {
+			q = q.Where("auctions.mileage <= ?", f.MileageMax)
+		}
+		if f.PriceMin > 0 {
+			q = q.Where("auctions.current_price >= ?", f.PriceMin)
+		}
+		if f.PriceMax > 0 {
+			q = q.Where("auctions.current_price <= ?", f.PriceMax)
+		}
+		// 検査待ちのオークションは入札できないため live / ending_soon に含めません
+		switch f.Status {
+		case StatusLive:
+			q = q.Where("auctions.end_at > ? AND auctions.inspection_status <> ?", now, model.AuctionInspectionPending)
+		case StatusEndingSoon:
+			q = q.Where("auctions.end_at > ? AND auctions.end_at <= ? AND auctions.inspection_status <> ?",
+				now, now.Add(EndingSoonWindow), model.AuctionInspectionPending)
+		case StatusEnded:
+			q = q.Where("auctions.end_at <= ?", now)
+		}
+		if f.SellerID != 0 {
+			q = q.Where("auctions.seller_id = ?", f.SellerID)
+		}
+		if report := f.reportScope(q.Session(&gorm.Session{NewDB: true})); report != nil {
+			q = q.Where("auctions.id IN (?)", report)
+		}
+		return q
+	}
+}
+
+// reportScope は車両状態表の条件に一致する auction_id のサブクエリを返します（条件が無ければ nil）
+func (f AuctionFilter) reportScope(db *gorm.DB) *gorm.DB {
+	q := db.Model(&model.ConditionReport{}).Select("condition_reports.auction_id")
+	used := false
+	in := func(col string, values []string) {
+		if len(values) > 0 {
+			q, used = q.Where(col+" IN ?", values), true
+		}
+	}
+	cmp := func(cond string, v int) {
+		if v > 0 {
+			q, used = q.Where(cond, v), true
+		}
+	}
+	in("condition_reports.transmission", f.Transmissions)
+	in("condition_reports.fuel_type", f.FuelTypes)
+	in("condition_reports.body_type", f.BodyTypes)
+	in("condition_reports.drivetrain", f.Drivetrains)
+	in("condition_reports.colour", f.Colours)
+	cmp("condition_reports.engine_displacement >= ?", f.DisplacementMin)
+	cmp("condition_reports.engine_displacement <= ?", f.DisplacementMax)
+	cmp("condition_reports.owners <= ?", f.OwnersMax)
+	if f.AccidentHistory != nil {
+		q, used = q.Where("condition_reports.accident_history = ?", *f.AccidentHistory), true
+	}
+	if score, ok := model.Grades[f.GradeMin]; ok {
+		q, used = q.Where("condition_reports.grade <> '' AND condition_reports.grade_score >= ?", score), true
+	}
+	if !used {
+		return nil
+	}
+	return q
+}
+
+// filtered は検索条件（全文検索を含む）を適用したオークションのクエリを返します
+func (r *AuctionRepo) filtered(f AuctionFilter) *gorm.DB {
+	q := r.DB.Model(&model.Auction{}).Scopes(f.scope(time.Now()))
+	if f.Query != "" {
+		q = q.Scopes(r.Search.Match(f.Query))
+	}
+	return q
+}
+
+// order は並び順キーを ORDER BY 句に変換します（未知のキーは無視します）
+// 全文検索で並び順の指定が無い場合は関連度順になります
+// 同順位の並びを安定させるため最後に id を付加します
+func (r *AuctionRepo) order(q *gorm.DB, f AuctionFilter) *gorm.DB {
+	keys := f.Sort
+	if len(keys) == 0 {
+		if f.Query != "" {
+			q = q.Scopes(r.Search.Rank(f.Query))
+		}
+		keys = []string{"-created_at"}
 	}
-	if err := q.Offset(offset).Limit(limit).Find(&auctions).Error; err != nil {
+	for _, k := range keys {
+		dir := "ASC"
+		if strings.HasPrefix(k, "-") {
+			dir = "DESC"
+			k = k[1:]
+		}
+		if col, ok := AuctionSortColumns[k]; ok {
+			q = q.Order("auctions." + col + " " + dir)
+		}
+	}
+	return q.Order("auctions.id DESC")
+}
+
+// FindPaginated はオフセット・リミット・検索条件を使ってオークションをページング取得します
+func (r *AuctionRepo) FindPaginated(offset, limit int, f AuctionFilter) ([]model.Auction, error) {
+	var auctions []model.Auction
+	if err := r.order(r.filtered(f), f).Preload("Photos", orderedPhotos).Preload("ConditionReport").
+		Offset(offset).Limit(limit).Find(&auctions).Error; err != nil {
 		return nil, err
 	}
 	return auctions, nil
 }
 
-// Count はタイトルフィルタ適用後のオークション総件数を返します
-func (r *AuctionRepo) Count(titleFilter string) (int64, error) {
+// FindPage は検索条件を適用したオークションをキーセット (created_at DESC, id DESC) で取得します
+// f.Sort は無視されます
+func (r *AuctionRepo) FindPage(f AuctionFilter, c *Cursor, limit int) (*CursorPage[model.Auction], error) {
+	return findKeyset(r.filtered(f).Preload("Photos", orderedPhotos).Preload("ConditionReport"), "auctions", c, limit, func(a model.Auction) Cursor {
+		return Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+	})
+}
+
+// Count は検索条件適用後のオークション総件数を返します
+func (r *AuctionRepo) Count(f AuctionFilter) (int64, error) {
 	var total int64
-	q := r.DB.Model(&model.Auction{})
-	if titleFilter != "" {
-		q = q.Where("title LIKE ?", "%"+titleFilter+"%")
-	}
-	if err := q.Count(&total).Error; err != nil {
+	if err := r.filtered(f).Count(&total).Error; err != nil {
 		return 0, err
 	}
 	return total, nil
 }
 
+// BackfillCurrentPrice は current_price 列が未設定の既存オークションに
+// 最高入札額（有効な入札が無ければ開始価格）を設定します
+func (r *AuctionRepo) BackfillCurrentPrice() error {
+	return r.DB.Exec(`UPDATE auctions SET current_price = COALESCE(
+		(SELECT MAX(bids.amount) FROM bids WHERE bids.auction_id = auctions.id AND bids.voided_at IS NULL),
+		start_price) WHERE current_price = 0`).Error
+}
+
 // DeleteByID は指定IDのオークションを削除します
 func (r *AuctionRepo) DeleteByID(id uint) error {
 	return r.DB.Delete(&model.Auction{}, id).Error
 }
 
 // Update は指定されたオークションのタイトル・説明・開始価格・終了日時を更新します
+// 開始価格の変更に合わせて current_price も再計算します
 func (r *AuctionRepo) Update(a *model.Auction) error {
 	return r.DB.Model(&model.Auction{}).
 		Where("id = ?", a.ID).
@@ -65,15 +315,72 @@ func (r *AuctionRepo) Update(a *model.Auction) error {
 			"description": a.Description,
 			"start_price": a.StartPrice,
 			"end_at":      a.EndAt,
+			"current_price": gorm.Expr(
+				"COALESCE((SELECT MAX(bids.amount) FROM bids WHERE bids.auction_id = auctions.id AND bids.voided_at IS NULL), ?)",
+				a.StartPrice),
 		}).Error
 }
 
-// FindByID は指定IDのオークションを取得します
+// FindByID は指定IDのオークションを写真ギャラリー付きで取得します
 func (r *AuctionRepo) FindByID(id uint) (*model.Auction, error) {
 	var a model.Auction
-	tx := r.DB.First(&a, id)
+	tx := r.DB.Preload("Photos", orderedPhotos).
+		Preload("ConditionReport.Damages").First(&a, id)
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
 	return &a, nil
 }
+
+// MatchFilters は指定IDのオークションが filters のそれぞれに一致するかを 1 回のクエリでまとめて判定します
+// 戻り値は filters と同じ順序です
+func (r *AuctionRepo) MatchFilters(id uint, filters []AuctionFilter) ([]bool, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	cols := make([]string, len(filters))
+	args := make([]any, len(filters))
+	for i, f := range filters {
+		cols[i] = "EXISTS (?)"
+		args[i] = r.filtered(f).Select("1").Where("auctions.id = ?", id)
+	}
+	rows, err := r.DB.Raw("SELECT "+strings.Join(cols, ", "), args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	vals := make([]int64, len(filters))
+	dest := make([]any, len(filters))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	matched := make([]bool, len(filters))
+	for i, v := range vals {
+		matched[i] = v != 0
+	}
+	return matched, nil
+}
+
+// UpdateCoverPhoto はカバー写真のストレージキーを photo_key に反映します
+// 配信用 URL は取得時に発行するため photo_url は空にします
+func (r *AuctionRepo) UpdateCoverPhoto(id uint, key string) error {
+	return r.DB.Model(&model.Auction{}).Where("id = ?", id).
+		Updates(map[string]any{"photo_key": key, "photo_url": ""}).Error
+}
+
+// BackfillPhotoKeys はローカル配信 URL (prefix 配下) のまま保存されている photo_url を
+// ストレージキーに移行します
+func (r *AuctionRepo) BackfillPhotoKeys(prefix string) error {
+	return r.DB.Model(&model.Auction{}).
+		Where("photo_key = '' AND photo_url LIKE ?", prefix+"%").
+		Updates(map[string]any{
+			"photo_key": gorm.Expr("SUBSTR(photo_url, ?)", len(prefix)+1),
+			"photo_url": "",
+		}).Error
+}
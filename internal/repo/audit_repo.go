@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditRepo は監査ログの永続化を担当するリポジトリです
+type AuditRepo struct{ DB *gorm.DB }
+
+// NewAuditRepo は新しい AuditRepo を生成します
+func NewAuditRepo(db *gorm.DB) *AuditRepo { return &AuditRepo{DB: db} }
+
+// AuditQuery は監査ログの検索条件です（ゼロ値の項目は条件に含めません）
+type AuditQuery struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	// BeforeID を指定すると、その ID より古いログを返します（ページ送り用）
+	BeforeID uint
+}
+
+// Create は監査ログを追記します
+// 管理操作と同じトランザクションで記録する場合は tx を渡します
+func (r *AuditRepo) Create(tx *gorm.DB, l *model.AuditLog) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Create(l).Error
+}
+
+// Find は条件に一致する監査ログを新しい順に最大 limit 件取得します
+func (r *AuditRepo) Find(q AuditQuery, limit int) ([]model.AuditLog, error) {
+	tx := r.DB.Model(&model.AuditLog{})
+	if q.ActorID != 0 {
+		tx = tx.Where("actor_id = ?", q.ActorID)
+	}
+	if q.Action != "" {
+		tx = tx.Where("action = ?", q.Action)
+	}
+	if q.TargetType != "" {
+		tx = tx.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != 0 {
+		tx = tx.Where("target_id = ?", q.TargetID)
+	}
+	if q.BeforeID != 0 {
+		tx = tx.Where("id < ?", q.BeforeID)
+	}
+	var logs []model.AuditLog
+	if err := tx.Order("id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -62,6 +62,14 @@ func (r *BidRepo) FindPaginated(auctionID uint, offset, limit int) ([]model.Bid,
 	return bids, nil
 }
 
+// FindPageByAuction は指定オークションIDの入札をキーセット (created_at DESC, id DESC) で取得します
+func (r *BidRepo) FindPageByAuction(auctionID uint, c *Cursor, limit int) (*CursorPage[model.Bid], error) {
+	q := r.DB.Model(&model.Bid{}).Where("bids.auction_id = ?", auctionID)
+	return findKeyset(q, "bids", c, limit, func(b model.Bid) Cursor {
+		return Cursor{CreatedAt: b.CreatedAt, ID: b.ID}
+	})
+}
+
 // CountByAuction は指定オークションIDの入札総件数を返します
 func (r *BidRepo) CountByAuction(auctionID uint) (int64, error) {
 	var cnt int64
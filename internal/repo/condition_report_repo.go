@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// ConditionReportRepo は車両状態表の永続化を担当するリポジトリです
+type ConditionReportRepo struct{ DB *gorm.DB }
+
+// NewConditionReportRepo は新しい ConditionReportRepo を生成します
+func NewConditionReportRepo(db *gorm.DB) *ConditionReportRepo { return &ConditionReportRepo{DB: db} }
+
+// FindByAuction は指定オークションの車両状態表を損傷箇所付きで取得します
+func (r *ConditionReportRepo) FindByAuction(auctionID uint) (*model.ConditionReport, error) {
+	var cr model.ConditionReport
+	if err := r.DB.Preload("Damages").Where("auction_id = ?", auctionID).First(&cr).Error; err != nil {
+		return nil, err
+	}
+	return &cr, nil
+}
+
+// Replace はオークションの車両状態表を保存します。既存の状態表があれば損傷箇所ごと置き換えます
+func (r *ConditionReportRepo) Replace(cr *model.ConditionReport) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.ConditionReport
+		err := tx.Where("auction_id = ?", cr.AuctionID).First(&existing).Error
+		switch {
+		case err == nil:
+			cr.ID, cr.CreatedAt = existing.ID, existing.CreatedAt
+			if err := tx.Where("report_id = ?", existing.ID).Delete(&model.PanelDamage{}).Error; err != nil {
+				return err
+			}
+			damages := cr.Damages
+			if err := tx.Omit("Damages").Save(cr).Error; err != nil {
+				return err
+			}
+			for i := range damages {
+				damages[i].ID, damages[i].ReportID = 0, cr.ID
+			}
+			if len(damages) > 0 {
+				if err := tx.Create(&damages).Error; err != nil {
+					return err
+				}
+			}
+			cr.Damages = damages
+			return nil
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(cr).Error
+		default:
+			return err
+		}
+	})
+}
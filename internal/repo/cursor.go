@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor はカーソルトークンが解読できない場合に返されます
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor はキーセットページネーションの位置 (created_at, id) を表します
+// 並び順は常に created_at DESC, id DESC で、同時刻のレコードも id で一意に並びます
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+	// Before が true の場合はこの位置より新しい側（前のページ）を取得します
+	Before bool `json:"b,omitempty"`
+}
+
+// Encode はカーソルをクライアントに渡す不透明なトークンに変換します
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor は Encode で生成したトークンをカーソルに戻します
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// CursorPage はキーセットで取得した 1 ページ分の結果です
+// Next / Prev は次・前のページが存在する場合のみ設定されます
+type CursorPage[T any] struct {
+	Items []T
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// findKeyset は q に対して (created_at, id) のキーセット条件を適用し、limit 件を取得します
+// OFFSET を使わないため、取得の合間に新しい行が挿入されても重複・欠落が起きません
+func findKeyset[T any](q *gorm.DB, table string, c *Cursor, limit int, key func(T) Cursor) (*CursorPage[T], error) {
+	createdAt, id := table+".created_at", table+".id"
+	backward := c != nil && c.Before
+	switch {
+	case c == nil:
+		q = q.Order(createdAt + " DESC").Order(id + " DESC")
+	case backward:
+		q = q.Where(createdAt+" > ? OR ("+createdAt+" = ? AND "+id+" > ?)", c.CreatedAt, c.CreatedAt, c.ID).
+			Order(createdAt + " ASC").Order(id + " ASC")
+	default:
+		q = q.Where(createdAt+" < ? OR ("+createdAt+" = ? AND "+id+" < ?)", c.CreatedAt, c.CreatedAt, c.ID).
+			Order(createdAt + " DESC").Order(id + " DESC")
+	}
+
+	// 1 件多く取得して、その方向にまだ続きがあるかを判定
+	var items []T
+	if err := q.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	first, last := key(items[0]), key(items[len(items)-1])
+	first.Before = true
+	// 後方向に取得した場合は元のカーソルより古い行が必ず存在し、
+	// 前方向にカーソル付きで取得した場合は元のカーソルより新しい行が必ず存在します
+	if (backward && more) || (!backward && c != nil) {
+		page.Prev = &first
+	}
+	if (!backward && more) || backward {
+		page.Next = &last
+	}
+	return page, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// VerificationRepo は業者の本人確認の申請と提出書類の永続化を担当するリポジトリです
+type VerificationRepo struct{ DB *gorm.DB }
+
+// NewVerificationRepo は新しい VerificationRepo を生成します
+func NewVerificationRepo(db *gorm.DB) *VerificationRepo { return &VerificationRepo{DB: db} }
+
+// withDocuments は提出書類を添付順で Preload するためのスコープです
+func withDocuments(db *gorm.DB) *gorm.DB {
+	return db.Preload("Documents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("verification_documents.id ASC")
+	})
+}
+
+// FindByID は申請を提出書類付きで取得します
+func (r *VerificationRepo) FindByID(id uint) (*model.DealerVerification, error) {
+	var v model.DealerVerification
+	if err := r.DB.Scopes(withDocuments).First(&v, id).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Latest はユーザーの最新の申請を提出書類付きで取得します
+func (r *VerificationRepo) Latest(userID uint) (*model.DealerVerification, error) {
+	var v model.DealerVerification
+	if err := r.DB.Scopes(withDocuments).Where("user_id = ?", userID).Order("id DESC").First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Search は申請を状態で絞り込み（空ならすべて）、提出の古い順にページネーション付きで取得し、総件数を返します
+func (r *VerificationRepo) Search(status string, page, size int) ([]model.DealerVerification, int64, error) {
+	tx := r.DB.Model(&model.DealerVerification{})
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.DealerVerification
+	if err := tx.Scopes(withDocuments).Order("submitted_at ASC").Order("id ASC").
+		Offset((page - 1) * size).Limit(size).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// Create は申請を保存します
+func (r *VerificationRepo) Create(v *model.DealerVerification) error {
+	return r.DB.Create(v).Error
+}
+
+// AddDocument は申請に書類を追加します
+func (r *VerificationRepo) AddDocument(d *model.VerificationDocument) error {
+	return r.DB.Create(d).Error
+}
+
+// DeleteDocument は申請から書類を削除します
+func (r *VerificationRepo) DeleteDocument(d *model.VerificationDocument) error {
+	return r.DB.Delete(d).Error
+}
+
+// Transition は申請が from のいずれかの状態である場合に限り updates を適用します（tx が nil なら通常の接続）
+// 管理者が同時に審査した場合に一方だけが成功するよう、条件付き UPDATE で行います
+// 戻り値は更新できたかどうかです
+func (r *VerificationRepo) Transition(tx *gorm.DB, id uint, from []string, updates map[string]any) (bool, error) {
+	if tx == nil {
+		tx = r.DB
+	}
+	res := tx.Model(&model.DealerVerification{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
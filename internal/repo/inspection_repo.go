@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// InspectionRepo は第三者検査の永続化を担当するリポジトリです
+type InspectionRepo struct{ DB *gorm.DB }
+
+// NewInspectionRepo は新しい InspectionRepo を生成します
+func NewInspectionRepo(db *gorm.DB) *InspectionRepo { return &InspectionRepo{DB: db} }
+
+// activeStatuses は進行中とみなす検査の状態です
+var activeStatuses = []string{model.InspectionRequested, model.InspectionAssigned}
+
+// FindByID は検査を取得します
+func (r *InspectionRepo) FindByID(id uint) (*model.Inspection, error) {
+	var in model.Inspection
+	if err := r.DB.First(&in, id).Error; err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// FindByAuction は指定オークションの検査を新しい順に取得します
+func (r *InspectionRepo) FindByAuction(auctionID uint) ([]model.Inspection, error) {
+	var list []model.Inspection
+	if err := r.DB.Where("auction_id = ?", auctionID).Order("id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FindQueue は検査員向けに、未割り当ての依頼と inspectorID に割り当て済みの検査を古い順に取得します
+func (r *InspectionRepo) FindQueue(inspectorID uint) ([]model.Inspection, error) {
+	var list []model.Inspection
+	err := r.DB.Where("status = ?", model.InspectionRequested).
+		Or("status = ? AND inspector_id = ?", model.InspectionAssigned, inspectorID).
+		Order("id ASC").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// HasActive は指定オークションに進行中の検査があるかを返します
+func (r *InspectionRepo) HasActive(auctionID uint) (bool, error) {
+	var n int64
+	err := r.DB.Model(&model.Inspection{}).
+		Where("auction_id = ? AND status IN ?", auctionID, activeStatuses).Count(&n).Error
+	return n > 0, err
+}
+
+// IsAssigned は userID が指定オークションの進行中の検査に割り当てられているかを返します
+func (r *InspectionRepo) IsAssigned(auctionID, userID uint) (bool, error) {
+	var n int64
+	err := r.DB.Model(&model.Inspection{}).
+		Where("auction_id = ? AND inspector_id = ? AND status = ?", auctionID, userID, model.InspectionAssigned).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// Create は検査を保存し、オークションを検査待ちにします
+func (r *InspectionRepo) Create(in *model.Inspection) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(in).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Auction{}).Where("id = ?", in.AuctionID).
+			Update("inspection_status", model.AuctionInspectionPending).Error
+	})
+}
+
+// Transition は検査が from のいずれかの状態である場合に限り updates を適用します
+// 同時に状態を変更しようとした場合に一方だけが成功するよう、条件付き UPDATE で行います
+// 戻り値は更新できたかどうかです
+func (r *InspectionRepo) Transition(id uint, from []string, updates map[string]any) (bool, error) {
+	return transition(r.DB, id, from, updates)
+}
+
+func transition(db *gorm.DB, id uint, from []string, updates map[string]any) (bool, error) {
+	res := db.Model(&model.Inspection{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Cancel は進行中の検査を取り消し、オークションを検査なしの状態に戻します
+func (r *InspectionRepo) Cancel(in *model.Inspection) (bool, error) {
+	ok := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ok, err = transition(tx, in.ID, activeStatuses, map[string]any{"status": model.InspectionCancelled})
+		if err != nil || !ok {
+			return err
+		}
+		return tx.Model(&model.Auction{}).Where("id = ?", in.AuctionID).
+			Update("inspection_status", model.AuctionInspectionNone).Error
+	})
+	return ok, err
+}
+
+// Complete は割り当て済みの検査を完了にし、車両状態表の評価点を確定してオークションを検査済みにします
+func (r *InspectionRepo) Complete(in *model.Inspection, grade string, score float64, notes string, now time.Time) (bool, error) {
+	ok := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ok, err = transition(tx, in.ID, []string{model.InspectionAssigned}, map[string]any{
+			"status": model.InspectionCompleted, "grade": grade, "notes": notes, "completed_at": now,
+		})
+		if err != nil || !ok {
+			return err
+		}
+		if err := tx.Model(&model.ConditionReport{}).Where("auction_id = ?", in.AuctionID).
+			Updates(map[string]any{"grade": grade, "grade_score": score, "submitted_by": *in.InspectorID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Auction{}).Where("id = ?", in.AuctionID).
+			Update("inspection_status", model.AuctionInspectionPassed).Error
+	})
+	return ok, err
+}
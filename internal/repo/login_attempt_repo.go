@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptRepo はログイン失敗の状況を DB に保存するリポジトリです
+// 複数のサーバーで失敗回数を共有する場合に使います（service.LoginAttemptStore の実装）
+type LoginAttemptRepo struct{ DB *gorm.DB }
+
+// NewLoginAttemptRepo は新しい LoginAttemptRepo を生成します
+func NewLoginAttemptRepo(db *gorm.DB) *LoginAttemptRepo { return &LoginAttemptRepo{DB: db} }
+
+// Get は subject のログイン失敗の状況を取得します（記録が無ければ nil）
+func (r *LoginAttemptRepo) Get(subject string) (*model.LoginAttempt, error) {
+	var list []model.LoginAttempt
+	if err := r.DB.Where("subject = ?", subject).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// Fail は失敗を 1 回記録し、更新後の状況を返します
+// 最後の失敗から window 以上経っていれば 1 回目として数え直し、block で受付停止の期限を求めます
+// 同時に失敗した場合も数え漏れが無いよう、行ロックを取って更新します
+// （新しい行は MySQL の厳格モードで日時のゼロ値が拒否されないよう、失敗回数 0・現在時刻で作成します）
+func (r *LoginAttemptRepo) Fail(subject string, now time.Time, window time.Duration,
+	block func(failures int) (time.Time, bool)) (*model.LoginAttempt, error) {
+	var a model.LoginAttempt
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.LoginAttempt{Subject: subject, LastFailure: now, BlockedUntil: now, UpdatedAt: now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("subject = ?", subject).First(&a).Error; err != nil {
+			return err
+		}
+		if a.LastFailure.IsZero() || now.Sub(a.LastFailure) >= window {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailure = now
+		a.BlockedUntil, a.Locked = block(a.Failures)
+		a.UpdatedAt = now
+		return tx.Save(&a).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Reset は subject のログイン失敗の記録を削除します（ログイン成功・管理者によるロック解除）
+func (r *LoginAttemptRepo) Reset(subject string) error {
+	return r.DB.Where("subject = ?", subject).Delete(&model.LoginAttempt{}).Error
+}
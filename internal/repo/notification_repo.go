@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// NotificationRepo はアプリ内通知の永続化を担当するリポジトリです
+type NotificationRepo struct{ DB *gorm.DB }
+
+// NewNotificationRepo は新しい NotificationRepo を生成します
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo { return &NotificationRepo{DB: db} }
+
+// Create は通知を保存します
+func (r *NotificationRepo) Create(n *model.Notification) error {
+	return r.DB.Create(n).Error
+}
+
+// FindByUser は指定ユーザーの通知を新しい順に取得します
+// unreadOnly が true の場合は未読のみを返します
+func (r *NotificationRepo) FindByUser(userID uint, unreadOnly bool, limit int) ([]model.Notification, error) {
+	var list []model.Notification
+	q := r.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	if err := q.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MarkRead は指定ユーザーの通知を既読にし、更新件数を返します
+func (r *NotificationRepo) MarkRead(userID, id uint, at time.Time) (int64, error) {
+	tx := r.DB.Model(&model.Notification{}).
+		Where("user_id = ? AND id = ? AND read_at IS NULL", userID, id).
+		Update("read_at", at)
+	return tx.RowsAffected, tx.Error
+}
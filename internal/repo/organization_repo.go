@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepo は組織とメンバーの永続化を担当するリポジトリです
+type OrganizationRepo struct{ DB *gorm.DB }
+
+// NewOrganizationRepo は新しい OrganizationRepo を生成します
+func NewOrganizationRepo(db *gorm.DB) *OrganizationRepo { return &OrganizationRepo{DB: db} }
+
+// Create は組織と最初のメンバー（オーナー）を 1 つのトランザクションで保存します
+func (r *OrganizationRepo) Create(org *model.Organization, owner *model.OrgMember) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Members").Create(org).Error; err != nil {
+			return err
+		}
+		owner.OrgID = org.ID
+		return tx.Create(owner).Error
+	})
+}
+
+// NameTaken は同じ名前の組織が既にあるかを返します
+func (r *OrganizationRepo) NameTaken(name string) (bool, error) {
+	var n int64
+	err := r.DB.Model(&model.Organization{}).Where("name = ?", name).Count(&n).Error
+	return n > 0, err
+}
+
+// FindByID は組織をメンバー（ユーザー情報付き）とともに取得します
+func (r *OrganizationRepo) FindByID(id uint) (*model.Organization, error) {
+	var org model.Organization
+	err := r.DB.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Members.User").First(&org, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Membership はユーザーの所属を返します（どの組織にも所属していなければ nil, nil）
+func (r *OrganizationRepo) Membership(tx *gorm.DB, userID uint) (*model.OrgMember, error) {
+	if tx == nil {
+		tx = r.DB
+	}
+	// 入札・出品のたびに呼ばれるため、未所属を ErrRecordNotFound（ログ出力あり）にしないよう Find を使います
+	var ms []model.OrgMember
+	if err := tx.Where("user_id = ?", userID).Limit(1).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return nil, nil
+	}
+	return &ms[0], nil
+}
+
+// AddMember はメンバーを追加します
+func (r *OrganizationRepo) AddMember(m *model.OrgMember) error {
+	return r.DB.Create(m).Error
+}
+
+// ErrLastOwner はオーナーが 1 人もいなくなる変更の場合に返されます（変更はロールバックされます）
+var ErrLastOwner = errors.New("organization must keep at least one owner")
+
+// UpdateMemberRole はメンバーの組織内ロールを変更します
+func (r *OrganizationRepo) UpdateMemberRole(orgID, userID uint, role string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.OrgMember{}).Where("org_id = ? AND user_id = ?", orgID, userID).
+			Update("role", role).Error; err != nil {
+			return err
+		}
+		return requireOwner(tx, orgID)
+	})
+}
+
+// RemoveMember はメンバーを組織から外します
+func (r *OrganizationRepo) RemoveMember(orgID, userID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&model.OrgMember{}).Error; err != nil {
+			return err
+		}
+		return requireOwner(tx, orgID)
+	})
+}
+
+// requireOwner は組織にオーナーが残っていなければ ErrLastOwner を返します
+func requireOwner(tx *gorm.DB, orgID uint) error {
+	var n int64
+	err := tx.Model(&model.OrgMember{}).Where("org_id = ? AND role = ?", orgID, model.OrgRoleOwner).Count(&n).Error
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// SetCreditLimit は組織の与信枠を変更します
+func (r *OrganizationRepo) SetCreditLimit(tx *gorm.DB, orgID uint, limit int) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.Organization{}).Where("id = ?", orgID).Update("credit_limit", limit).Error
+}
+
+// LockForBid は入札の与信判定を直列化するため、組織の行を FOR UPDATE でロックして取得します
+func (r *OrganizationRepo) LockForBid(tx *gorm.DB, orgID uint) (*model.Organization, error) {
+	var org model.Organization
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, orgID).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Exposure は now 時点で開催中のオークションのうち、組織が最高入札となっている金額の合計を返します
+// excludeAuctionID のオークションは合計に含めません（同じオークションでの入札額の更新時に使用）
+func (r *OrganizationRepo) Exposure(tx *gorm.DB, orgID, excludeAuctionID uint, now time.Time) (int, error) {
+	if tx == nil {
+		tx = r.DB
+	}
+	var total int
+	err := tx.Model(&model.Auction{}).
+		Select("COALESCE(SUM(auctions.current_price), 0)").
+		Where("auctions.end_at > ? AND auctions.cancelled_at IS NULL AND auctions.id <> ?", now, excludeAuctionID).
+		Where(`EXISTS (SELECT 1 FROM bids WHERE bids.auction_id = auctions.id AND bids.voided_at IS NULL
+			AND bids.org_id = ? AND bids.amount = auctions.current_price)`, orgID).
+		Scan(&total).Error
+	return total, err
+}
+
+// LeadingBid は指定オークションの有効な最高入札を返します（入札が無ければ nil, nil）
+func (r *OrganizationRepo) LeadingBid(tx *gorm.DB, auctionID uint) (*model.Bid, error) {
+	if tx == nil {
+		tx = r.DB
+	}
+	var bids []model.Bid
+	err := tx.Where("auction_id = ? AND voided_at IS NULL", auctionID).
+		Order("amount DESC, id DESC").Limit(1).Find(&bids).Error
+	if err != nil || len(bids) == 0 {
+		return nil, err
+	}
+	return &bids[0], nil
+}
+
+// FindBids は組織として行われた入札を新しい順にページネーション付きで取得し、総件数を返します
+func (r *OrganizationRepo) FindBids(orgID uint, page, size int) ([]model.Bid, int64, error) {
+	q := r.DB.Model(&model.Bid{}).Where("org_id = ?", orgID)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var bids []model.Bid
+	if err := q.Order("created_at DESC, id DESC").Offset((page - 1) * size).Limit(size).Find(&bids).Error; err != nil {
+		return nil, 0, err
+	}
+	return bids, total, nil
+}
+
+// FindAuctions は組織に帰属するオークションを新しい順にページネーション付きで取得し、総件数を返します
+func (r *OrganizationRepo) FindAuctions(orgID uint, page, size int) ([]model.Auction, int64, error) {
+	q := r.DB.Model(&model.Auction{}).Where("org_id = ?", orgID)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.Auction
+	if err := q.Order("created_at DESC, id DESC").Offset((page - 1) * size).Limit(size).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// SetAuctionOrg はオークションを組織に帰属させます
+func (r *OrganizationRepo) SetAuctionOrg(auctionID, orgID uint) error {
+	return r.DB.Model(&model.Auction{}).Where("id = ?", auctionID).Update("org_id", orgID).Error
+}
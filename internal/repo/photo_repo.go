@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// PhotoRepo はオークション写真の永続化を担当するリポジトリです
+type PhotoRepo struct{ DB *gorm.DB }
+
+// NewPhotoRepo は新しい PhotoRepo を生成します
+func NewPhotoRepo(db *gorm.DB) *PhotoRepo { return &PhotoRepo{DB: db} }
+
+// orderedPhotos は写真を表示順で Preload するためのスコープです
+func orderedPhotos(db *gorm.DB) *gorm.DB {
+	return db.Order("auction_photos.position ASC").Order("auction_photos.id ASC")
+}
+
+// FindByAuction は指定オークションの写真を表示順で取得します
+func (r *PhotoRepo) FindByAuction(auctionID uint) ([]model.AuctionPhoto, error) {
+	var photos []model.AuctionPhoto
+	if err := r.DB.Where("auction_id = ?", auctionID).Scopes(orderedPhotos).Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// FindByID は指定オークションに属する写真を取得します
+func (r *PhotoRepo) FindByID(auctionID, id uint) (*model.AuctionPhoto, error) {
+	var p model.AuctionPhoto
+	if err := r.DB.Where("auction_id = ? AND id = ?", auctionID, id).First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create は写真を末尾に追加します。isCover が true または最初の写真の場合はカバーに設定します
+func (r *PhotoRepo) Create(p *model.AuctionPhoto) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		var maxPos *int
+		if err := tx.Model(&model.AuctionPhoto{}).Where("auction_id = ?", p.AuctionID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AuctionPhoto{}).Where("auction_id = ?", p.AuctionID).
+			Select("MAX(position)").Scan(&maxPos).Error; err != nil {
+			return err
+		}
+		if maxPos != nil {
+			p.Position = *maxPos + 1
+		}
+		if count == 0 {
+			p.IsCover = true
+		}
+		if p.IsCover {
+			if err := clearCover(tx, p.AuctionID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(p).Error
+	})
+}
+
+// UpdateCaption は写真のキャプションを更新します
+func (r *PhotoRepo) UpdateCaption(p *model.AuctionPhoto) error {
+	return r.DB.Model(p).Update("caption", p.Caption).Error
+}
+
+// SetCover は指定した写真だけをカバーにします
+func (r *PhotoRepo) SetCover(auctionID, id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := clearCover(tx, auctionID); err != nil {
+			return err
+		}
+		return tx.Model(&model.AuctionPhoto{}).Where("auction_id = ? AND id = ?", auctionID, id).
+			Update("is_cover", true).Error
+	})
+}
+
+// Reorder は ids の順に Position を 0 から振り直します
+func (r *PhotoRepo) Reorder(auctionID uint, ids []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			if err := tx.Model(&model.AuctionPhoto{}).Where("auction_id = ? AND id = ?", auctionID, id).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete は写真を削除し、カバーだった場合は表示順で先頭の写真を新しいカバーにします
+func (r *PhotoRepo) Delete(p *model.AuctionPhoto) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(p).Error; err != nil {
+			return err
+		}
+		if !p.IsCover {
+			return nil
+		}
+		var next model.AuctionPhoto
+		err := tx.Where("auction_id = ?", p.AuctionID).Scopes(orderedPhotos).First(&next).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_cover", true).Error
+	})
+}
+
+// clearCover は指定オークションの全写真のカバー指定を外します
+func clearCover(tx *gorm.DB, auctionID uint) error {
+	return tx.Model(&model.AuctionPhoto{}).Where("auction_id = ? AND is_cover = ?", auctionID, true).
+		Update("is_cover", false).Error
+}
@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitRepo はレート制限のトークンバケットを DB に保存するリポジトリです
+// 複数のサーバーで制限を共有する場合に使います（api.RateLimitStore の実装）
+type RateLimitRepo struct{ DB *gorm.DB }
+
+// NewRateLimitRepo は新しい RateLimitRepo を生成します
+func NewRateLimitRepo(db *gorm.DB) *RateLimitRepo { return &RateLimitRepo{DB: db} }
+
+// Take は subject のバケットを行ロックを取って読み込み、take で補充・消費した結果を保存します
+// 記録の無いバケットは RefilledAt が nil の状態で take に渡します
+func (r *RateLimitRepo) Take(subject string, take func(b *model.RateLimitBucket) bool) (*model.RateLimitBucket, bool, error) {
+	var (
+		b  model.RateLimitBucket
+		ok bool
+	)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.RateLimitBucket{Subject: subject}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("subject = ?", subject).First(&b).Error; err != nil {
+			return err
+		}
+		ok = take(&b)
+		return tx.Model(&b).Updates(map[string]any{"tokens": b.Tokens, "refilled_at": b.RefilledAt}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &b, ok, nil
+}
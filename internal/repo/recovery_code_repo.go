@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepo は二要素認証のリカバリーコードの永続化を担当するリポジトリです
+type RecoveryCodeRepo struct{ DB *gorm.DB }
+
+// NewRecoveryCodeRepo は新しい RecoveryCodeRepo を生成します
+func NewRecoveryCodeRepo(db *gorm.DB) *RecoveryCodeRepo { return &RecoveryCodeRepo{DB: db} }
+
+// Replace はユーザーのリカバリーコードをすべて削除し、新しいコードを保存します（tx が nil なら既定の DB を使用）
+func (r *RecoveryCodeRepo) Replace(tx *gorm.DB, userID uint, codes []model.RecoveryCode) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Use は未使用のリカバリーコードを使用済みにし、使用できたかを返します（同時に使われても成功するのは 1 回のみ）
+func (r *RecoveryCodeRepo) Use(userID uint, hash string, now time.Time) (bool, error) {
+	res := r.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Remaining は未使用のリカバリーコードの数を返します
+func (r *RecoveryCodeRepo) Remaining(userID uint) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepo はリフレッシュトークンの永続化を担当するリポジトリです
+type RefreshTokenRepo struct{ DB *gorm.DB }
+
+// NewRefreshTokenRepo は新しい RefreshTokenRepo を生成します
+func NewRefreshTokenRepo(db *gorm.DB) *RefreshTokenRepo { return &RefreshTokenRepo{DB: db} }
+
+// Create はリフレッシュトークンを保存します（tx が nil なら既定の DB を使用）
+func (r *RefreshTokenRepo) Create(tx *gorm.DB, t *model.RefreshToken) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Create(t).Error
+}
+
+// FindByHash はトークンのハッシュからリフレッシュトークンを取得します
+func (r *RefreshTokenRepo) FindByHash(hash string) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+	if err := r.DB.Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Rotate は未使用・未失効のトークンを使用済みにします
+// 同じトークンで同時に再発行された場合は一方のみ成功し、もう一方は false を返します
+func (r *RefreshTokenRepo) Rotate(tx *gorm.DB, id uint, now time.Time) (bool, error) {
+	res := tx.Model(&model.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeFamily は同じログインから発行されたトークンをすべて失効させます
+func (r *RefreshTokenRepo) RevokeFamily(tx *gorm.DB, familyID string, now time.Time) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// RevokeUser はユーザーのトークンをすべて失効させます
+func (r *RefreshTokenRepo) RevokeUser(tx *gorm.DB, userID uint, now time.Time) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// SavedSearchRepo は保存済み検索条件の永続化を担当するリポジトリです
+type SavedSearchRepo struct{ DB *gorm.DB }
+
+// NewSavedSearchRepo は新しい SavedSearchRepo を生成します
+func NewSavedSearchRepo(db *gorm.DB) *SavedSearchRepo { return &SavedSearchRepo{DB: db} }
+
+// Create は保存済み検索条件を保存します
+func (r *SavedSearchRepo) Create(s *model.SavedSearch) error {
+	return r.DB.Create(s).Error
+}
+
+// Save は保存済み検索条件を更新します
+func (r *SavedSearchRepo) Save(s *model.SavedSearch) error {
+	return r.DB.Save(s).Error
+}
+
+// TouchMatched は最後に新着通知を送った日時だけを更新します
+// 通知の配信中に本人が編集・一時停止した内容を上書きしないよう、他の列には触れません
+func (r *SavedSearchRepo) TouchMatched(id uint, at time.Time) error {
+	return r.DB.Model(&model.SavedSearch{ID: id}).UpdateColumn("last_matched_at", at).Error
+}
+
+// FindByUser は指定ユーザーの保存済み検索条件を新しい順に取得します
+func (r *SavedSearchRepo) FindByUser(userID uint) ([]model.SavedSearch, error) {
+	var list []model.SavedSearch
+	if err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FindByUserAndID は指定ユーザーが所有する保存済み検索条件を取得します
+func (r *SavedSearchRepo) FindByUserAndID(userID, id uint) (*model.SavedSearch, error) {
+	var s model.SavedSearch
+	if err := r.DB.Where("user_id = ? AND id = ?", userID, id).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindActive は一時停止されていない全ての保存済み検索条件を取得します
+func (r *SavedSearchRepo) FindActive() ([]model.SavedSearch, error) {
+	var list []model.SavedSearch
+	if err := r.DB.Where("paused = ?", false).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Delete は指定ユーザーが所有する保存済み検索条件を削除し、削除件数を返します
+func (r *SavedSearchRepo) Delete(userID, id uint) (int64, error) {
+	tx := r.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&model.SavedSearch{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// SigningKeyRepo は JWT の署名鍵の永続化を担当するリポジトリです
+type SigningKeyRepo struct{ DB *gorm.DB }
+
+// NewSigningKeyRepo は新しい SigningKeyRepo を生成します
+func NewSigningKeyRepo(db *gorm.DB) *SigningKeyRepo { return &SigningKeyRepo{DB: db} }
+
+// List はすべての署名鍵を使用開始日時の古い順に取得します
+func (r *SigningKeyRepo) List() ([]model.SigningKey, error) {
+	var list []model.SigningKey
+	err := r.DB.Order("activates_at, kid").Find(&list).Error
+	return list, err
+}
+
+// Create は署名鍵を保存します
+func (r *SigningKeyRepo) Create(k *model.SigningKey) error {
+	return r.DB.Create(k).Error
+}
+
+// Delete は署名鍵を削除します
+func (r *SigningKeyRepo) Delete(kids []string) error {
+	if len(kids) == 0 {
+		return nil
+	}
+	return r.DB.Where("kid IN ?", kids).Delete(&model.SigningKey{}).Error
+}
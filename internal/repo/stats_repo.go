@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// StatsRepo は管理画面向けの集計クエリを担当するリポジトリです
+type StatsRepo struct{ DB *gorm.DB }
+
+// NewStatsRepo は新しい StatsRepo を生成します
+func NewStatsRepo(db *gorm.DB) *StatsRepo { return &StatsRepo{DB: db} }
+
+// UserStats はユーザー数の集計です
+type UserStats struct {
+	Total     int64            `json:"total"`
+	ByRole    map[string]int64 `json:"by_role"`
+	Suspended int64            `json:"suspended"`
+}
+
+// AuctionStats はオークション数の集計です
+type AuctionStats struct {
+	Total              int64 `json:"total"`
+	Live               int64 `json:"live"`
+	Ended              int64 `json:"ended"`
+	Cancelled          int64 `json:"cancelled"`
+	AwaitingInspection int64 `json:"awaiting_inspection"`
+}
+
+// BidStats は入札数の集計です
+type BidStats struct {
+	Total   int64 `json:"total"`
+	Last24h int64 `json:"last_24h"`
+	Voided  int64 `json:"voided"`
+}
+
+// Users はロール別・停止中のユーザー数を集計します
+func (r *StatsRepo) Users() (*UserStats, error) {
+	var rows []struct {
+		Role string
+		N    int64
+	}
+	// 複数ロールのユーザーは各ロールで数えるため、ロール別の合計は Total を超えることがあります
+	err := r.DB.Model(&model.UserRole{}).
+		Joins("JOIN users ON users.id = user_roles.user_id AND users.deleted_at IS NULL").
+		Select("user_roles.role, COUNT(*) AS n").Group("user_roles.role").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	st := &UserStats{ByRole: map[string]int64{}}
+	for _, row := range rows {
+		st.ByRole[row.Role] = row.N
+	}
+	if err := r.DB.Model(&model.User{}).Count(&st.Total).Error; err != nil {
+		return nil, err
+	}
+	err = r.DB.Model(&model.User{}).Where("suspended_at IS NOT NULL").Count(&st.Suspended).Error
+	return st, err
+}
+
+// Auctions は now 時点の状態別オークション数を集計します
+func (r *StatsRepo) Auctions(now time.Time) (*AuctionStats, error) {
+	st := &AuctionStats{}
+	counts := []struct {
+		dst   *int64
+		where string
+		args  []any
+	}{
+		{&st.Total, "1 = 1", nil},
+		{&st.Live, "end_at > ? AND inspection_status <> ?", []any{now, model.AuctionInspectionPending}},
+		{&st.Ended, "end_at <= ? AND cancelled_at IS NULL", []any{now}},
+		{&st.Cancelled, "cancelled_at IS NOT NULL", nil},
+		{&st.AwaitingInspection, "end_at > ? AND inspection_status = ?", []any{now, model.AuctionInspectionPending}},
+	}
+	for _, c := range counts {
+		if err := r.DB.Model(&model.Auction{}).Where(c.where, c.args...).Count(c.dst).Error; err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// Bids は総入札数・直近 24 時間の入札数・無効化された入札数を集計します
+func (r *StatsRepo) Bids(now time.Time) (*BidStats, error) {
+	st := &BidStats{}
+	if err := r.DB.Model(&model.Bid{}).Count(&st.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := r.DB.Model(&model.Bid{}).Where("created_at > ?", now.Add(-24*time.Hour)).Count(&st.Last24h).Error; err != nil {
+		return nil, err
+	}
+	err := r.DB.Model(&model.Bid{}).Where("voided_at IS NOT NULL").Count(&st.Voided).Error
+	return st, err
+}
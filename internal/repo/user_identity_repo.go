@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepo は外部 IdP のアカウントとの紐付けの永続化を担当するリポジトリです
+type UserIdentityRepo struct{ DB *gorm.DB }
+
+// NewUserIdentityRepo は新しい UserIdentityRepo を生成します
+func NewUserIdentityRepo(db *gorm.DB) *UserIdentityRepo { return &UserIdentityRepo{DB: db} }
+
+// Find は IdP と sub の紐付けを取得します（紐付けが無ければ nil）
+func (r *UserIdentityRepo) Find(issuer, subject string) (*model.UserIdentity, error) {
+	var list []model.UserIdentity
+	if err := r.DB.Where("issuer = ? AND subject = ?", issuer, subject).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// Create は紐付けを保存します（tx が nil なら既定の DB を使用）
+func (r *UserIdentityRepo) Create(tx *gorm.DB, id *model.UserIdentity) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Create(id).Error
+}
+
+// Touch はログイン日時と IdP から受け取ったメールアドレスを更新します
+func (r *UserIdentityRepo) Touch(id uint, email string, now time.Time) error {
+	return r.DB.Model(&model.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]any{"email": email, "last_login_at": now}).Error
+}
+
+// CreateWithUser は新しいユーザー（付与ロールを含む）と紐付けを同じトランザクションで保存します
+func (r *UserIdentityRepo) CreateWithUser(u *model.User, id *model.UserIdentity) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		id.UserID = u.ID
+		return tx.Create(id).Error
+	})
+}
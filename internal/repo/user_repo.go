@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"time"
+
 	"github.com/ksj/car-auction/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepo はユーザーの永続化を担当するリポジトリです
@@ -15,13 +18,197 @@ func NewUserRepo(db *gorm.DB) *UserRepo { return &UserRepo{DB: db} }
 // メールアドレスが重複していないか確認する際にも使用されます
 func (r *UserRepo) FindByEmail(email string) (*model.User, error) {
 	var u model.User
-	if err := r.DB.Where("email = ?", email).First(&u).Error; err != nil {
+	if err := r.DB.Preload("Roles").Where("email = ?", email).First(&u).Error; err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-// Create は新しいユーザーをデータベースに保存します
+// Create は新しいユーザーを付与ロール (u.Roles) とともにデータベースに保存します
 func (r *UserRepo) Create(u *model.User) error {
 	return r.DB.Create(u).Error
 }
+
+// Get はトランザクション内でユーザーを取得します（付与ロールは読み込みません, tx が nil なら既定の DB を使用）
+// 入札時の検査など、ロックを取得したトランザクションと同じ接続で読む場合に使います
+func (r *UserRepo) Get(tx *gorm.DB, id uint) (*model.User, error) {
+	if tx == nil {
+		tx = r.DB
+	}
+	var u model.User
+	if err := tx.First(&u, id).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// FindByID は指定IDのユーザーを取得します
+func (r *UserRepo) FindByID(id uint) (*model.User, error) {
+	var u model.User
+	if err := r.DB.Preload("Roles").First(&u, id).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UserQuery は管理画面のユーザー検索条件です
+type UserQuery struct {
+	// Email はメールアドレスの部分一致です
+	Email     string
+	Role      string
+	Suspended *bool
+	// DeletionRequested は本人がアカウントの削除を申請しているかです
+	DeletionRequested *bool
+}
+
+// Search は条件に一致するユーザーを新しい順にページネーション付きで取得し、総件数を返します
+func (r *UserRepo) Search(q UserQuery, page, size int) ([]model.User, int64, error) {
+	tx := r.DB.Model(&model.User{})
+	if q.Email != "" {
+		tx = tx.Where("email LIKE ?", "%"+q.Email+"%")
+	}
+	if q.Role != "" {
+		tx = tx.Where("id IN (?)", r.DB.Model(&model.UserRole{}).Select("user_id").Where("role = ?", q.Role))
+	}
+	if q.Suspended != nil {
+		if *q.Suspended {
+			tx = tx.Where("suspended_at IS NOT NULL")
+		} else {
+			tx = tx.Where("suspended_at IS NULL")
+		}
+	}
+	if q.DeletionRequested != nil {
+		if *q.DeletionRequested {
+			tx = tx.Where("deletion_requested_at IS NOT NULL")
+		} else {
+			tx = tx.Where("deletion_requested_at IS NULL")
+		}
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []model.User
+	if err := tx.Preload("Roles").Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// AddRole はユーザーにロールを付与します（付与済みなら何もしません）
+func (r *UserRepo) AddRole(id uint, role string) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserRole{UserID: id, Role: role, CreatedAt: time.Now()}).Error
+}
+
+// SetRoles はユーザーのロールを roles に置き換えます
+// 主ロール (users.role) が外れる場合は roles の先頭に変更します
+func (r *UserRepo) SetRoles(tx *gorm.DB, id uint, roles []string) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	if err := tx.Where("user_id = ? AND role NOT IN ?", id, roles).Delete(&model.UserRole{}).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, role := range roles {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.UserRole{UserID: id, Role: role, CreatedAt: now}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Model(&model.User{}).Where("id = ? AND role NOT IN ?", id, roles).Update("role", roles[0]).Error
+}
+
+// BackfillRoles は複数ロール導入前のユーザーに users.role を付与ロールとして登録します
+func (r *UserRepo) BackfillRoles() error {
+	return r.DB.Exec(`INSERT INTO user_roles (user_id, role, created_at)
+		SELECT users.id, users.role, users.created_at FROM users
+		WHERE users.role <> '' AND NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)`).Error
+}
+
+// BumpTokenVersion はトークンの世代番号を増やし、発行済みのアクセストークンを無効にします
+func (r *UserRepo) BumpTokenVersion(tx *gorm.DB, id uint) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// MarkEmailVerified はメールアドレスを確認済みにします（確認済みなら日時を変更しません）
+func (r *UserRepo) MarkEmailVerified(tx *gorm.DB, id uint, now time.Time) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", now).Error
+}
+
+// UpdatePassword はパスワードハッシュを変更します
+func (r *UserRepo) UpdatePassword(tx *gorm.DB, id uint, hash string) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// UpdateProfile はプロフィールの項目を変更します（fields は列名と値の組）
+func (r *UserRepo) UpdateProfile(tx *gorm.DB, id uint, fields map[string]any) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// UpdateEmail はメールアドレスを変更し、確認済みにします（変更先への確認メールで受信を確認した後に使います）
+func (r *UserRepo) UpdateEmail(tx *gorm.DB, id uint, email string, now time.Time) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Updates(map[string]any{
+		"email":             email,
+		"email_verified_at": now,
+	}).Error
+}
+
+// SetDealerVerified は業者の本人確認の承認日時を設定します（at が nil なら承認を取り消します）
+func (r *UserRepo) SetDealerVerified(tx *gorm.DB, id uint, at *time.Time) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Update("dealer_verified_at", at).Error
+}
+
+// SetDeletionRequest はアカウント削除の申請日時と理由を設定します（at が nil なら申請を取り消します）
+func (r *UserRepo) SetDeletionRequest(tx *gorm.DB, id uint, at *time.Time, reason string) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Updates(map[string]any{
+		"deletion_requested_at": at,
+		"deletion_reason":       reason,
+	}).Error
+}
+
+// SetTOTP は二要素認証の共有鍵・有効化日時・最後に受け付けたコードの時間ステップを設定します
+// secret が空、enabledAt が nil なら無効化します
+func (r *UserRepo) SetTOTP(tx *gorm.DB, id uint, secret string, enabledAt *time.Time, lastStep int64) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return tx.Model(&model.User{}).Where("id = ?", id).Updates(map[string]any{
+		"totp_secret":     secret,
+		"totp_enabled_at": enabledAt,
+		"totp_last_step":  lastStep,
+	}).Error
+}
+
+// AcceptTOTPStep は受け付けたコードの時間ステップを記録します
+// 同じか古いステップが既に記録されていれば false を返します（コードの再利用の拒否）
+func (r *UserRepo) AcceptTOTPStep(id uint, step int64) (bool, error) {
+	res := r.DB.Model(&model.User{}).Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserTokenRepo はメールアドレス確認・パスワード再設定用トークンの永続化を担当するリポジトリです
+type UserTokenRepo struct{ DB *gorm.DB }
+
+// NewUserTokenRepo は新しい UserTokenRepo を生成します
+func NewUserTokenRepo(db *gorm.DB) *UserTokenRepo { return &UserTokenRepo{DB: db} }
+
+// Replace はユーザーの同じ用途の未使用トークンを無効にし、新しいトークンを保存します
+func (r *UserTokenRepo) Replace(t *model.UserToken) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", t.UserID, t.Purpose).
+			Update("used_at", t.CreatedAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(t).Error
+	})
+}
+
+// Consume は有効期限内の未使用トークンを使用済みにして返します
+// 存在しない・期限切れ・使用済みの場合は gorm.ErrRecordNotFound を返します（同時に使われても成功するのは 1 回のみ）
+func (r *UserTokenRepo) Consume(tx *gorm.DB, hash, purpose string, now time.Time) (*model.UserToken, error) {
+	var t model.UserToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", hash, purpose).First(&t).Error; err != nil {
+		return nil, err
+	}
+	res := tx.Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", t.ID, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &t, nil
+}
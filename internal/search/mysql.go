@@ -0,0 +1,61 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const mysqlFulltextIndex = "ft_auctions_title_description"
+
+// mysqlIndex は ngram パーサーの FULLTEXT インデックスを使う MySQL 向けの実装です
+// ngram はトークンを空白に頼らず分割するため、日本語・韓国語の検索語にも一致します
+type mysqlIndex struct{ db *gorm.DB }
+
+// Migrate は auctions(title, description) に FULLTEXT インデックスを作成します
+func (m *mysqlIndex) Migrate() error {
+	var n int64
+	if err := m.db.Raw(`SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'auctions' AND index_name = ?`,
+		mysqlFulltextIndex).Scan(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return m.db.Exec("ALTER TABLE auctions ADD FULLTEXT INDEX " + mysqlFulltextIndex +
+		" (title, description) WITH PARSER ngram").Error
+}
+
+// booleanQuery は各検索語を必須フレーズとする BOOLEAN MODE のクエリを組み立てます
+// ngram_token_size (既定 2) 未満の語は LIKE で補うため返しません
+func (m *mysqlIndex) booleanQuery(q string) (string, []string) {
+	long, short := splitByLength(terms(q), 2)
+	parts := make([]string, len(long))
+	for i, w := range long {
+		parts[i] = `+"` + strings.ReplaceAll(w, `"`, "") + `"`
+	}
+	return strings.Join(parts, " "), short
+}
+
+func (m *mysqlIndex) Match(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		expr, short := m.booleanQuery(q)
+		if expr != "" {
+			db = db.Where("MATCH(auctions.title, auctions.description) AGAINST (? IN BOOLEAN MODE)", expr)
+		}
+		return likeScope(db, short)
+	}
+}
+
+func (m *mysqlIndex) Rank(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		expr, _ := m.booleanQuery(q)
+		if expr == "" {
+			return db
+		}
+		return db.
+			Select("auctions.*, MATCH(auctions.title, auctions.description) AGAINST (? IN BOOLEAN MODE) AS relevance", expr).
+			Order("relevance DESC")
+	}
+}
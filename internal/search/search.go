@@ -0,0 +1,73 @@
+// Package search はオークションのタイトル・説明文に対する全文検索を提供します。
+// DB の方言ごとに実装を切り替え、本番 (MySQL) では ngram パーサーの FULLTEXT インデックス、
+// テスト (SQLite) では trigram トークナイザーの FTS5 仮想テーブルを使用します。
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Index はオークションの全文検索インデックスを表します
+type Index interface {
+	// Migrate は全文検索用のインデックスを作成します（既に存在する場合は何もしません）
+	Migrate() error
+	// Match は検索語 q に一致するオークションに絞り込むスコープを返します
+	Match(q string) func(*gorm.DB) *gorm.DB
+	// Rank は検索語 q との関連度を relevance 列として選択し、関連度が高い順に並べるスコープを返します
+	Rank(q string) func(*gorm.DB) *gorm.DB
+}
+
+// New は db の方言に応じた Index を返します
+func New(db *gorm.DB) Index {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return &mysqlIndex{db: db}
+	case "sqlite":
+		return &sqliteIndex{db: db}
+	default:
+		return &likeIndex{}
+	}
+}
+
+// terms は検索文字列を空白（全角スペースを含む）で区切った検索語に分割します
+func terms(q string) []string {
+	return strings.Fields(q)
+}
+
+// splitByLength は検索語を n 文字以上のものと n 文字未満のものに分けます
+// n-gram インデックスは n 文字未満の語を検索できないため LIKE で補います
+func splitByLength(words []string, n int) (long, short []string) {
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= n {
+			long = append(long, w)
+		} else {
+			short = append(short, w)
+		}
+	}
+	return long, short
+}
+
+// likeScope は各検索語がタイトルまたは説明文に含まれる行に絞り込みます
+func likeScope(q *gorm.DB, words []string) *gorm.DB {
+	for _, w := range words {
+		pattern := "%" + w + "%"
+		q = q.Where("auctions.title LIKE ? OR auctions.description LIKE ?", pattern, pattern)
+	}
+	return q
+}
+
+// likeIndex は全文検索機能を持たない DB 向けの LIKE による実装です（関連度順の並び替えは行いません）
+type likeIndex struct{}
+
+func (likeIndex) Migrate() error { return nil }
+
+func (likeIndex) Match(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB { return likeScope(db, terms(q)) }
+}
+
+func (likeIndex) Rank(string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB { return db }
+}
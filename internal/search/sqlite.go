@@ -0,0 +1,71 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqliteIndex は FTS5 (trigram トークナイザー) の外部コンテンツテーブルを使う SQLite 向けの実装です
+// 主にテスト用で、auctions テーブルの変更はトリガーで auctions_fts に反映されます
+type sqliteIndex struct{ db *gorm.DB }
+
+// Migrate は auctions_fts 仮想テーブルと同期用トリガーを作成し、既存行を索引付けします
+func (s *sqliteIndex) Migrate() error {
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS auctions_fts USING fts5(
+			title, description, content='auctions', content_rowid='id', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS auctions_fts_ai AFTER INSERT ON auctions BEGIN
+			INSERT INTO auctions_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS auctions_fts_ad AFTER DELETE ON auctions BEGIN
+			INSERT INTO auctions_fts(auctions_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS auctions_fts_au AFTER UPDATE ON auctions BEGIN
+			INSERT INTO auctions_fts(auctions_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+			INSERT INTO auctions_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+		END`,
+		`INSERT INTO auctions_fts(auctions_fts) VALUES ('rebuild')`,
+	}
+	for _, stmt := range stmts {
+		if err := s.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchQuery は各検索語をフレーズとして AND で結合した FTS5 クエリを組み立てます
+// trigram は 3 文字未満の語に一致しないため、それらは LIKE で補うため返しません
+func (s *sqliteIndex) matchQuery(q string) (string, []string) {
+	long, short := splitByLength(terms(q), 3)
+	parts := make([]string, len(long))
+	for i, w := range long {
+		parts[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, " AND "), short
+}
+
+func (s *sqliteIndex) Match(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		expr, short := s.matchQuery(q)
+		if expr != "" {
+			db = db.Where("auctions.id IN (SELECT rowid FROM auctions_fts WHERE auctions_fts MATCH ?)", expr)
+		}
+		return likeScope(db, short)
+	}
+}
+
+// Rank は bm25 スコア（小さいほど関連度が高い）の昇順に並べます
+func (s *sqliteIndex) Rank(q string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		expr, _ := s.matchQuery(q)
+		if expr == "" {
+			return db
+		}
+		return db.
+			Select(`auctions.*, (SELECT bm25(auctions_fts) FROM auctions_fts
+				WHERE auctions_fts MATCH ? AND auctions_fts.rowid = auctions.id) AS relevance`, expr).
+			Order("relevance ASC")
+	}
+}
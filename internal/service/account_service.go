@@ -0,0 +1,295 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/mail"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAccountToken はメールアドレス確認・パスワード再設定のトークンが存在しない・期限切れ・使用済みの場合に返されます
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+	// ErrEmailAlreadyVerified は確認済みのメールアドレスに確認メールを再送しようとした場合に返されます
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	// ErrEmailNotVerified はメールアドレスが未確認のユーザーが入札した場合に返されます
+	ErrEmailNotVerified = errors.New("email address is not verified")
+	// ErrInvalidPassword は新しいパスワードが要件を満たさない場合に返されます
+	ErrInvalidPassword = errors.New("password must be at least 8 characters")
+	// ErrInvalidEmail はメールアドレスの形式が正しくない場合に返されます
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailInUse は変更先のメールアドレスを他のユーザーが使用している場合に返されます
+	ErrEmailInUse = errors.New("email address is already in use")
+)
+
+const (
+	// emailVerifyTTL はメールアドレス確認トークンの有効期限です
+	emailVerifyTTL = 48 * time.Hour
+	// passwordResetTTL はパスワード再設定トークンの有効期限です
+	passwordResetTTL = 30 * time.Minute
+	// emailChangeTTL はメールアドレス変更の確認トークンの有効期限です
+	emailChangeTTL = 24 * time.Hour
+	// minPasswordLen はパスワード再設定時の最小文字数です
+	minPasswordLen = 8
+)
+
+// AccountService はメールアドレス確認・パスワード再設定・メールアドレス変更を担当します
+type AccountService struct {
+	users           *repo.UserRepo
+	tokens          *repo.UserTokenRepo
+	refresh         *repo.RefreshTokenRepo
+	mailer          mail.Sender
+	appURL          string
+	requireVerified bool
+	// audit はメールアドレス変更の監査ログです（nil なら記録しません）
+	audit *AuditService
+}
+
+// NewAccountService はリポジトリとメール送信手段を注入して AccountService を生成します
+// appURL はメール本文のリンク先、requireVerified はメールアドレス未確認のユーザーの入札を拒否するかです
+func NewAccountService(users *repo.UserRepo, tokens *repo.UserTokenRepo, refresh *repo.RefreshTokenRepo,
+	mailer mail.Sender, appURL string, requireVerified bool) *AccountService {
+	return &AccountService{users: users, tokens: tokens, refresh: refresh, mailer: mailer,
+		appURL: appURL, requireVerified: requireVerified}
+}
+
+// UseAudit はメールアドレス変更の申請・完了を記録する監査ログを設定します
+func (s *AccountService) UseAudit(a *AuditService) { s.audit = a }
+
+// SendVerification はメールアドレス確認のリンクをメールで送ります（以前に送ったリンクは無効になります）
+func (s *AccountService) SendVerification(userID uint) error {
+	u, err := s.users.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	raw, err := s.issue(&model.UserToken{UserID: u.ID, Purpose: model.TokenEmailVerify}, emailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("以下のリンクからメールアドレスの確認を完了してください（有効期限: %d 時間）。\n\n%s\n",
+		int(emailVerifyTTL.Hours()), s.link("/verify-email", raw))
+	return s.mailer.Send(u.Email, "メールアドレスの確認", body)
+}
+
+// SignupListener は UserService.OnSignup に登録するリスナーで、新規ユーザーに確認メールを送ります
+// 送信に失敗してもサインアップは成功させ、ユーザーは確認メールを再送できます
+func (s *AccountService) SignupListener(u *model.User) {
+	if err := s.SendVerification(u.ID); err != nil {
+		log.Printf("account: failed to send verification mail to user %d: %v", u.ID, err)
+	}
+}
+
+// VerifyEmail はメールアドレス確認トークンを使用済みにし、メールアドレスを確認済みにします
+func (s *AccountService) VerifyEmail(raw string) error {
+	return s.users.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := s.consume(tx, raw, model.TokenEmailVerify)
+		if err != nil {
+			return err
+		}
+		return s.users.MarkEmailVerified(tx, t.UserID, time.Now())
+	})
+}
+
+// ForgotPassword はパスワード再設定のリンクをメールで送ります
+// 登録の有無を推測されないよう、未登録のメールアドレスでもエラーを返しません
+func (s *AccountService) ForgotPassword(email string) error {
+	u, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := s.issue(&model.UserToken{UserID: u.ID, Purpose: model.TokenPasswordReset}, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("以下のリンクからパスワードを再設定してください（有効期限: %d 分）。\n"+
+		"お心当たりが無い場合はこのメールを破棄してください。\n\n%s\n",
+		int(passwordResetTTL.Minutes()), s.link("/reset-password", raw))
+	return s.mailer.Send(u.Email, "パスワードの再設定", body)
+}
+
+// ResetPassword はパスワード再設定トークンを使用済みにしてパスワードを変更します
+// 発行済みのリフレッシュトークン・アクセストークンはすべて無効にし、メールを受け取れたことからメールアドレスも確認済みにします
+func (s *AccountService) ResetPassword(raw, password string) error {
+	if len(password) < minPasswordLen {
+		return ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.users.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := s.consume(tx, raw, model.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := s.users.UpdatePassword(tx, t.UserID, string(hash)); err != nil {
+			return err
+		}
+		if err := s.users.MarkEmailVerified(tx, t.UserID, now); err != nil {
+			return err
+		}
+		if err := s.refresh.RevokeUser(tx, t.UserID, now); err != nil {
+			return err
+		}
+		return s.users.BumpTokenVersion(tx, t.UserID)
+	})
+}
+
+// RequestEmailChange は現在のパスワードを確認し、変更先のメールアドレスに確認のリンクを送ります
+// リンクから ConfirmEmailChange で確認するまでメールアドレスは変わりません（現在のアドレスにも通知を送ります）
+func (s *AccountService) RequestEmailChange(actor Actor, password, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if addr, err := netmail.ParseAddress(newEmail); err != nil || addr.Address != newEmail {
+		return ErrInvalidEmail
+	}
+	u, err := s.users.FindByID(actor.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return ErrIncorrectPassword
+	}
+	if err := s.checkEmailFree(nil, newEmail); err != nil {
+		return err
+	}
+	raw, err := s.issue(&model.UserToken{UserID: u.ID, Purpose: model.TokenEmailChange, NewEmail: newEmail}, emailChangeTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.audited(nil, actor, AuditEntry{Action: AuditEmailChangeRequest,
+		Detail: map[string]any{"from": u.Email, "to": newEmail}}); err != nil {
+		return err
+	}
+	body := fmt.Sprintf("以下のリンクからメールアドレスの変更を完了してください（有効期限: %d 時間）。\n"+
+		"お心当たりが無い場合はこのメールを破棄してください。\n\n%s\n",
+		int(emailChangeTTL.Hours()), s.link("/confirm-email", raw))
+	if err := s.mailer.Send(newEmail, "メールアドレスの変更の確認", body); err != nil {
+		return err
+	}
+	notice := fmt.Sprintf("アカウントのメールアドレスを %s に変更する手続きが行われました。\n"+
+		"お心当たりが無い場合はパスワードを変更してください。\n", newEmail)
+	return s.mailer.Send(u.Email, "メールアドレスの変更", notice)
+}
+
+// ConfirmEmailChange はメールアドレス変更の確認トークンを使用済みにし、メールアドレスを変更先に変更します
+// 変更先で受信できたことから、変更後のメールアドレスは確認済みとします
+func (s *AccountService) ConfirmEmailChange(raw string) error {
+	return s.users.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := s.consume(tx, raw, model.TokenEmailChange)
+		if err != nil {
+			return err
+		}
+		u, err := s.users.Get(tx, t.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// 申請後に他のユーザーが同じメールアドレスを登録した場合
+		if err := s.checkEmailFree(tx, t.NewEmail); err != nil {
+			return err
+		}
+		if err := s.users.UpdateEmail(tx, t.UserID, t.NewEmail, time.Now()); err != nil {
+			return err
+		}
+		return s.audited(tx, Actor{UserID: t.UserID}, AuditEntry{Action: AuditEmailChange,
+			Detail: map[string]any{"from": u.Email, "to": t.NewEmail}})
+	})
+}
+
+// checkEmailFree はメールアドレスを他のユーザーが使用していないか確認します（使用中なら ErrEmailInUse）
+func (s *AccountService) checkEmailFree(tx *gorm.DB, email string) error {
+	if tx == nil {
+		tx = s.users.DB
+	}
+	var n int64
+	if err := tx.Model(&model.User{}).Unscoped().Where("email = ?", email).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrEmailInUse
+	}
+	return nil
+}
+
+// audited は監査ログを tx で記録します（監査ログが未設定なら何もしません）
+func (s *AccountService) audited(tx *gorm.DB, actor Actor, e AuditEntry) error {
+	if s.audit == nil {
+		return nil
+	}
+	e.TargetType, e.TargetID = AuditTargetUser, actor.UserID
+	return s.audit.record(tx, actor, e)
+}
+
+// GuardBid は BidService に登録する入札時の検査で、メールアドレスが未確認のユーザーの入札を拒否します
+func (s *AccountService) GuardBid(tx *gorm.DB, _ *model.Auction, bid *model.Bid) error {
+	if !s.requireVerified {
+		return nil
+	}
+	u, err := s.users.Get(tx, bid.UserID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// issue はトークン t（UserID・Purpose などを設定済み）を発行して保存し、メールに載せるトークン本体を返します
+func (s *AccountService) issue(t *model.UserToken, ttl time.Duration) (string, error) {
+	b, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	t.TokenHash = hashToken(raw)
+	t.ExpiresAt = now.Add(ttl)
+	t.CreatedAt = now
+	if err := s.tokens.Replace(t); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// consume はトークンを使用済みにして返します（無効なトークンは ErrInvalidAccountToken）
+func (s *AccountService) consume(tx *gorm.DB, raw, purpose string) (*model.UserToken, error) {
+	if raw == "" {
+		return nil, ErrInvalidAccountToken
+	}
+	t, err := s.tokens.Consume(tx, hashToken(raw), purpose, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAccountToken
+	}
+	return t, err
+}
+
+// link はフロントエンドの path にトークンをクエリとして付けた URL を返します
+func (s *AccountService) link(path, raw string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(raw)
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/ws"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrAdminTargetNotFound は操作対象のユーザー・オークション・入札が存在しない場合に返されます
+	ErrAdminTargetNotFound = errors.New("target not found")
+	// ErrAdminConflict は対象が既にその状態である（停止済み・終了済みなど）場合に返されます
+	ErrAdminConflict = errors.New("target state conflict")
+	// ErrInvalidAdminAction は理由の未入力や管理者自身・他の管理者の停止など、実行できない操作の場合に返されます
+	ErrInvalidAdminAction = errors.New("invalid admin action")
+)
+
+// 監査ログの操作名
+const (
+	AuditUserSuspend   = "user.suspend"
+	AuditUserReinstate = "user.reinstate"
+	AuditUserRoles     = "user.roles"
+	AuditAuctionClose  = "auction.close"
+	AuditAuctionCancel = "auction.cancel"
+	AuditBidVoid       = "bid.void"
+)
+
+// maxAdminReasonRunes は操作理由の最大文字数です
+const maxAdminReasonRunes = 255
+
+// AdminStats は管理画面のシステム統計です
+type AdminStats struct {
+	Users       *repo.UserStats    `json:"users"`
+	Auctions    *repo.AuctionStats `json:"auctions"`
+	Bids        *repo.BidStats     `json:"bids"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// AdminService は管理者向けの操作を提供します
+// 状態を変更する操作はすべて、変更と同じトランザクションで監査ログを記録します
+type AdminService struct {
+	db       *gorm.DB
+	users    *repo.UserRepo
+	auctions *repo.AuctionRepo
+	stats    *repo.StatsRepo
+	audit    *AuditService
+	notifier *NotificationService
+	hub      *ws.Hub
+	// loginGuard はログイン失敗によるロックの解除に使います（nil ならロック解除できません）
+	loginGuard *LoginGuard
+}
+
+// NewAdminService はリポジトリ・監査ログ・通知・WebSocket Hub を注入して AdminService を生成します
+func NewAdminService(users *repo.UserRepo, auctions *repo.AuctionRepo, stats *repo.StatsRepo,
+	audit *AuditService, notifier *NotificationService, hub *ws.Hub) *AdminService {
+	return &AdminService{db: users.DB, users: users, auctions: auctions, stats: stats,
+		audit: audit, notifier: notifier, hub: hub}
+}
+
+// ListUsers はユーザーを検索条件付きで新しい順に取得します
+func (s *AdminService) ListUsers(q repo.UserQuery, page, size int) ([]model.User, int64, error) {
+	if q.Role != "" && !rbac.ValidRole(q.Role) {
+		return nil, 0, fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAction, q.Role)
+	}
+	return s.users.Search(q, page, size)
+}
+
+// GetUser はユーザーを取得します
+func (s *AdminService) GetUser(id uint) (*model.User, error) {
+	u, err := s.users.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAdminTargetNotFound
+	}
+	return u, err
+}
+
+// SuspendUser はアカウントを停止します
+// 停止中はログインできず、発行済みのトークンも AuthMiddleware で拒否されます
+func (s *AdminService) SuspendUser(actor Actor, id uint, reason string) (*model.User, error) {
+	reason, err := adminReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.ID == actor.UserID || u.HasRole(model.RoleAdmin) {
+		return nil, fmt.Errorf("%w: administrators cannot be suspended", ErrInvalidAdminAction)
+	}
+	now := time.Now()
+	err = s.audited(actor, AuditEntry{Action: AuditUserSuspend, TargetType: AuditTargetUser, TargetID: id, Reason: reason},
+		func(tx *gorm.DB) error {
+			res := tx.Model(&model.User{}).Where("id = ? AND suspended_at IS NULL", id).
+				Updates(map[string]any{"suspended_at": now, "suspend_reason": reason})
+			return rowsOrConflict(res)
+		})
+	if err != nil {
+		return nil, err
+	}
+	u.SuspendedAt, u.SuspendReason = &now, reason
+	s.notify(u.ID, nil, "account_suspended", "アカウントが停止されました", "理由: "+reason)
+	return u, nil
+}
+
+// ReinstateUser は停止中のアカウントを再開します
+func (s *AdminService) ReinstateUser(actor Actor, id uint, reason string) (*model.User, error) {
+	u, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	err = s.audited(actor, AuditEntry{Action: AuditUserReinstate, TargetType: AuditTargetUser, TargetID: id, Reason: reason,
+		Detail: map[string]any{"suspended_at": u.SuspendedAt, "suspend_reason": u.SuspendReason}},
+		func(tx *gorm.DB) error {
+			res := tx.Model(&model.User{}).Where("id = ? AND suspended_at IS NOT NULL", id).
+				Updates(map[string]any{"suspended_at": nil, "suspend_reason": ""})
+			return rowsOrConflict(res)
+		})
+	if err != nil {
+		return nil, err
+	}
+	u.SuspendedAt, u.SuspendReason = nil, ""
+	s.notify(u.ID, nil, "account_reinstated", "アカウントが再開されました", "")
+	return u, nil
+}
+
+// UseLoginGuard はロック解除の対象となるログイン失敗の回数制限を設定します
+func (s *AdminService) UseLoginGuard(g *LoginGuard) { s.loginGuard = g }
+
+// UnlockUser はログイン失敗によるアカウントのロックと待ち時間を解除します
+// ロックの状態は DB 以外（メモリ）に保存される場合もあるため、解除の後で監査ログを記録します
+func (s *AdminService) UnlockUser(actor Actor, id uint, reason string) (*model.User, error) {
+	if s.loginGuard == nil {
+		return nil, fmt.Errorf("%w: login lockout is not enabled", ErrInvalidAdminAction)
+	}
+	u, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.Unlock(u.Email); err != nil {
+		return nil, err
+	}
+	err = s.audit.Record(actor, AuditEntry{Action: AuditUserUnlock, TargetType: AuditTargetUser, TargetID: id,
+		Reason: strings.TrimSpace(reason)})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetUserRoles はユーザーのロールを roles に置き換えます
+// 自分自身から admin ロールを外すことはできません（管理者不在を防ぐため）
+func (s *AdminService) SetUserRoles(actor Actor, id uint, roles []string, reason string) (*model.User, error) {
+	var clean []string
+	for _, r := range roles {
+		if !rbac.ValidRole(r) {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAction, r)
+		}
+		if !slices.Contains(clean, r) {
+			clean = append(clean, r)
+		}
+	}
+	if len(clean) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidAdminAction)
+	}
+	u, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.ID == actor.UserID && !slices.Contains(clean, model.RoleAdmin) {
+		return nil, fmt.Errorf("%w: cannot remove your own admin role", ErrInvalidAdminAction)
+	}
+	err = s.audited(actor, AuditEntry{Action: AuditUserRoles, TargetType: AuditTargetUser, TargetID: id,
+		Reason: strings.TrimSpace(reason), Detail: map[string]any{"before": u.RoleNames(), "after": clean}},
+		func(tx *gorm.DB) error { return s.users.SetRoles(tx, id, clean) })
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUser(id)
+}
+
+// CloseAuction は開催中のオークションを即時終了します（その時点の最高入札が落札となります）
+func (s *AdminService) CloseAuction(actor Actor, id uint, reason string) (*model.Auction, error) {
+	reason, err := adminReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.findAuction(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = s.audited(actor, AuditEntry{Action: AuditAuctionClose, TargetType: AuditTargetAuction, TargetID: id, Reason: reason,
+		Detail: map[string]any{"end_at": a.EndAt}},
+		func(tx *gorm.DB) error {
+			res := tx.Model(&model.Auction{}).Where("id = ? AND end_at > ?", id, now).Update("end_at", now)
+			return rowsOrConflict(res)
+		})
+	if err != nil {
+		return nil, err
+	}
+	a.EndAt = now
+	s.broadcast(id, map[string]any{"type": "auction_closed", "auction_id": id, "end_at": now})
+	s.notify(a.SellerID, &a.ID, "auction_closed", "オークションが管理者により終了されました", "理由: "+reason)
+	return a, nil
+}
+
+// CancelAuction はオークションを取り消します
+// 開催中であれば同時に終了し、取り消したオークションは落札なしとして扱います
+func (s *AdminService) CancelAuction(actor Actor, id uint, reason string) (*model.Auction, error) {
+	reason, err := adminReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.findAuction(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	endAt := a.EndAt
+	if endAt.After(now) {
+		endAt = now
+	}
+	err = s.audited(actor, AuditEntry{Action: AuditAuctionCancel, TargetType: AuditTargetAuction, TargetID: id, Reason: reason,
+		Detail: map[string]any{"end_at": a.EndAt, "current_price": a.CurrentPrice}},
+		func(tx *gorm.DB) error {
+			res := tx.Model(&model.Auction{}).Where("id = ? AND cancelled_at IS NULL", id).
+				Updates(map[string]any{"cancelled_at": now, "cancel_reason": reason, "end_at": endAt})
+			return rowsOrConflict(res)
+		})
+	if err != nil {
+		return nil, err
+	}
+	a.CancelledAt, a.CancelReason, a.EndAt = &now, reason, endAt
+	s.broadcast(id, map[string]any{"type": "auction_cancelled", "auction_id": id, "reason": reason})
+	s.notify(a.SellerID, &a.ID, "auction_cancelled", "オークションが管理者により取り消されました", "理由: "+reason)
+	return a, nil
+}
+
+// VoidBid は不正な入札を無効にし、オークションの現在価格を有効な入札から再計算します
+func (s *AdminService) VoidBid(actor Actor, bidID uint, reason string) (*model.Bid, error) {
+	reason, err := adminReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	var bid model.Bid
+	var price int
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bid, bidID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAdminTargetNotFound
+			}
+			return err
+		}
+		if bid.VoidedAt != nil {
+			return ErrAdminConflict
+		}
+		var auc model.Auction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&auc, bid.AuctionID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&bid).Updates(map[string]any{"voided_at": now, "void_reason": reason}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&model.Bid{}).Select("COALESCE(MAX(amount), 0)").
+			Where("auction_id = ? AND voided_at IS NULL", bid.AuctionID).Scan(&price).Error
+		if err != nil {
+			return err
+		}
+		price = max(price, auc.StartPrice)
+		if err := tx.Model(&auc).Update("current_price", price).Error; err != nil {
+			return err
+		}
+		return s.audit.record(tx, actor, AuditEntry{Action: AuditBidVoid, TargetType: AuditTargetBid, TargetID: bidID, Reason: reason,
+			Detail: map[string]any{"auction_id": bid.AuctionID, "amount": bid.Amount,
+				"previous_price": auc.CurrentPrice, "current_price": price}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	bid.VoidedAt, bid.VoidReason = &now, reason
+	s.broadcast(bid.AuctionID, map[string]any{"type": "bid_voided", "bid_id": bid.ID, "current_price": price})
+	s.notify(bid.UserID, &bid.AuctionID, "bid_voided", "入札が管理者により無効にされました", "理由: "+reason)
+	return &bid, nil
+}
+
+// Stats はユーザー・オークション・入札の統計を返します
+func (s *AdminService) Stats() (*AdminStats, error) {
+	now := time.Now()
+	users, err := s.stats.Users()
+	if err != nil {
+		return nil, err
+	}
+	auctions, err := s.stats.Auctions(now)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := s.stats.Bids(now)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminStats{Users: users, Auctions: auctions, Bids: bids, GeneratedAt: now}, nil
+}
+
+// AuditLogs は監査ログを新しい順に取得します
+func (s *AdminService) AuditLogs(q repo.AuditQuery, limit int) ([]model.AuditLog, error) {
+	return s.audit.List(q, limit)
+}
+
+// audited は fn による変更と監査ログの記録を 1 つのトランザクションで実行します
+func (s *AdminService) audited(actor Actor, e AuditEntry, fn func(tx *gorm.DB) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return s.audit.record(tx, actor, e)
+	})
+}
+
+// findAuction はオークションを取得します
+func (s *AdminService) findAuction(id uint) (*model.Auction, error) {
+	a, err := s.auctions.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAdminTargetNotFound
+	}
+	return a, err
+}
+
+// broadcast はオークションの閲覧者に管理操作を通知します
+func (s *AdminService) broadcast(auctionID uint, ev map[string]any) {
+	if s.hub == nil {
+		return
+	}
+	data, _ := json.Marshal(ev)
+	s.hub.Broadcast(auctionID, data)
+}
+
+// notify は管理操作の対象ユーザーにアプリ内通知とメールを送ります（失敗は操作を止めません）
+func (s *AdminService) notify(userID uint, auctionID *uint, kind, title, body string) {
+	if s.notifier == nil {
+		return
+	}
+	_ = s.notifier.Notify(&model.Notification{
+		UserID: userID, Kind: kind, Title: title, Body: body, AuctionID: auctionID,
+	}, true)
+}
+
+// adminReason は操作理由を検証します（監査のため必須です）
+func adminReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: reason is required", ErrInvalidAdminAction)
+	}
+	if len([]rune(reason)) > maxAdminReasonRunes {
+		return "", fmt.Errorf("%w: reason is too long", ErrInvalidAdminAction)
+	}
+	return reason, nil
+}
+
+// rowsOrConflict は条件付き UPDATE が 1 行も更新しなかった場合に ErrAdminConflict を返します
+func rowsOrConflict(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAdminConflict
+	}
+	return nil
+}
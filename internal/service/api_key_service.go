@@ -0,0 +1,255 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAPIKey は API キーが存在しない・失効済み・期限切れの場合に返されます
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyIPNotAllowed は接続元 IP が API キーの許可リストに無い場合に返されます
+	ErrAPIKeyIPNotAllowed = errors.New("api key is not allowed from this address")
+	// ErrAPIKeyNotFound は失効させる API キーが存在しない（自分のキーでない・失効済み）場合に返されます
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKeyRequest は名前・権限・有効期限・許可リストが不正な場合や、キーの数が上限に達した場合に返されます
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+)
+
+const (
+	// apiKeyPrefix は API キーの先頭に付ける識別子です（漏洩したキーをスキャナーで検出しやすくするため）
+	apiKeyPrefix = "cak_"
+	// maxAPIKeysPerUser はユーザーごとに同時に有効にできる API キーの数です
+	maxAPIKeysPerUser = 20
+	// maxAPIKeyNameRunes は API キーの名前の最大文字数です
+	maxAPIKeyNameRunes = 100
+	// apiKeyTouchInterval より短い間隔の利用では最終利用日時を更新しません（毎リクエストの書き込みを避けるため）
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKeyRequest は API キーの作成時に受け取るリクエスト DTO です
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt を省略すると無期限です
+	ExpiresAt  *time.Time `json:"expires_at"`
+	AllowedIPs []string   `json:"allowed_ips"`
+}
+
+// CreatedAPIKey は作成した API キーです（キー本体は作成時の応答でのみ返します）
+type CreatedAPIKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyPrincipal は API キーで認証した利用者です
+// 使える権限は所有者の現在のロールの権限と Scopes の両方に含まれるものに限ります
+type APIKeyPrincipal struct {
+	UserID uint
+	Roles  []string
+	Scopes []string
+}
+
+// APIKeyService は API キーの作成・一覧・失効と、API キーによる認証を担当します
+type APIKeyService struct {
+	keys  *repo.APIKeyRepo
+	users *repo.UserRepo
+}
+
+// NewAPIKeyService はリポジトリを注入して APIKeyService を生成します
+func NewAPIKeyService(keys *repo.APIKeyRepo, users *repo.UserRepo) *APIKeyService {
+	return &APIKeyService{keys: keys, users: users}
+}
+
+// Create はユーザーの API キーを作成します
+// 権限は 1 つ以上、かつユーザーが現在持つ権限の範囲で指定します
+func (s *APIKeyService) Create(userID uint, req CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameRunes {
+		return nil, fmt.Errorf("%w: name is required (max %d characters)", ErrInvalidAPIKeyRequest, maxAPIKeyNameRunes)
+	}
+	u, err := s.users.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := apiKeyScopes(req.Scopes, rbac.Permissions(u.RoleNames()))
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := apiKeyAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+	}
+	n, err := s.keys.CountActive(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("%w: at most %d active api keys are allowed", ErrInvalidAPIKeyRequest, maxAPIKeysPerUser)
+	}
+
+	id, err := randomToken(4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	prefix := apiKeyPrefix + hex.EncodeToString(id)
+	raw := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	k := &model.APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		KeyHash:    hashToken(raw),
+		Scopes:     scopes,
+		AllowedIPs: allowed,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  now,
+	}
+	if err := s.keys.Create(k); err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{APIKey: k, Key: raw}, nil
+}
+
+// apiKeyScopes は指定された権限を検証し、重複を除いて昇順に並べます
+func apiKeyScopes(scopes, granted []string) ([]string, error) {
+	var out []string
+	for _, sc := range scopes {
+		sc = strings.TrimSpace(sc)
+		if !slices.Contains(granted, sc) {
+			return nil, fmt.Errorf("%w: scope %q is not granted to the user", ErrInvalidAPIKeyRequest, sc)
+		}
+		out = append(out, sc)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// apiKeyAllowedIPs は許可リストの IP アドレス・CIDR を検証し、正規化した表記で返します
+func apiKeyAllowedIPs(list []string) ([]string, error) {
+	out := []string{}
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if p, err := netip.ParsePrefix(v); err == nil {
+			out = append(out, p.Masked().String())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid ip address or cidr %q", ErrInvalidAPIKeyRequest, v)
+		}
+		out = append(out, addr.Unmap().String())
+	}
+	return out, nil
+}
+
+// ipAllowed は接続元 IP が許可リストに含まれるかを返します（許可リストが空ならすべて許可）
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, v := range allowed {
+		if p, err := netip.ParsePrefix(v); err == nil {
+			if p.Contains(addr) {
+				return true
+			}
+		} else if a, err := netip.ParseAddr(v); err == nil && a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// List はユーザーの API キーを新しい順に返します（失効済みを含む）
+func (s *APIKeyService) List(userID uint) ([]model.APIKey, error) {
+	return s.keys.ListByUser(userID)
+}
+
+// Revoke はユーザーの API キーを失効させます
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	ok, err := s.keys.Revoke(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate は "Authorization: ApiKey ..." のキーを検証し、所有者の現在のロールとキーの権限を返します
+// 所有者のアカウントが停止されている場合は ErrAccountSuspended を返します
+func (s *APIKeyService) Authenticate(raw, ip string) (*APIKeyPrincipal, error) {
+	rest, ok := strings.CutPrefix(raw, apiKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	k, err := s.keys.FindByPrefix(apiKeyPrefix + id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(k.KeyHash), []byte(hashToken(raw))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if k.RevokedAt != nil || (k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+	if !ipAllowed(k.AllowedIPs, ip) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+	u, err := s.users.FindByID(k.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.keys.Touch(k.ID, now); err != nil {
+			return nil, err
+		}
+	}
+	return &APIKeyPrincipal{UserID: u.ID, Roles: u.RoleNames(), Scopes: k.Scopes}, nil
+}
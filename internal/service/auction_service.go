@@ -3,10 +3,15 @@ package service
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ksj/car-auction/internal/model"
 	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/vin"
+	"github.com/ksj/car-auction/internal/ws"
+	"gorm.io/gorm"
 )
 
 // CreateAuctionRequest は API から受け取る JSON と 1:1 でマッピングされる DTO です
@@ -21,6 +26,12 @@ type CreateAuctionRequest struct {
 	Mileage   int    `json:"mileage"`
 	Year      int    `json:"year"`
 	PhotoURL  string `json:"photo_url"`
+	// PhotoKey は /upload が返したストレージキーです（指定時は PhotoURL より優先されます）
+	PhotoKey string `json:"photo_key"`
+
+	// VIN・車台番号（任意）。指定するとメーカー・車種・年式が未入力なら自動で補完されます
+	VIN           string `json:"vin"`
+	ChassisNumber string `json:"chassis_number"`
 }
 
 // UpdateAuctionRequest は更新可能なフィールドのみを保持する DTO です
@@ -31,14 +42,57 @@ type UpdateAuctionRequest struct {
 	EndAt       *time.Time `json:"end_at,omitempty"`
 }
 
+// AuctionDetail はオークション詳細に現在の閲覧状況を付加したレスポンスです
+type AuctionDetail struct {
+	*model.Auction
+	Viewers ws.Presence `json:"viewers"`
+	// Vehicle は VIN・車台番号からデコードした車両情報です
+	Vehicle *vin.Info `json:"vehicle,omitempty"`
+}
+
 // AuctionService はオークションのビジネスロジックを担当します
 type AuctionService struct {
 	repo *repo.AuctionRepo
+	hub  *ws.Hub
+	urls URLResolver
+
+	// onCreate は新規出品の保存後に呼び出されるリスナーです
+	onCreate []func(*model.Auction)
+	// guards は新規出品の保存前に出品者を検査する関数です（エラーを返すと出品を拒否します）
+	guards []func(sellerID uint) error
+}
+
+// NewAuctionService はリポジトリ、WebSocket Hub、写真 URL の解決手段を注入して AuctionService を生成します
+func NewAuctionService(r *repo.AuctionRepo, hub *ws.Hub, urls URLResolver) *AuctionService {
+	return &AuctionService{repo: r, hub: hub, urls: urls}
+}
+
+// withPhotoURLs はオークション一覧の各ギャラリーとカバー写真に配信用 URL を設定します
+func (s *AuctionService) withPhotoURLs(auctions []model.Auction) []model.Auction {
+	for i := range auctions {
+		s.resolvePhotos(&auctions[i])
+	}
+	return auctions
+}
+
+// resolvePhotos はオークションのギャラリーとカバー写真に配信用 URL を設定します
+// 署名付き URL は期限切れになるため、DB にはストレージキーを保存し取得のたびに URL を発行します
+func (s *AuctionService) resolvePhotos(a *model.Auction) {
+	resolvePhotoURLs(s.urls, a.Photos)
+	if a.PhotoKey != "" && s.urls != nil {
+		a.PhotoURL = s.urls.URL(a.PhotoKey)
+	}
+}
+
+// OnCreate は新規出品が保存された後に呼び出されるリスナーを登録します
+// リスナーは CreateAuction の呼び出し元と同じゴルーチンで順に実行されます
+func (s *AuctionService) OnCreate(fn func(*model.Auction)) {
+	s.onCreate = append(s.onCreate, fn)
 }
 
-// NewAuctionService はリポジトリを注入して AuctionService を生成します
-func NewAuctionService(r *repo.AuctionRepo) *AuctionService {
-	return &AuctionService{repo: r}
+// AddGuard は新規出品の保存前に呼び出される出品者の検査を登録します
+func (s *AuctionService) AddGuard(fn func(sellerID uint) error) {
+	s.guards = append(s.guards, fn)
 }
 
 // ListAuctions は全オークションを取得します (GET)
@@ -47,26 +101,57 @@ func (s *AuctionService) ListAuctions() ([]model.Auction, error) {
 }
 
 // CreateAuction は認証済みユーザー (sellerID) とリクエスト DTO を使って新規オークションを作成します (POST)
+// VIN・車台番号が指定された場合は検証・デコードし、同じ車両の開催中オークションがあれば ErrDuplicateVIN を返します
 func (s *AuctionService) CreateAuction(sellerID uint, req CreateAuctionRequest) (*model.Auction, error) {
-	if req.Title == "" || req.StartPrice <= 0 || req.Maker == "" || req.ModelName == "" {
-		return nil, errors.New("invalid request")
+	now := time.Now()
+	spec, err := decodeVehicle(req.VIN, req.ChassisNumber, now)
+	if err != nil {
+		return nil, err
 	}
 	a := &model.Auction{
-		Title:       req.Title,
-		Description: req.Description,
-		StartPrice:  req.StartPrice,
-		Maker:       req.Maker,
-		ModelName:   req.ModelName,
-		Mileage:     req.Mileage,
-		Year:        req.Year,
-		PhotoURL:    req.PhotoURL,
-		SellerID:    sellerID,
-		CreatedAt:   time.Now(),
-		EndAt:       req.EndAt,
-	}
-	if err := s.repo.Create(a); err != nil {
+		Title:        req.Title,
+		Description:  req.Description,
+		StartPrice:   req.StartPrice,
+		CurrentPrice: req.StartPrice,
+		Maker:        req.Maker,
+		ModelName:    req.ModelName,
+		Mileage:      req.Mileage,
+		Year:         req.Year,
+		PhotoURL:     req.PhotoURL,
+		PhotoKey:     req.PhotoKey,
+		SellerID:     sellerID,
+		CreatedAt:    now,
+		EndAt:        req.EndAt,
+	}
+	if spec != nil {
+		applyVehicleSpec(a, spec)
+	}
+	if a.Title == "" || a.StartPrice <= 0 || a.Maker == "" || a.ModelName == "" {
+		return nil, errors.New("invalid request")
+	}
+	if a.PhotoKey != "" {
+		a.PhotoURL = ""
+	}
+	for _, g := range s.guards {
+		if err := g(sellerID); err != nil {
+			return nil, err
+		}
+	}
+	if spec != nil {
+		created, err := s.repo.CreateVehicle(a, now)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			return nil, ErrDuplicateVIN
+		}
+	} else if err := s.repo.Create(a); err != nil {
 		return nil, err
 	}
+	for _, fn := range s.onCreate {
+		fn(a)
+	}
+	s.resolvePhotos(a)
 	return a, nil
 }
 
@@ -108,6 +193,7 @@ func (s *AuctionService) UpdateAuction(userID, id uint, req UpdateAuctionRequest
 	if err := s.repo.Update(existing); err != nil {
 		return nil, err
 	}
+	s.resolvePhotos(existing)
 	return existing, nil
 }
 
@@ -136,32 +222,198 @@ func (s *AuctionService) GetAuction(id uint) (*model.Auction, error) {
 	if err != nil {
 		return nil, fmt.Errorf("auction %d not found: %w", id, err)
 	}
+	s.resolvePhotos(a)
 	return a, nil
 }
 
-// PaginatedAuctions は page (1 ベース)、size、titleFilter を使って
+// GetAuctionDetail は指定された ID のオークションと、WebSocket で閲覧中の人数を返します
+func (s *AuctionService) GetAuctionDetail(id uint) (*AuctionDetail, error) {
+	a, err := s.GetAuction(id)
+	if err != nil {
+		return nil, err
+	}
+	d := &AuctionDetail{Auction: a}
+	if s.hub != nil {
+		d.Viewers = s.hub.Presence(id)
+	}
+	// 保存時に検証済みのため、ここでのデコード失敗は無視します
+	d.Vehicle, _ = decodeVehicle(a.VIN, a.ChassisNumber, a.CreatedAt)
+	return d, nil
+}
+
+// ErrInvalidFilter は検索条件が不正な場合に返されます
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ErrAuctionNotFound は操作対象のオークションが存在しない場合に返されます
+var ErrAuctionNotFound = errors.New("auction not found")
+
+// auctionByID はオークションを取得します（存在しない場合は ErrAuctionNotFound を返します）
+func auctionByID(auctions *repo.AuctionRepo, id uint) (*model.Auction, error) {
+	a, err := auctions.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: id %d", ErrAuctionNotFound, id)
+	}
+	return a, err
+}
+
+// MaxPageSize は一覧取得で指定できる最大件数です
+const MaxPageSize = 100
+
+// ValidateFilter は検索条件の値域と組み合わせを検証します
+func ValidateFilter(f repo.AuctionFilter) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidFilter, fmt.Sprintf(format, args...))
+	}
+	maxYear := time.Now().Year() + 1
+	for _, y := range []int{f.YearMin, f.YearMax} {
+		if y != 0 && (y < 1900 || y > maxYear) {
+			return invalid("year must be between 1900 and %d", maxYear)
+		}
+	}
+	if f.YearMin > 0 && f.YearMax > 0 && f.YearMin > f.YearMax {
+		return invalid("year_min must not exceed year_max")
+	}
+	if f.MileageMin < 0 || f.MileageMax < 0 || f.PriceMin < 0 || f.PriceMax < 0 {
+		return invalid("ranges must not be negative")
+	}
+	if f.MileageMin > 0 && f.MileageMax > 0 && f.MileageMin > f.MileageMax {
+		return invalid("mileage_min must not exceed mileage_max")
+	}
+	if f.PriceMin > 0 && f.PriceMax > 0 && f.PriceMin > f.PriceMax {
+		return invalid("price_min must not exceed price_max")
+	}
+	switch f.Status {
+	case "", repo.StatusLive, repo.StatusEndingSoon, repo.StatusEnded:
+	default:
+		return invalid("unknown status %q", f.Status)
+	}
+	for _, k := range f.Sort {
+		if _, ok := repo.AuctionSortColumns[strings.TrimPrefix(k, "-")]; !ok {
+			return invalid("unknown sort key %q", k)
+		}
+	}
+	enums := []struct {
+		name            string
+		values, allowed []string
+	}{
+		{"transmission", f.Transmissions, model.Transmissions},
+		{"fuel_type", f.FuelTypes, model.FuelTypes},
+		{"body_type", f.BodyTypes, model.BodyTypes},
+		{"drivetrain", f.Drivetrains, model.Drivetrains},
+		{"colour", f.Colours, model.Colours},
+	}
+	for _, e := range enums {
+		for _, v := range e.values {
+			if !slices.Contains(e.allowed, v) {
+				return invalid("unknown %s %q", e.name, v)
+			}
+		}
+	}
+	if f.DisplacementMin < 0 || f.DisplacementMax < 0 || f.OwnersMax < 0 {
+		return invalid("ranges must not be negative")
+	}
+	if f.DisplacementMin > 0 && f.DisplacementMax > 0 && f.DisplacementMin > f.DisplacementMax {
+		return invalid("displacement_min must not exceed displacement_max")
+	}
+	if _, ok := model.Grades[f.GradeMin]; f.GradeMin != "" && !ok {
+		return invalid("unknown grade_min %q", f.GradeMin)
+	}
+	return nil
+}
+
+// PaginatedAuctions は page (1 ベース)、size、検索条件 f を使って
 // ページングされたオークション一覧と総件数を返します。
 // 戻り値: オークション一覧 ([]model.Auction)、総件数 (int64)、エラー (error)
-func (s *AuctionService) PaginatedAuctions(page, size int, titleFilter string) ([]model.Auction, int64, error) {
+func (s *AuctionService) PaginatedAuctions(page, size int, f repo.AuctionFilter) ([]model.Auction, int64, error) {
 	if page < 1 {
 		page = 1
 	}
 	if size < 1 {
 		size = 10
 	}
+	if size > MaxPageSize {
+		size = MaxPageSize
+	}
+	if err := ValidateFilter(f); err != nil {
+		return nil, 0, err
+	}
 	offset := (page - 1) * size
 
 	// 1) ページングされた一覧を取得
-	auctions, err := s.repo.FindPaginated(offset, size, titleFilter)
+	auctions, err := s.repo.FindPaginated(offset, size, f)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 2) 総件数を取得
-	total, err := s.repo.Count(titleFilter)
+	total, err := s.repo.Count(f)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return auctions, total, nil
+	return s.withPhotoURLs(auctions), total, nil
+}
+
+// AuctionSearchResult は一覧の 1 ページ分と、同じ検索条件でのファセット集計です
+type AuctionSearchResult struct {
+	Items  []model.Auction
+	Total  int64
+	Facets *repo.AuctionFacets
+}
+
+// SearchAuctions は PaginatedAuctions と同じ条件で一覧を取得し、
+// withFacets が true の場合はメーカー・車種・年式・走行距離・状態ごとの件数も合わせて返します
+func (s *AuctionService) SearchAuctions(page, size int, f repo.AuctionFilter, withFacets bool) (*AuctionSearchResult, error) {
+	items, total, err := s.PaginatedAuctions(page, size, f)
+	if err != nil {
+		return nil, err
+	}
+	res := &AuctionSearchResult{Items: items, Total: total}
+	if withFacets {
+		if res.Facets, err = s.repo.Facets(f); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// CursorAuctions はカーソル (不透明トークン) と limit を使って、検索条件に合うオークションを
+// 新しい順にキーセットページングで取得します。cursor が空の場合は先頭ページを返します。
+// キーセットは作成日時順で固定のため f.Sort は指定できません。
+func (s *AuctionService) CursorAuctions(f repo.AuctionFilter, cursor string, limit int) (*repo.CursorPage[model.Auction], error) {
+	if err := ValidateFilter(f); err != nil {
+		return nil, err
+	}
+	if len(f.Sort) > 0 {
+		return nil, fmt.Errorf("%w: sort is not supported with cursor pagination", ErrInvalidFilter)
+	}
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.repo.FindPage(f, c, clampLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	s.withPhotoURLs(page.Items)
+	return page, nil
+}
+
+// decodeCursor は空文字を「カーソル無し」として扱いつつトークンを解読します
+func decodeCursor(token string) (*repo.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return repo.DecodeCursor(token)
+}
+
+// clampLimit はカーソルページングの取得件数を 1〜MaxPageSize に収めます
+func clampLimit(limit int) int {
+	if limit < 1 {
+		return 10
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
 }
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+// Actor は操作を行ったユーザーと接続元 IP です（監査ログに記録します）
+type Actor struct {
+	UserID uint
+	IP     string
+}
+
+// AuditEntry は監査ログ 1 件分の内容です
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   uint
+	Reason     string
+	// Detail は JSON に変換して保存します（nil なら保存しません）
+	Detail any
+}
+
+// 監査ログの対象種別
+const (
+	AuditTargetUser    = "user"
+	AuditTargetAuction = "auction"
+	AuditTargetBid     = "bid"
+)
+
+// AuditService は監査ログの記録と検索を担当します
+type AuditService struct{ repo *repo.AuditRepo }
+
+// NewAuditService はリポジトリを注入して AuditService を生成します
+func NewAuditService(r *repo.AuditRepo) *AuditService { return &AuditService{repo: r} }
+
+// Record は監査ログを記録します
+func (s *AuditService) Record(actor Actor, e AuditEntry) error {
+	return s.record(nil, actor, e)
+}
+
+// record は tx（nil なら通常の接続）で監査ログを記録します
+// 操作と同じトランザクションで記録すれば、ログの無い変更が残ることはありません
+func (s *AuditService) record(tx *gorm.DB, actor Actor, e AuditEntry) error {
+	l := &model.AuditLog{
+		ActorID:    actor.UserID,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Reason:     e.Reason,
+		IP:         actor.IP,
+		CreatedAt:  time.Now(),
+	}
+	if e.Detail != nil {
+		b, err := json.Marshal(e.Detail)
+		if err != nil {
+			return err
+		}
+		l.Detail = string(b)
+	}
+	return s.repo.Create(tx, l)
+}
+
+// List は条件に一致する監査ログを新しい順に取得します
+func (s *AuditService) List(q repo.AuditQuery, limit int) ([]model.AuditLog, error) {
+	return s.repo.Find(q, clampLimit(limit))
+}
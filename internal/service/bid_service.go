@@ -9,13 +9,22 @@ import (
 	"github.com/ksj/car-auction/internal/model"
 	"github.com/ksj/car-auction/internal/repo"
 	"github.com/ksj/car-auction/internal/ws"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// ErrAwaitingInspection は第三者検査で評価点が確定する前のオークションに入札した場合に返されます
+var ErrAwaitingInspection = errors.New("auction is awaiting inspection")
+
+// BidGuard は入札を保存する直前に、オークションの行ロックを保持したトランザクション内で呼ばれる検査です
+// エラーを返すと入札は拒否されます。bid の属性（所属組織など）を設定することもできます
+type BidGuard func(tx *gorm.DB, auc *model.Auction, bid *model.Bid) error
+
 // BidService は入札に関するビジネスロジックを提供します
 type BidService struct {
-	Repo *repo.BidRepo
-	hub  *ws.Hub
+	Repo   *repo.BidRepo
+	hub    *ws.Hub
+	guards []BidGuard
 }
 
 // NewBidService は BidRepo と WebSocket Hub を注入して生成します
@@ -23,6 +32,11 @@ func NewBidService(r *repo.BidRepo, hub *ws.Hub) *BidService {
 	return &BidService{Repo: r, hub: hub}
 }
 
+// AddGuard は入札時の検査を登録します（登録順に実行されます）
+func (s *BidService) AddGuard(g BidGuard) {
+	s.guards = append(s.guards, g)
+}
+
 // PlaceBid はオークションID、ユーザーID、入札額を受け取り、入札処理を行います
 func (s *BidService) PlaceBid(auctionID, userID uint, amount int) (*model.Bid, error) {
 	// 1) トランザクション開始
@@ -42,12 +56,34 @@ func (s *BidService) PlaceBid(auctionID, userID uint, amount int) (*model.Bid, e
 		tx.Rollback()
 		return nil, errors.New("auction already closed")
 	}
+	if auc.InspectionStatus == model.AuctionInspectionPending {
+		tx.Rollback()
+		return nil, ErrAwaitingInspection
+	}
 
 	// 4) 入札額が開始価格以下か確認
-	if amount <= int(auc.StartPrice) {
+	if amount <= auc.StartPrice {
 		tx.Rollback()
 		return nil, errors.New("bid too low")
 	}
+	bid := &model.Bid{
+		AuctionID: auctionID,
+		UserID:    userID,
+		Amount:    amount,
+		CreatedAt: now,
+	}
+	for _, g := range s.guards {
+		if err := g(tx, &auc, bid); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	// 検索用の current_price は最高入札額を保つため、上回った場合だけ更新
+	if err := tx.Model(&model.Auction{}).Where("id = ? AND current_price < ?", auctionID, amount).
+		Update("current_price", amount).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	// 5) 終了5分以内なら終了時間を5分延長
 	if auc.EndAt.Sub(now) <= 5*time.Minute {
@@ -61,12 +97,6 @@ func (s *BidService) PlaceBid(auctionID, userID uint, amount int) (*model.Bid, e
 	}
 
 	// 6) 入札を作成
-	bid := &model.Bid{
-		AuctionID: auctionID,
-		UserID:    userID,
-		Amount:    amount,
-		CreatedAt: now,
-	}
 	if err := tx.Create(bid).Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -79,6 +109,7 @@ func (s *BidService) PlaceBid(auctionID, userID uint, amount int) (*model.Bid, e
 
 	// WebSocket で入札情報をブロードキャスト
 	ev := map[string]interface{}{
+		"type":       "bid",
 		"bid":        bid,
 		"new_end_at": auc.EndAt,
 	}
@@ -114,3 +145,13 @@ func (s *BidService) PaginatedBids(auctionID uint, page, size int) ([]model.Bid,
 	}
 	return bids, total, nil
 }
+
+// CursorBids は指定オークションの入札を新しい順にキーセットページングで取得します
+// 取得の合間に新しい入札が入っても、ページ間で重複・欠落が起きません
+func (s *BidService) CursorBids(auctionID uint, cursor string, limit int) (*repo.CursorPage[model.Bid], error) {
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.FindPageByAuction(auctionID, c, clampLimit(limit))
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidConditionReport は車両状態表の値が不正な場合に返されます
+	ErrInvalidConditionReport = errors.New("invalid condition report")
+	// ErrConditionReportNotFound は車両状態表が未提出の場合に返されます
+	ErrConditionReportNotFound = errors.New("condition report not found")
+	// ErrConditionReportLocked は検査で評価点が確定した後に車両状態表を変更しようとした場合に返されます
+	ErrConditionReportLocked = errors.New("condition report is locked after inspection")
+)
+
+// 車両状態表の数値項目の上限
+const (
+	MaxEngineDisplacement = 10000
+	MaxOwners             = 20
+	MaxPanelDamages       = 100
+	// MaxDamageNoteLength は損傷箇所のメモの最大文字数です（panel_damages.note の列長）
+	MaxDamageNoteLength = 255
+)
+
+// PanelDamageRequest は損傷箇所 1 件の DTO です
+type PanelDamageRequest struct {
+	Panel string `json:"panel"`
+	Code  string `json:"code"`
+	Note  string `json:"note"`
+}
+
+// ConditionReportRequest は車両状態表の提出 DTO です
+type ConditionReportRequest struct {
+	Transmission       string               `json:"transmission"`
+	FuelType           string               `json:"fuel_type"`
+	BodyType           string               `json:"body_type"`
+	Drivetrain         string               `json:"drivetrain"`
+	EngineDisplacement int                  `json:"engine_displacement"`
+	Colour             string               `json:"colour"`
+	Owners             int                  `json:"owners"`
+	AccidentHistory    bool                 `json:"accident_history"`
+	Grade              string               `json:"grade"`
+	Damages            []PanelDamageRequest `json:"damages"`
+}
+
+// validate は列挙値と値域を検証します
+func (req ConditionReportRequest) validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidConditionReport, fmt.Sprintf(format, args...))
+	}
+	enums := []struct {
+		name, value string
+		allowed     []string
+	}{
+		{"transmission", req.Transmission, model.Transmissions},
+		{"fuel_type", req.FuelType, model.FuelTypes},
+		{"body_type", req.BodyType, model.BodyTypes},
+		{"drivetrain", req.Drivetrain, model.Drivetrains},
+		{"colour", req.Colour, model.Colours},
+	}
+	for _, e := range enums {
+		if !slices.Contains(e.allowed, e.value) {
+			return invalid("%s must be one of %v", e.name, e.allowed)
+		}
+	}
+	if req.EngineDisplacement < 0 || req.EngineDisplacement > MaxEngineDisplacement {
+		return invalid("engine_displacement must be between 0 and %d", MaxEngineDisplacement)
+	}
+	if req.Owners < 0 || req.Owners > MaxOwners {
+		return invalid("owners must be between 0 and %d", MaxOwners)
+	}
+	if req.Grade != "" {
+		if _, ok := model.Grades[req.Grade]; !ok {
+			return invalid("unknown grade %q", req.Grade)
+		}
+		// R / RA は修復歴車の評価点
+		if (req.Grade == "R" || req.Grade == "RA") != req.AccidentHistory {
+			return invalid("grade %q contradicts accident_history", req.Grade)
+		}
+	}
+	if len(req.Damages) > MaxPanelDamages {
+		return invalid("at most %d damages", MaxPanelDamages)
+	}
+	for _, d := range req.Damages {
+		if !slices.Contains(model.Panels, d.Panel) {
+			return invalid("unknown panel %q", d.Panel)
+		}
+		if !slices.Contains(model.DamageCodes, d.Code) {
+			return invalid("unknown damage code %q", d.Code)
+		}
+		if utf8.RuneCountInString(d.Note) > MaxDamageNoteLength {
+			return invalid("damage note must be at most %d characters", MaxDamageNoteLength)
+		}
+	}
+	return nil
+}
+
+// ConditionReportService は車両状態表の提出・取得を担当します
+type ConditionReportService struct {
+	repo        *repo.ConditionReportRepo
+	auctions    *repo.AuctionRepo
+	inspections *repo.InspectionRepo
+}
+
+// NewConditionReportService はリポジトリを注入して ConditionReportService を生成します
+func NewConditionReportService(r *repo.ConditionReportRepo, auctions *repo.AuctionRepo, inspections *repo.InspectionRepo) *ConditionReportService {
+	return &ConditionReportService{repo: r, auctions: auctions, inspections: inspections}
+}
+
+// Get は指定オークションの車両状態表を返します
+func (s *ConditionReportService) Get(auctionID uint) (*model.ConditionReport, error) {
+	cr, err := s.repo.FindByAuction(auctionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrConditionReportNotFound
+	}
+	return cr, err
+}
+
+// Submit は車両状態表を検証して保存します（既存の状態表は置き換えます）
+// 検査を依頼していなければ出品者が、検査待ちの間は割り当てられた検査員のみが提出でき、
+// 検査員が評価点を確定した後は変更できません
+func (s *ConditionReportService) Submit(userID, auctionID uint, req ConditionReportRequest) (*model.ConditionReport, error) {
+	a, err := auctionByID(s.auctions, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	switch a.InspectionStatus {
+	case model.AuctionInspectionPassed:
+		return nil, ErrConditionReportLocked
+	case model.AuctionInspectionPending:
+		ok, err := s.inspections.IsAssigned(auctionID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotAssignedInspector
+		}
+	default:
+		if err := checkAuctionEditor(a, userID, s.inspections); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	cr := &model.ConditionReport{
+		AuctionID:          auctionID,
+		Transmission:       req.Transmission,
+		FuelType:           req.FuelType,
+		BodyType:           req.BodyType,
+		Drivetrain:         req.Drivetrain,
+		EngineDisplacement: req.EngineDisplacement,
+		Colour:             req.Colour,
+		Owners:             req.Owners,
+		AccidentHistory:    req.AccidentHistory,
+		Grade:              req.Grade,
+		GradeScore:         model.Grades[req.Grade],
+		SubmittedBy:        userID,
+	}
+	for _, d := range req.Damages {
+		cr.Damages = append(cr.Damages, model.PanelDamage{Panel: d.Panel, Code: d.Code, Note: d.Note})
+	}
+	if err := s.repo.Replace(cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInspectionNotFound は検査が存在しない場合に返されます
+	ErrInspectionNotFound = errors.New("inspection not found")
+	// ErrInspectionConflict は現在の状態では実行できない操作の場合に返されます
+	ErrInspectionConflict = errors.New("inspection state conflict")
+	// ErrInvalidInspection は検査の依頼・確定内容が不正な場合に返されます
+	ErrInvalidInspection = errors.New("invalid inspection")
+	// ErrNotAssignedInspector は検査に割り当てられた検査員以外が操作した場合に返されます
+	ErrNotAssignedInspector = errors.New("not the assigned inspector")
+)
+
+// InspectionRequest は検査依頼の DTO です
+// InspectorID を指定するとその検査員に直接割り当て、省略すると検査員の依頼一覧に公開します
+type InspectionRequest struct {
+	InspectorID *uint `json:"inspector_id"`
+}
+
+// InspectionSignOff は検査員が評価点を確定する DTO です
+type InspectionSignOff struct {
+	Grade string `json:"grade"`
+	Notes string `json:"notes"`
+}
+
+// InspectionService は第三者検査のワークフローを担当します
+type InspectionService struct {
+	repo     *repo.InspectionRepo
+	auctions *repo.AuctionRepo
+	reports  *repo.ConditionReportRepo
+	users    *repo.UserRepo
+	notifier *NotificationService
+}
+
+// NewInspectionService はリポジトリと通知サービスを注入して InspectionService を生成します
+func NewInspectionService(r *repo.InspectionRepo, auctions *repo.AuctionRepo, reports *repo.ConditionReportRepo,
+	users *repo.UserRepo, notifier *NotificationService) *InspectionService {
+	return &InspectionService{repo: r, auctions: auctions, reports: reports, users: users, notifier: notifier}
+}
+
+// Request は出品者が自分のオークションの検査を依頼します
+// 検査中のオークションは評価点が確定するまで入札を受け付けないため、入札済み・終了済みのオークションには依頼できません
+// 出品者が検査員ロールを持っていても、自分を検査員に指定することはできません
+func (s *InspectionService) Request(sellerID, auctionID uint, req InspectionRequest) (*model.Inspection, error) {
+	a, err := auctionByID(s.auctions, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if a.SellerID != sellerID {
+		return nil, ErrNotAuctionOwner
+	}
+	now := time.Now()
+	switch {
+	case !a.EndAt.After(now):
+		return nil, fmt.Errorf("%w: auction already ended", ErrInspectionConflict)
+	case a.CurrentPrice > a.StartPrice:
+		return nil, fmt.Errorf("%w: auction already has bids", ErrInspectionConflict)
+	case a.InspectionStatus == model.AuctionInspectionPassed:
+		return nil, fmt.Errorf("%w: auction already inspected", ErrInspectionConflict)
+	}
+	active, err := s.repo.HasActive(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, fmt.Errorf("%w: an inspection is already in progress", ErrInspectionConflict)
+	}
+
+	in := &model.Inspection{AuctionID: auctionID, SellerID: sellerID, Status: model.InspectionRequested}
+	if req.InspectorID != nil {
+		u, err := s.users.FindByID(*req.InspectorID)
+		if err != nil || !u.HasRole(model.RoleInspector) {
+			return nil, fmt.Errorf("%w: user %d is not an inspector", ErrInvalidInspection, *req.InspectorID)
+		}
+		if u.ID == a.SellerID {
+			return nil, fmt.Errorf("%w: sellers cannot inspect their own auction", ErrInvalidInspection)
+		}
+		in.InspectorID, in.Status, in.AssignedAt = req.InspectorID, model.InspectionAssigned, &now
+	}
+	if err := s.repo.Create(in); err != nil {
+		return nil, err
+	}
+	if in.InspectorID != nil {
+		s.notify(*in.InspectorID, in, "inspection_assigned", "検査が割り当てられました",
+			fmt.Sprintf("オークション「%s」の検査が割り当てられました。", a.Title))
+	}
+	return in, nil
+}
+
+// ListByAuction はオークションの検査履歴を返します（出品者と割り当てられた検査員のみ）
+func (s *InspectionService) ListByAuction(userID, auctionID uint) ([]model.Inspection, error) {
+	a, err := auctionByID(s.auctions, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.FindByAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if a.SellerID == userID {
+		return list, nil
+	}
+	for _, in := range list {
+		if in.InspectorID != nil && *in.InspectorID == userID {
+			return list, nil
+		}
+	}
+	return nil, ErrNotAuctionOwner
+}
+
+// Queue は検査員が引き受け可能な依頼と、自分に割り当てられた検査を返します
+func (s *InspectionService) Queue(inspectorID uint) ([]model.Inspection, error) {
+	return s.repo.FindQueue(inspectorID)
+}
+
+// Claim は検査員が未割り当ての依頼を引き受けます（自分が出品したオークションの依頼は引き受けられません）
+func (s *InspectionService) Claim(inspectorID, id uint) (*model.Inspection, error) {
+	in, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.SellerID == inspectorID {
+		return nil, fmt.Errorf("%w: sellers cannot inspect their own auction", ErrInvalidInspection)
+	}
+	now := time.Now()
+	ok, err := s.repo.Transition(id, []string{model.InspectionRequested}, map[string]any{
+		"status": model.InspectionAssigned, "inspector_id": inspectorID, "assigned_at": now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: inspection is %s", ErrInspectionConflict, in.Status)
+	}
+	in.Status, in.InspectorID, in.AssignedAt = model.InspectionAssigned, &inspectorID, &now
+	s.notify(in.SellerID, in, "inspection_assigned", "検査員が決まりました",
+		"依頼した検査を検査員が引き受けました。")
+	return in, nil
+}
+
+// Decline は割り当てられた検査員が検査を辞退し、依頼を未割り当てに戻します
+func (s *InspectionService) Decline(inspectorID, id uint) (*model.Inspection, error) {
+	in, err := s.assigned(inspectorID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Transition(id, []string{model.InspectionAssigned}, map[string]any{
+		"status": model.InspectionRequested, "inspector_id": nil, "assigned_at": nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: inspection is no longer assigned", ErrInspectionConflict)
+	}
+	in.Status, in.InspectorID, in.AssignedAt = model.InspectionRequested, nil, nil
+	s.notify(in.SellerID, in, "inspection_declined", "検査員が検査を辞退しました",
+		"依頼した検査は再び検査員の依頼一覧に公開されました。")
+	return in, nil
+}
+
+// Complete は割り当てられた検査員が評価点を確定します
+// 事前に車両状態表が提出されている必要があり、確定後はオークションの入札が可能になります
+func (s *InspectionService) Complete(inspectorID, id uint, req InspectionSignOff) (*model.Inspection, error) {
+	in, err := s.assigned(inspectorID, id)
+	if err != nil {
+		return nil, err
+	}
+	score, ok := model.Grades[req.Grade]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown grade %q", ErrInvalidInspection, req.Grade)
+	}
+	report, err := s.reports.FindByAuction(in.AuctionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: submit the condition report before signing off", ErrInspectionConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (req.Grade == "R" || req.Grade == "RA") != report.AccidentHistory {
+		return nil, fmt.Errorf("%w: grade %q contradicts accident_history", ErrInvalidInspection, req.Grade)
+	}
+	now := time.Now()
+	ok, err = s.repo.Complete(in, req.Grade, score, req.Notes, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: inspection is no longer assigned", ErrInspectionConflict)
+	}
+	in.Status, in.Grade, in.Notes, in.CompletedAt = model.InspectionCompleted, req.Grade, req.Notes, &now
+	s.notify(in.SellerID, in, "inspection_completed", "検査が完了しました",
+		fmt.Sprintf("評価点 %s で検査が完了し、オークションへの入札が可能になりました。", req.Grade))
+	return in, nil
+}
+
+// Cancel は出品者が進行中の検査を取り消します。オークションは検査なしで入札可能に戻ります
+func (s *InspectionService) Cancel(sellerID, id uint) (*model.Inspection, error) {
+	in, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.SellerID != sellerID {
+		return nil, ErrNotAuctionOwner
+	}
+	ok, err := s.repo.Cancel(in)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: inspection is %s", ErrInspectionConflict, in.Status)
+	}
+	in.Status = model.InspectionCancelled
+	if in.InspectorID != nil {
+		s.notify(*in.InspectorID, in, "inspection_cancelled", "検査が取り消されました",
+			"出品者が検査の依頼を取り消しました。")
+	}
+	return in, nil
+}
+
+func (s *InspectionService) find(id uint) (*model.Inspection, error) {
+	in, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInspectionNotFound
+	}
+	return in, err
+}
+
+// assigned は検査を取得し、inspectorID が割り当てられた検査員であることを確認します
+func (s *InspectionService) assigned(inspectorID, id uint) (*model.Inspection, error) {
+	in, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.InspectorID == nil || *in.InspectorID != inspectorID {
+		return nil, ErrNotAssignedInspector
+	}
+	return in, nil
+}
+
+// notify は検査の状態変化をアプリ内通知とメールで知らせます（失敗はワークフローを止めません）
+func (s *InspectionService) notify(userID uint, in *model.Inspection, kind, title, body string) {
+	if s.notifier == nil {
+		return
+	}
+	aid := in.AuctionID
+	_ = s.notifier.Notify(&model.Notification{
+		UserID: userID, Kind: kind, Title: title, Body: body, AuctionID: &aid,
+	}, true)
+}
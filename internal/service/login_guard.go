@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+)
+
+// ErrLoginThrottled は連続したログイン失敗により、ログインを一時的に受け付けない場合に返されます
+// 実際には再試行までの時間を含む *LoginThrottledError が返されます
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// LoginThrottledError はログインの受付停止と、再試行できるまでの時間です
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	// Locked は失敗回数の上限に達した一時ロックであることを示します（false なら段階的な待ち時間）
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("login temporarily locked, retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%s, retry after %s", ErrLoginThrottled, e.RetryAfter.Round(time.Second))
+}
+
+// Is により errors.Is(err, ErrLoginThrottled) で判定できます
+func (e *LoginThrottledError) Is(target error) bool { return target == ErrLoginThrottled }
+
+// ログイン試行の監査ログの操作名
+const (
+	AuditLoginSucceeded = "auth.login_succeeded"
+	AuditLoginFailed    = "auth.login_failed"
+	AuditLoginBlocked   = "auth.login_blocked"
+	AuditUserUnlock     = "user.unlock"
+)
+
+// AuditTargetLogin はログイン試行の監査ログの対象種別です（対象 ID はユーザー ID, 存在しないアカウントなら 0）
+const AuditTargetLogin = "login"
+
+// 段階的な待ち時間: 無料の失敗回数を超えると、失敗ごとに loginBaseDelay から倍にしていきます（loginMaxDelay まで）
+const (
+	loginBaseDelay = time.Second
+	loginMaxDelay  = time.Minute
+)
+
+// LoginAttemptStore はアカウント・接続元 IP ごとのログイン失敗の状況の保存先です
+// サーバー 1 台なら NewMemoryLoginAttemptStore、複数台で共有する場合は repo.LoginAttemptRepo を使います
+type LoginAttemptStore interface {
+	// Get は subject の状況を返します（記録が無ければ nil）
+	Get(subject string) (*model.LoginAttempt, error)
+	// Fail は失敗を 1 回記録し、更新後の状況を返します
+	// 最後の失敗から window 以上経っていれば 1 回目として数え直し、block で受付停止の期限とロックかどうかを求めます
+	Fail(subject string, now time.Time, window time.Duration, block func(failures int) (time.Time, bool)) (*model.LoginAttempt, error)
+	// Reset は subject の記録を削除します
+	Reset(subject string) error
+}
+
+var _ LoginAttemptStore = (*repo.LoginAttemptRepo)(nil)
+
+// LoginPolicy はログイン失敗に対する待ち時間とロックの設定です
+type LoginPolicy struct {
+	// FreeAttempts 回までの連続失敗は待ち時間なしで再試行できます
+	FreeAttempts int
+	// AccountLockThreshold 回連続で失敗したアカウントは LockoutDuration の間ロックします（0 はロックしない）
+	AccountLockThreshold int
+	// IPLockThreshold 回連続で失敗した接続元 IP は LockoutDuration の間ロックします（0 はロックしない）
+	// 共有 IP の利用者を巻き込まないよう、接続元 IP には段階的な待ち時間をかけません
+	IPLockThreshold int
+	// LockoutDuration はロックの期間で、最後の失敗からこの時間が経つと失敗回数も数え直します
+	LockoutDuration time.Duration
+}
+
+// LoginGuard はログインの総当たり攻撃を防ぐため、アカウント・接続元 IP ごとに失敗を数え、
+// 段階的な待ち時間と一時的なロックをかけます。ログインの試行はすべて監査ログに記録します
+type LoginGuard struct {
+	store  LoginAttemptStore
+	policy LoginPolicy
+	audit  *AuditService
+}
+
+// NewLoginGuard は保存先と設定を注入して LoginGuard を生成します（audit が nil なら監査ログを記録しません）
+func NewLoginGuard(store LoginAttemptStore, policy LoginPolicy, audit *AuditService) *LoginGuard {
+	return &LoginGuard{store: store, policy: policy, audit: audit}
+}
+
+// accountSubject はアカウントの識別子です
+// 存在しないメールアドレスも同じように数え、応答の違いから登録の有無がわからないようにします
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string { return "ip:" + ip }
+
+// subjects はアカウントと接続元 IP（空なら除く）の識別子を返します
+func (g *LoginGuard) subjects(email, ip string) []string {
+	s := []string{accountSubject(email)}
+	if ip != "" {
+		s = append(s, ipSubject(ip))
+	}
+	return s
+}
+
+// Check はアカウントと接続元 IP がログインを受け付けられる状態かを確認します
+// 受付停止中なら監査ログに記録し、再試行までの時間が長い方の *LoginThrottledError を返します
+func (g *LoginGuard) Check(email, ip string) error {
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for _, subject := range g.subjects(email, ip) {
+		a, err := g.store.Get(subject)
+		if err != nil {
+			return err
+		}
+		if a == nil || !now.Before(a.BlockedUntil) {
+			continue
+		}
+		if wait := a.BlockedUntil.Sub(now); throttled == nil || wait > throttled.RetryAfter {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: a.Locked}
+		}
+	}
+	if throttled == nil {
+		return nil
+	}
+	if err := g.record(0, ip, AuditLoginBlocked, email, throttled.Error()); err != nil {
+		return err
+	}
+	return throttled
+}
+
+// Failed はログインの失敗を記録し、必要に応じて待ち時間・ロックをかけます
+// userID は存在しないアカウントなら 0、reason は "password" や "mfa" のような失敗した段階です
+func (g *LoginGuard) Failed(userID uint, email, ip, reason string) error {
+	now := time.Now()
+	if _, err := g.store.Fail(accountSubject(email), now, g.policy.LockoutDuration, g.accountBlock(now)); err != nil {
+		return err
+	}
+	if ip != "" {
+		if _, err := g.store.Fail(ipSubject(ip), now, g.policy.LockoutDuration, g.ipBlock(now)); err != nil {
+			return err
+		}
+	}
+	return g.record(userID, ip, AuditLoginFailed, email, reason)
+}
+
+// Succeeded はログインの成功を記録し、アカウントの失敗回数を数え直します
+// 接続元 IP の失敗回数は、同じ IP から別アカウントへの試行を続けられないよう期限切れまで残します
+func (g *LoginGuard) Succeeded(u *model.User, ip string) error {
+	if err := g.store.Reset(accountSubject(u.Email)); err != nil {
+		return err
+	}
+	return g.record(u.ID, ip, AuditLoginSucceeded, u.Email, "")
+}
+
+// Unlock はアカウントのロックと待ち時間を解除します
+func (g *LoginGuard) Unlock(email string) error {
+	return g.store.Reset(accountSubject(email))
+}
+
+// accountBlock はアカウントの失敗回数から、段階的な待ち時間またはロックの期限を求めます
+// 待ち時間が無い場合は now を返します（DB に日時のゼロ値を保存しないため）
+func (g *LoginGuard) accountBlock(now time.Time) func(int) (time.Time, bool) {
+	return func(failures int) (time.Time, bool) {
+		if g.policy.AccountLockThreshold > 0 && failures >= g.policy.AccountLockThreshold {
+			return now.Add(g.policy.LockoutDuration), true
+		}
+		if failures <= g.policy.FreeAttempts {
+			return now, false
+		}
+		delay := loginMaxDelay
+		if n := failures - g.policy.FreeAttempts - 1; n < 16 {
+			delay = min(loginBaseDelay<<n, loginMaxDelay)
+		}
+		return now.Add(delay), false
+	}
+}
+
+// ipBlock は接続元 IP の失敗回数からロックの期限を求めます
+func (g *LoginGuard) ipBlock(now time.Time) func(int) (time.Time, bool) {
+	return func(failures int) (time.Time, bool) {
+		if g.policy.IPLockThreshold > 0 && failures >= g.policy.IPLockThreshold {
+			return now.Add(g.policy.LockoutDuration), true
+		}
+		return now, false
+	}
+}
+
+// record はログイン試行を監査ログに記録します
+// 管理操作の検索 (actor_id) と混ざらないよう、成功した場合も操作者は記録しません
+func (g *LoginGuard) record(userID uint, ip, action, email, reason string) error {
+	if g.audit == nil {
+		return nil
+	}
+	return g.audit.Record(Actor{IP: ip}, AuditEntry{
+		Action: action, TargetType: AuditTargetLogin, TargetID: userID, Reason: reason,
+		Detail: map[string]string{"email": email},
+	})
+}
+
+// memoryStorePurgeSize を超える数の識別子を記録したら、期限切れの記録を削除します
+const memoryStorePurgeSize = 10000
+
+// MemoryLoginAttemptStore はプロセス内のメモリにログイン失敗の状況を保存します（再起動で消えます）
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+}
+
+// NewMemoryLoginAttemptStore は空の MemoryLoginAttemptStore を生成します
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: map[string]model.LoginAttempt{}}
+}
+
+// Get は subject の状況を返します（記録が無ければ nil）
+func (m *MemoryLoginAttemptStore) Get(subject string) (*model.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.attempts[subject]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// Fail は失敗を 1 回記録し、更新後の状況を返します
+func (m *MemoryLoginAttemptStore) Fail(subject string, now time.Time, window time.Duration,
+	block func(failures int) (time.Time, bool)) (*model.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.attempts) >= memoryStorePurgeSize {
+		for k, a := range m.attempts {
+			if now.Sub(a.LastFailure) >= window && !now.Before(a.BlockedUntil) {
+				delete(m.attempts, k)
+			}
+		}
+	}
+	a, ok := m.attempts[subject]
+	if !ok || now.Sub(a.LastFailure) >= window {
+		a = model.LoginAttempt{Subject: subject}
+	}
+	a.Failures++
+	a.LastFailure = now
+	a.BlockedUntil, a.Locked = block(a.Failures)
+	a.UpdatedAt = now
+	m.attempts[subject] = a
+	return &a, nil
+}
+
+// Reset は subject の記録を削除します
+func (m *MemoryLoginAttemptStore) Reset(subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.attempts, subject)
+	return nil
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/totp"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMFARequired はポリシーにより二要素認証の有効化が必要な操作（高額入札・出品）の場合に返されます
+	ErrMFARequired = errors.New("two-factor authentication is required for this action")
+	// ErrMFAAlreadyEnabled は二要素認証が既に有効な場合に返されます
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	// ErrMFANotEnrolled は二要素認証の登録手続きをしていない、または有効になっていない場合に返されます
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrInvalidMFACode はワンタイムコード・リカバリーコードが一致しない、または使用済みの場合に返されます
+	ErrInvalidMFACode = errors.New("invalid authentication code")
+)
+
+const (
+	// recoveryCodeCount は一度に発行するリカバリーコードの数です
+	recoveryCodeCount = 10
+	// totpSkew は時計のずれを許容する前後の時間ステップ数です
+	totpSkew = 1
+)
+
+// MFAPolicy は二要素認証を必須とする操作の設定です
+type MFAPolicy struct {
+	// BidThreshold 以上の入札には二要素認証の有効化が必要です（0 は制限なし）
+	BidThreshold int
+	// RequireForSelling が true の場合、出品には二要素認証の有効化が必要です
+	RequireForSelling bool
+}
+
+// Enrollment は二要素認証の登録手続きで認証アプリに登録する情報です
+type Enrollment struct {
+	Secret string `json:"secret"`
+	// URI は otpauth:// 形式で、QR コードにして認証アプリで読み取ります
+	URI string `json:"uri"`
+}
+
+// MFAStatus は二要素認証の設定状況です
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFAService は TOTP による二要素認証の登録・検証と、二要素認証を必須とするポリシーを担当します
+type MFAService struct {
+	users  *repo.UserRepo
+	codes  *repo.RecoveryCodeRepo
+	issuer string
+	policy MFAPolicy
+}
+
+// NewMFAService はリポジトリを注入して MFAService を生成します
+// issuer は認証アプリに表示する発行者名です
+func NewMFAService(users *repo.UserRepo, codes *repo.RecoveryCodeRepo, issuer string, policy MFAPolicy) *MFAService {
+	return &MFAService{users: users, codes: codes, issuer: issuer, policy: policy}
+}
+
+// Status は二要素認証の設定状況を返します
+func (s *MFAService) Status(userID uint) (*MFAStatus, error) {
+	u, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	st := &MFAStatus{Enabled: u.MFAEnabled(), EnabledAt: u.TOTPEnabledAt}
+	if st.Enabled {
+		if st.RecoveryCodesRemaining, err = s.codes.Remaining(u.ID); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// Enroll は新しい共有鍵を発行して登録手続きを始めます（Activate でコードを確認するまで有効になりません）
+func (s *MFAService) Enroll(userID uint) (*Enrollment, error) {
+	u, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetTOTP(nil, u.ID, secret, nil, 0); err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, URI: totp.URI(s.issuer, u.Email, secret)}, nil
+}
+
+// Activate は認証アプリのコードを確認して二要素認証を有効にし、リカバリーコードを返します
+// リカバリーコードはこの時だけ返すため、ユーザーに保管してもらいます
+func (s *MFAService) Activate(userID uint, code string) ([]string, error) {
+	u, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	now := time.Now()
+	step, ok := totp.Validate(u.TOTPSecret, code, now, totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	var codes []string
+	err = s.users.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.users.SetTOTP(tx, u.ID, u.TOTPSecret, &now, step); err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, u.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable はワンタイムコードまたはリカバリーコードを確認して二要素認証を無効にします
+func (s *MFAService) Disable(userID uint, code string) error {
+	u, err := s.enabledUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Verify(u, code); err != nil {
+		return err
+	}
+	return s.users.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.users.SetTOTP(tx, u.ID, "", nil, 0); err != nil {
+			return err
+		}
+		return s.codes.Replace(tx, u.ID, nil)
+	})
+}
+
+// RegenerateRecoveryCodes はワンタイムコードを確認してリカバリーコードを再発行します（以前のコードは無効になります）
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	u, err := s.enabledUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTP(u, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(nil, u.ID)
+}
+
+// Verify はワンタイムコードまたはリカバリーコードを検証します（UserService.UseMFA に登録し、ログインの 2 段階目で使います）
+// 受け付けたコードは再利用できません
+func (s *MFAService) Verify(u *model.User, code string) error {
+	if !u.MFAEnabled() {
+		return ErrMFANotEnrolled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(u, code)
+	}
+	ok, err := s.codes.Use(u.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// GuardBid は BidService に登録する入札時の検査で、基準額以上の入札に二要素認証の有効化を求めます
+func (s *MFAService) GuardBid(tx *gorm.DB, _ *model.Auction, bid *model.Bid) error {
+	if s.policy.BidThreshold <= 0 || bid.Amount < s.policy.BidThreshold {
+		return nil
+	}
+	return s.requireEnabled(tx, bid.UserID)
+}
+
+// GuardCreateAuction は AuctionService に登録する出品時の検査で、ポリシーにより出品者に二要素認証の有効化を求めます
+func (s *MFAService) GuardCreateAuction(sellerID uint) error {
+	if !s.policy.RequireForSelling {
+		return nil
+	}
+	return s.requireEnabled(s.users.DB, sellerID)
+}
+
+// requireEnabled はユーザーの二要素認証が有効でなければ ErrMFARequired を返します
+func (s *MFAService) requireEnabled(tx *gorm.DB, userID uint) error {
+	u, err := s.users.Get(tx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled() {
+		return ErrMFARequired
+	}
+	return nil
+}
+
+// verifyTOTP はワンタイムコードを検証し、同じコードの再利用を拒否します
+func (s *MFAService) verifyTOTP(u *model.User, code string) error {
+	step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	accepted, err := s.users.AcceptTOTPStep(u.ID, step)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes は新しいリカバリーコードを発行してハッシュを保存し、コード本体を返します
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	now := time.Now()
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b, err := randomToken(10)
+		if err != nil {
+			return nil, err
+		}
+		// 16 文字を 4 文字ずつ区切った xxxx-xxxx-xxxx-xxxx 形式（80 ビット）
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = fmt.Sprintf("%s-%s-%s-%s", raw[0:4], raw[4:8], raw[8:12], raw[12:16])
+		rows[i] = model.RecoveryCode{UserID: userID, CodeHash: hashToken(raw), CreatedAt: now}
+	}
+	if err := s.codes.Replace(tx, userID, rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode は入力されたリカバリーコードから区切り・空白を除き小文字にします
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// user はユーザーを取得します（存在しなければ ErrAccountNotFound）
+func (s *MFAService) user(userID uint) (*model.User, error) {
+	u, err := s.users.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	return u, err
+}
+
+// enabledUser は二要素認証が有効なユーザーを取得します
+func (s *MFAService) enabledUser(userID uint) (*model.User, error) {
+	u, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.MFAEnabled() {
+		return nil, ErrMFANotEnrolled
+	}
+	return u, nil
+}
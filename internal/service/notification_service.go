@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ksj/car-auction/internal/mail"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+)
+
+// ErrNotificationNotFound は通知が存在しないか、既に既読の場合に返されます
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationService はアプリ内通知とメール通知を提供します
+type NotificationService struct {
+	repo   *repo.NotificationRepo
+	users  *repo.UserRepo
+	mailer mail.Sender
+}
+
+// NewNotificationService はリポジトリとメール送信者を注入して NotificationService を生成します
+func NewNotificationService(r *repo.NotificationRepo, users *repo.UserRepo, mailer mail.Sender) *NotificationService {
+	return &NotificationService{repo: r, users: users, mailer: mailer}
+}
+
+// Notify はアプリ内通知を作成し、email が true の場合はユーザーのメールアドレスにも送信します
+// メール送信の失敗はログに記録するのみで、アプリ内通知の作成は取り消しません
+func (s *NotificationService) Notify(n *model.Notification, email bool) error {
+	n.CreatedAt = time.Now()
+	if err := s.repo.Create(n); err != nil {
+		return err
+	}
+	if !email || s.mailer == nil {
+		return nil
+	}
+	u, err := s.users.FindByID(n.UserID)
+	if err != nil {
+		log.Printf("NOTIFY: user %d not found for email: %v", n.UserID, err)
+		return nil
+	}
+	if err := s.mailer.Send(u.Email, n.Title, n.Body); err != nil {
+		log.Printf("NOTIFY: email to user %d failed: %v", n.UserID, err)
+	}
+	return nil
+}
+
+// List は指定ユーザーの通知を新しい順に最大 limit 件返します
+func (s *NotificationService) List(userID uint, unreadOnly bool, limit int) ([]model.Notification, error) {
+	return s.repo.FindByUser(userID, unreadOnly, clampLimit(limit))
+}
+
+// MarkRead は指定ユーザーの通知を既読にします
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	n, err := s.repo.MarkRead(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/oidc"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/ksj/car-auction/internal/repo"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidOIDCState は IdP からのコールバックの state がログイン開始時のものと一致しない・期限切れの場合に返されます
+	ErrInvalidOIDCState = errors.New("invalid or expired oidc login state")
+	// ErrOIDCNotProvisioned は IdP のアカウントに対応するユーザーが無く、自動作成も行わない場合に返されます
+	ErrOIDCNotProvisioned = errors.New("no local account for this identity")
+)
+
+const (
+	// oidcStateTTL はログイン開始から IdP のコールバックまでの有効期限です
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateType はログイン開始時に発行する state トークンの typ クレームです（AuthMiddleware では受け付けません）
+	oidcStateType = "oidc_state"
+)
+
+// 外部 IdP のアカウントの監査ログの操作種別
+const (
+	AuditIdentityLinked      = "auth.identity_linked"
+	AuditIdentityProvisioned = "auth.identity_provisioned"
+)
+
+// OIDCPolicy は IdP のクレームをローカルのユーザー・ロールに対応付ける方法です
+type OIDCPolicy struct {
+	// RoleClaim はロールの対応付けに使うクレーム名です（"groups" など, 空なら対応付けません）
+	RoleClaim string
+	// RoleMap は RoleClaim の値からロールへの対応です
+	// 対応するロールが 1 つ以上あれば、ログインのたびにユーザーのロールをその組に置き換えます
+	RoleMap map[string]string
+	// AutoProvision が true の場合、対応するユーザーが無ければ作成します
+	AutoProvision bool
+	// DefaultRole は自動作成したユーザーのロールに対応するものが無い場合に付与するロールです
+	DefaultRole string
+}
+
+// OIDCLogin はログイン開始時に利用者をリダイレクトする IdP の URL と、
+// コールバックの照合に使う state トークンです（HttpOnly の Cookie で保持します）
+type OIDCLogin struct {
+	AuthURL    string
+	StateToken string
+}
+
+// OIDCService は外部の IdP (OpenID Connect) によるログインを担当します
+// IdP で認証した利用者にも、パスワードログインと同じアクセストークン・リフレッシュトークンを発行します
+type OIDCService struct {
+	client     *oidc.Client
+	identities *repo.UserIdentityRepo
+	users      *UserService
+	audit      *AuditService
+	policy     OIDCPolicy
+}
+
+// NewOIDCService は IdP のクライアントとリポジトリを注入して OIDCService を生成します
+func NewOIDCService(client *oidc.Client, identities *repo.UserIdentityRepo, users *UserService, audit *AuditService, policy OIDCPolicy) *OIDCService {
+	return &OIDCService{client: client, identities: identities, users: users, audit: audit, policy: policy}
+}
+
+// ParseOIDCRoleMap は "engineering=seller,buyers=bidder" 形式の対応表を読み取ります
+func ParseOIDCRoleMap(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || !rbac.ValidRole(role) {
+			return nil, fmt.Errorf("invalid oidc role mapping %q", pair)
+		}
+		m[group] = role
+	}
+	return m, nil
+}
+
+// Begin はログインを開始します
+// state・nonce・PKCE の code_verifier を生成し、署名した state トークンに入れて返します
+func (s *OIDCService) Begin(ctx context.Context) (*OIDCLogin, error) {
+	var vals [3]string
+	for i := range vals {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	state, nonce, verifier := vals[0], vals[1], vals[2]
+	authURL, err := s.client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := signingKeys.Sign(jwt.MapClaims{
+		"typ":      oidcStateType,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLogin{AuthURL: authURL, StateToken: tok}, nil
+}
+
+// Complete は IdP からのコールバックを処理します
+// state を照合して認可コードを交換し、ID トークンのクレームからユーザーを特定（または作成）してログインさせます
+func (s *OIDCService) Complete(ctx context.Context, stateToken, state, code, ip string) (*LoginResult, error) {
+	nonce, verifier, err := parseOIDCState(stateToken, state)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, fmt.Errorf("%w: missing code", ErrInvalidOIDCState)
+	}
+	id, err := s.client.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.resolveUser(id, ip)
+	if err != nil {
+		return nil, err
+	}
+	return s.users.LoginExternal(u, ip)
+}
+
+// parseOIDCState は state トークンを検証し、コールバックの state と一致すれば nonce と code_verifier を返します
+func parseOIDCState(raw, state string) (string, string, error) {
+	claims, err := signingKeys.Parse(raw)
+	if err != nil || claims["typ"] != oidcStateType {
+		return "", "", ErrInvalidOIDCState
+	}
+	want, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+	if want == "" || want != state || nonce == "" || verifier == "" {
+		return "", "", ErrInvalidOIDCState
+	}
+	return nonce, verifier, nil
+}
+
+// resolveUser は IdP のアカウントに対応するユーザーを返します
+//  1. 紐付け済みならそのユーザー
+//  2. IdP が確認済みとするメールアドレスのユーザーがいれば紐付ける
+//  3. AutoProvision が true なら作成して紐付ける
+//
+// RoleMap に対応するロールがあれば、ユーザーのロールを IdP 側の所属に合わせて置き換えます
+func (s *OIDCService) resolveUser(id *oidc.IDToken, ip string) (*model.User, error) {
+	issuer := s.client.Issuer()
+	roles := s.mappedRoles(id)
+	link, err := s.identities.Find(issuer, id.Subject)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var u *model.User
+	switch {
+	case link != nil:
+		if err := s.identities.Touch(link.ID, id.Email, now); err != nil {
+			return nil, err
+		}
+		if u, err = s.users.Repo.FindByID(link.UserID); err != nil {
+			return nil, err
+		}
+	case id.Email == "" || !id.EmailVerified:
+		// 未確認のメールアドレスで既存のユーザーに紐付けると、IdP 側でアドレスを詐称した乗っ取りを許してしまいます
+		return nil, fmt.Errorf("%w: the identity provider did not return a verified email", ErrOIDCNotProvisioned)
+	default:
+		identity := &model.UserIdentity{Issuer: issuer, Subject: id.Subject, Email: id.Email, LastLoginAt: &now, CreatedAt: now}
+		u, err = s.users.Repo.FindByEmail(id.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		action := AuditIdentityLinked
+		if u != nil {
+			identity.UserID = u.ID
+			if err := s.identities.Create(nil, identity); err != nil {
+				return nil, err
+			}
+		} else {
+			if !s.policy.AutoProvision {
+				return nil, ErrOIDCNotProvisioned
+			}
+			if u, err = s.provision(id, roles, identity, now); err != nil {
+				return nil, err
+			}
+			action = AuditIdentityProvisioned
+		}
+		if err := s.record(u, ip, action, issuer); err != nil {
+			return nil, err
+		}
+	}
+	if len(roles) > 0 && !sameRoles(u.RoleNames(), roles) {
+		if err := s.users.Repo.SetRoles(nil, u.ID, roles); err != nil {
+			return nil, err
+		}
+		if u, err = s.users.Repo.FindByID(u.ID); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// provision は IdP のアカウントに対応するユーザーを作成します
+// パスワードは推測できない乱数とし（パスワード再設定で設定できます）、メールアドレスは確認済みとします
+func (s *OIDCService) provision(id *oidc.IDToken, roles []string, identity *model.UserIdentity, now time.Time) (*model.User, error) {
+	if len(roles) == 0 {
+		roles = []string{s.policy.DefaultRole}
+	}
+	raw, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(raw)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	u := &model.User{
+		Email:           id.Email,
+		Password:        string(hash),
+		Role:            roles[0],
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+	}
+	for _, r := range roles {
+		u.Roles = append(u.Roles, model.UserRole{Role: r, CreatedAt: now})
+	}
+	if err := s.identities.CreateWithUser(u, identity); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// mappedRoles は RoleClaim の値を RoleMap でロールに変換します（重複を除き、クレームの順）
+func (s *OIDCService) mappedRoles(id *oidc.IDToken) []string {
+	if s.policy.RoleClaim == "" {
+		return nil
+	}
+	var roles []string
+	for _, v := range id.Strings(s.policy.RoleClaim) {
+		if r, ok := s.policy.RoleMap[v]; ok && !slices.Contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// sameRoles はロールの組が等しいかを返します（順序は問いません）
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, r := range a {
+		if !slices.Contains(b, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// record は IdP のアカウントの紐付け・作成を監査ログに記録します
+func (s *OIDCService) record(u *model.User, ip, action, issuer string) error {
+	if s.audit == nil {
+		return nil
+	}
+	return s.audit.Record(Actor{UserID: u.ID, IP: ip}, AuditEntry{
+		Action: action, TargetType: AuditTargetUser, TargetID: u.ID, Detail: map[string]string{"issuer": issuer},
+	})
+}
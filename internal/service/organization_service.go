@@ -0,0 +1,318 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrgNotFound は組織が存在しない、またはユーザーがどの組織にも所属していない場合に返されます
+	ErrOrgNotFound = errors.New("organization not found")
+	// ErrOrgForbidden は組織内ロールが操作に足りない場合（オーナー以外のメンバー管理、閲覧者の入札など）に返されます
+	ErrOrgForbidden = errors.New("forbidden: insufficient organization role")
+	// ErrOrgConflict は既に所属している、最後のオーナーを外す、同じ組織のメンバー同士で競り合うなどの場合に返されます
+	ErrOrgConflict = errors.New("organization conflict")
+	// ErrInvalidOrg は組織名・ロール・与信枠の指定が不正な場合に返されます
+	ErrInvalidOrg = errors.New("invalid organization request")
+	// ErrCreditLimitExceeded は入札により組織の与信枠を超える場合に返されます
+	ErrCreditLimitExceeded = errors.New("organization credit limit exceeded")
+)
+
+// AuditOrgCreditLimit は与信枠変更の監査ログの操作名です
+const AuditOrgCreditLimit = "org.credit_limit"
+
+// AuditTargetOrg は監査ログの対象種別（組織）です
+const AuditTargetOrg = "organization"
+
+// OrgDetail は組織の詳細と与信の利用状況です
+type OrgDetail struct {
+	*model.Organization
+	// Exposure は開催中のオークションで組織が最高入札となっている金額の合計です
+	Exposure int `json:"exposure"`
+	// AvailableCredit は残りの与信枠です（与信枠が無制限なら nil）
+	AvailableCredit *int `json:"available_credit,omitempty"`
+}
+
+// OrganizationService は販売店などの組織とメンバー、組織としての入札・出品を担当します
+type OrganizationService struct {
+	repo          *repo.OrganizationRepo
+	users         *repo.UserRepo
+	audit         *AuditService
+	defaultCredit int
+}
+
+// NewOrganizationService はリポジトリと監査ログを注入して OrganizationService を生成します
+// defaultCredit は新しい組織の与信枠です（0 は無制限）
+func NewOrganizationService(r *repo.OrganizationRepo, users *repo.UserRepo, audit *AuditService, defaultCredit int) *OrganizationService {
+	return &OrganizationService{repo: r, users: users, audit: audit, defaultCredit: defaultCredit}
+}
+
+// Create は組織を作成し、作成者をオーナーとして登録します
+func (s *OrganizationService) Create(userID uint, name string) (*OrgDetail, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 255 {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidOrg)
+	}
+	m, err := s.repo.Membership(nil, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		return nil, fmt.Errorf("%w: already a member of an organization", ErrOrgConflict)
+	}
+	taken, err := s.repo.NameTaken(name)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, fmt.Errorf("%w: organization name is taken", ErrOrgConflict)
+	}
+	org := &model.Organization{Name: name, CreditLimit: s.defaultCredit}
+	owner := &model.OrgMember{UserID: userID, Role: model.OrgRoleOwner, CreatedAt: time.Now()}
+	if err := s.repo.Create(org, owner); err != nil {
+		return nil, err
+	}
+	return s.detail(org.ID)
+}
+
+// Mine はユーザーが所属する組織の詳細を返します
+func (s *OrganizationService) Mine(userID uint) (*OrgDetail, error) {
+	m, err := s.membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(m.OrgID)
+}
+
+// AddMember はオーナーが既存ユーザーをメールアドレスで組織に追加します
+func (s *OrganizationService) AddMember(ownerID uint, email, role string) (*OrgDetail, error) {
+	owner, err := s.owner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(model.OrgRoles, role) {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidOrg, role)
+	}
+	u, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: user %q not found", ErrInvalidOrg, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.Membership(nil, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: user already belongs to an organization", ErrOrgConflict)
+	}
+	if err := s.repo.AddMember(&model.OrgMember{OrgID: owner.OrgID, UserID: u.ID, Role: role, CreatedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	return s.detail(owner.OrgID)
+}
+
+// UpdateMember はオーナーがメンバーの組織内ロールを変更します
+func (s *OrganizationService) UpdateMember(ownerID, userID uint, role string) (*OrgDetail, error) {
+	owner, err := s.owner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(model.OrgRoles, role) {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidOrg, role)
+	}
+	if err := s.sameOrg(owner.OrgID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMemberRole(owner.OrgID, userID, role); err != nil {
+		return nil, orgRepoError(err)
+	}
+	return s.detail(owner.OrgID)
+}
+
+// RemoveMember はメンバーを組織から外します（オーナーは誰でも、オーナー以外は自分自身のみ外せます）
+func (s *OrganizationService) RemoveMember(actorID, userID uint) error {
+	actor, err := s.membership(actorID)
+	if err != nil {
+		return err
+	}
+	if actorID != userID && actor.Role != model.OrgRoleOwner {
+		return ErrOrgForbidden
+	}
+	if err := s.sameOrg(actor.OrgID, userID); err != nil {
+		return err
+	}
+	return orgRepoError(s.repo.RemoveMember(actor.OrgID, userID))
+}
+
+// Bids は組織として行われた入札を新しい順に取得します（すべてのメンバーが閲覧できます）
+func (s *OrganizationService) Bids(userID uint, page, size int) ([]model.Bid, int64, error) {
+	m, err := s.membership(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindBids(m.OrgID, page, size)
+}
+
+// Auctions は組織に帰属するオークションを新しい順に取得します（すべてのメンバーが閲覧できます）
+func (s *OrganizationService) Auctions(userID uint, page, size int) ([]model.Auction, int64, error) {
+	m, err := s.membership(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindAuctions(m.OrgID, page, size)
+}
+
+// SetCreditLimit は管理者が組織の与信枠を変更します（0 は無制限, 監査ログに記録します）
+func (s *OrganizationService) SetCreditLimit(actor Actor, orgID uint, limit int, reason string) (*OrgDetail, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: credit limit must not be negative", ErrInvalidOrg)
+	}
+	org, err := s.repo.FindByID(orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.SetCreditLimit(tx, orgID, limit); err != nil {
+			return err
+		}
+		return s.audit.record(tx, actor, AuditEntry{Action: AuditOrgCreditLimit, TargetType: AuditTargetOrg, TargetID: orgID,
+			Reason: strings.TrimSpace(reason), Detail: map[string]any{"before": org.CreditLimit, "after": limit}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(orgID)
+}
+
+// GuardBid は BidService に登録する入札時の検査です
+// 組織のメンバーの入札は組織に帰属させ、次の場合は拒否します
+//   - 閲覧者 (viewer) の入札
+//   - 自組織が出品したオークションへの入札
+//   - 同じ組織の別のメンバーが最高入札となっているオークションでの競り合い
+//   - 開催中のオークションで組織が最高入札となっている金額の合計が与信枠を超える入札
+func (s *OrganizationService) GuardBid(tx *gorm.DB, auc *model.Auction, bid *model.Bid) error {
+	m, err := s.repo.Membership(tx, bid.UserID)
+	if err != nil || m == nil {
+		return err
+	}
+	if m.Role == model.OrgRoleViewer {
+		return fmt.Errorf("%w: viewers cannot bid", ErrOrgForbidden)
+	}
+	if auc.OrgID != nil && *auc.OrgID == m.OrgID {
+		return fmt.Errorf("%w: cannot bid on your organization's auction", ErrOrgConflict)
+	}
+	leading, err := s.repo.LeadingBid(tx, auc.ID)
+	if err != nil {
+		return err
+	}
+	if leading != nil && leading.OrgID != nil && *leading.OrgID == m.OrgID && leading.UserID != bid.UserID {
+		return fmt.Errorf("%w: another member of your organization holds the highest bid", ErrOrgConflict)
+	}
+	// 組織の行をロックし、メンバーの同時入札による与信枠の超過を防ぎます
+	org, err := s.repo.LockForBid(tx, m.OrgID)
+	if err != nil {
+		return err
+	}
+	if org.CreditLimit > 0 {
+		exposure, err := s.repo.Exposure(tx, org.ID, auc.ID, bid.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if exposure+bid.Amount > org.CreditLimit {
+			return fmt.Errorf("%w: %d of %d in use", ErrCreditLimitExceeded, exposure, org.CreditLimit)
+		}
+	}
+	orgID := m.OrgID
+	bid.OrgID = &orgID
+	return nil
+}
+
+// AttributeAuction は AuctionService.OnCreate に登録するリスナーで、組織のメンバーの出品を組織に帰属させます
+func (s *OrganizationService) AttributeAuction(a *model.Auction) {
+	m, err := s.repo.Membership(nil, a.SellerID)
+	if err != nil || m == nil {
+		return
+	}
+	if err := s.repo.SetAuctionOrg(a.ID, m.OrgID); err != nil {
+		return
+	}
+	orgID := m.OrgID
+	a.OrgID = &orgID
+}
+
+// detail は組織の詳細と与信の利用状況を組み立てます
+func (s *OrganizationService) detail(orgID uint) (*OrgDetail, error) {
+	org, err := s.repo.FindByID(orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	exposure, err := s.repo.Exposure(nil, orgID, 0, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	d := &OrgDetail{Organization: org, Exposure: exposure}
+	if org.CreditLimit > 0 {
+		avail := max(org.CreditLimit-exposure, 0)
+		d.AvailableCredit = &avail
+	}
+	return d, nil
+}
+
+// membership はユーザーの所属を返します（未所属なら ErrOrgNotFound）
+func (s *OrganizationService) membership(userID uint) (*model.OrgMember, error) {
+	m, err := s.repo.Membership(nil, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrOrgNotFound
+	}
+	return m, nil
+}
+
+// owner はユーザーが組織のオーナーであることを確認して所属を返します
+func (s *OrganizationService) owner(userID uint) (*model.OrgMember, error) {
+	m, err := s.membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Role != model.OrgRoleOwner {
+		return nil, ErrOrgForbidden
+	}
+	return m, nil
+}
+
+// sameOrg は userID が orgID のメンバーであることを確認します
+func (s *OrganizationService) sameOrg(orgID, userID uint) error {
+	m, err := s.repo.Membership(nil, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil || m.OrgID != orgID {
+		return fmt.Errorf("%w: member not found", ErrOrgNotFound)
+	}
+	return nil
+}
+
+// orgRepoError はリポジトリのエラーをサービスのエラーに変換します
+func orgRepoError(err error) error {
+	if errors.Is(err, repo.ErrLastOwner) {
+		return fmt.Errorf("%w: %w", ErrOrgConflict, err)
+	}
+	return err
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ksj/car-auction/internal/media"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+// MaxPhotosPerAuction は 1 オークションに添付できる写真の上限です
+const MaxPhotosPerAuction = 30
+
+var (
+	// ErrNotAuctionOwner は出品者以外がギャラリーを変更しようとした場合に返されます
+	ErrNotAuctionOwner = errors.New("forbidden: not owner")
+	// ErrPhotoNotFound は写真が存在しないか、指定オークションのものではない場合に返されます
+	ErrPhotoNotFound = errors.New("photo not found")
+	// ErrInvalidPhoto は写真の内容・並び順の指定が不正な場合に返されます
+	ErrInvalidPhoto = errors.New("invalid photo request")
+)
+
+// PhotoStore は写真ファイルの保存先です
+type PhotoStore interface {
+	URLResolver
+	Put(key string, r io.Reader, contentType string) error
+	Delete(key string) error
+}
+
+// URLResolver はストレージキーから配信用 URL を求めます
+type URLResolver interface {
+	URL(key string) string
+}
+
+// resolvePhotoURLs はギャラリーの各写真に配信用 URL と srcset を設定します
+func resolvePhotoURLs(urls URLResolver, photos []model.AuctionPhoto) {
+	if urls == nil {
+		return
+	}
+	for i := range photos {
+		p := &photos[i]
+		p.URL = urls.URL(p.StorageKey)
+		p.Srcset = srcset(urls, p.StorageKey, parseWidths(p.ThumbWidths), p.Width)
+	}
+}
+
+// srcset はサムネイルと原寸画像から <img srcset> 用の文字列を組み立てます
+func srcset(urls URLResolver, key string, thumbs []int, width int) string {
+	parts := make([]string, 0, len(thumbs)+1)
+	for _, w := range thumbs {
+		parts = append(parts, fmt.Sprintf("%s %dw", urls.URL(media.ThumbnailKey(key, w)), w))
+	}
+	if width > 0 {
+		parts = append(parts, fmt.Sprintf("%s %dw", urls.URL(key), width))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseWidths は "320,640" 形式の幅リストを解析します
+func parseWidths(s string) []int {
+	var out []int
+	for _, v := range strings.Split(s, ",") {
+		if w, err := strconv.Atoi(v); err == nil {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// StoredImage は画像パイプラインで処理して保存した画像です
+type StoredImage struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	Srcset      string `json:"srcset"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ThumbWidths []int  `json:"-"`
+}
+
+// storeImage は画像を検証・再エンコードし、原寸とサムネイルを prefix 配下に内容アドレスのキーで保存します
+func storeImage(store PhotoStore, prefix string, file io.Reader) (*StoredImage, error) {
+	res, err := media.Process(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPhoto, err)
+	}
+	img := &StoredImage{
+		Key:    prefix + "/" + res.Original.Name,
+		Width:  res.Original.Width,
+		Height: res.Original.Height,
+	}
+	saved := []string{}
+	for _, v := range append([]media.Variant{res.Original}, res.Thumbnails...) {
+		key := prefix + "/" + v.Name
+		if err := store.Put(key, bytes.NewReader(v.Data), v.ContentType); err != nil {
+			for _, k := range saved {
+				_ = store.Delete(k)
+			}
+			return nil, err
+		}
+		saved = append(saved, key)
+	}
+	for _, t := range res.Thumbnails {
+		img.ThumbWidths = append(img.ThumbWidths, t.Width)
+	}
+	img.URL = store.URL(img.Key)
+	img.Srcset = srcset(store, img.Key, img.ThumbWidths, img.Width)
+	return img, nil
+}
+
+// deleteImage は原寸画像とサムネイルを削除します
+func deleteImage(store PhotoStore, key string, thumbs []int) error {
+	for _, w := range thumbs {
+		if err := store.Delete(media.ThumbnailKey(key, w)); err != nil {
+			return err
+		}
+	}
+	return store.Delete(key)
+}
+
+// PhotoUpdateRequest は写真のキャプション・カバー指定を変更する DTO です
+type PhotoUpdateRequest struct {
+	Caption *string `json:"caption,omitempty"`
+	IsCover *bool   `json:"is_cover,omitempty"`
+}
+
+// PhotoService はオークション写真ギャラリーのビジネスロジックを担当します
+type PhotoService struct {
+	repo        *repo.PhotoRepo
+	auctions    *repo.AuctionRepo
+	inspections *repo.InspectionRepo
+	store       PhotoStore
+}
+
+// NewPhotoService はリポジトリとストレージを注入して PhotoService を生成します
+func NewPhotoService(r *repo.PhotoRepo, auctions *repo.AuctionRepo, inspections *repo.InspectionRepo, store PhotoStore) *PhotoService {
+	return &PhotoService{repo: r, auctions: auctions, inspections: inspections, store: store}
+}
+
+// checkOwner はオークションの出品者、または検査に割り当てられた検査員が userID であることを確認します
+func (s *PhotoService) checkOwner(userID, auctionID uint) error {
+	a, err := auctionByID(s.auctions, auctionID)
+	if err != nil {
+		return err
+	}
+	return checkAuctionEditor(a, userID, s.inspections)
+}
+
+// checkAuctionEditor は userID がオークションの出品者、または進行中の検査に割り当てられた検査員であることを確認します
+func checkAuctionEditor(a *model.Auction, userID uint, inspections *repo.InspectionRepo) error {
+	if a.SellerID == userID {
+		return nil
+	}
+	if inspections != nil {
+		ok, err := inspections.IsAssigned(a.ID, userID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return ErrNotAuctionOwner
+}
+
+// List は指定オークションの写真を表示順で返します
+func (s *PhotoService) List(auctionID uint) ([]model.AuctionPhoto, error) {
+	photos, err := s.repo.FindByAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	resolvePhotoURLs(s.store, photos)
+	return photos, nil
+}
+
+// Upload はオークションに紐付かない画像を画像パイプラインで処理して保存します
+func (s *PhotoService) Upload(file io.Reader) (*StoredImage, error) {
+	return storeImage(s.store, "images", file)
+}
+
+// Attach は写真を画像パイプラインで処理・保存してギャラリーの末尾に追加します（出品者のみ）
+// 最初の写真、または isCover が true の写真はカバーになります
+func (s *PhotoService) Attach(userID, auctionID uint, file io.Reader, caption string, isCover bool) (*model.AuctionPhoto, error) {
+	if err := s.checkOwner(userID, auctionID); err != nil {
+		return nil, err
+	}
+	photos, err := s.repo.FindByAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(photos) >= MaxPhotosPerAuction {
+		return nil, fmt.Errorf("%w: at most %d photos per auction", ErrInvalidPhoto, MaxPhotosPerAuction)
+	}
+
+	img, err := storeImage(s.store, fmt.Sprintf("auctions/%d", auctionID), file)
+	if err != nil {
+		return nil, err
+	}
+	// キーは内容から決まるため、同じ画像の重複登録はファイルを共有してしまう
+	for _, existing := range photos {
+		if existing.StorageKey == img.Key {
+			return nil, fmt.Errorf("%w: the same photo is already attached", ErrInvalidPhoto)
+		}
+	}
+	widths := make([]string, len(img.ThumbWidths))
+	for i, w := range img.ThumbWidths {
+		widths[i] = strconv.Itoa(w)
+	}
+	p := &model.AuctionPhoto{
+		AuctionID:   auctionID,
+		StorageKey:  img.Key,
+		Caption:     caption,
+		IsCover:     isCover,
+		Width:       img.Width,
+		Height:      img.Height,
+		ThumbWidths: strings.Join(widths, ","),
+	}
+	if err := s.repo.Create(p); err != nil {
+		_ = deleteImage(s.store, img.Key, img.ThumbWidths)
+		return nil, err
+	}
+	p.URL, p.Srcset = img.URL, img.Srcset
+	if err := s.syncCover(auctionID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update は写真のキャプションを変更し、is_cover=true の場合はカバーに設定します（出品者のみ）
+func (s *PhotoService) Update(userID, auctionID, photoID uint, req PhotoUpdateRequest) (*model.AuctionPhoto, error) {
+	if err := s.checkOwner(userID, auctionID); err != nil {
+		return nil, err
+	}
+	p, err := s.find(auctionID, photoID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Caption != nil {
+		p.Caption = *req.Caption
+		if err := s.repo.UpdateCaption(p); err != nil {
+			return nil, err
+		}
+	}
+	if req.IsCover != nil && *req.IsCover && !p.IsCover {
+		if err := s.repo.SetCover(auctionID, photoID); err != nil {
+			return nil, err
+		}
+		p.IsCover = true
+		if err := s.syncCover(auctionID); err != nil {
+			return nil, err
+		}
+	}
+	photos := []model.AuctionPhoto{*p}
+	resolvePhotoURLs(s.store, photos)
+	return &photos[0], nil
+}
+
+// Reorder は写真 ID の並びでギャラリーの表示順を置き換えます（出品者のみ）
+// ids には当該オークションの全ての写真を重複なく含める必要があります
+func (s *PhotoService) Reorder(userID, auctionID uint, ids []uint) ([]model.AuctionPhoto, error) {
+	if err := s.checkOwner(userID, auctionID); err != nil {
+		return nil, err
+	}
+	photos, err := s.repo.FindByAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[uint]bool, len(photos))
+	for _, p := range photos {
+		existing[p.ID] = true
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !existing[id] || seen[id] {
+			return nil, fmt.Errorf("%w: photo_ids must list every photo exactly once", ErrInvalidPhoto)
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(existing) {
+		return nil, fmt.Errorf("%w: photo_ids must list every photo exactly once", ErrInvalidPhoto)
+	}
+	if err := s.repo.Reorder(auctionID, ids); err != nil {
+		return nil, err
+	}
+	return s.List(auctionID)
+}
+
+// Delete は写真とそのファイルを削除します（出品者のみ）
+func (s *PhotoService) Delete(userID, auctionID, photoID uint) error {
+	if err := s.checkOwner(userID, auctionID); err != nil {
+		return err
+	}
+	p, err := s.find(auctionID, photoID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(p); err != nil {
+		return err
+	}
+	if err := deleteImage(s.store, p.StorageKey, parseWidths(p.ThumbWidths)); err != nil {
+		return err
+	}
+	return s.syncCover(auctionID)
+}
+
+// find は指定オークションに属する写真を取得します
+func (s *PhotoService) find(auctionID, photoID uint) (*model.AuctionPhoto, error) {
+	p, err := s.repo.FindByID(auctionID, photoID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	return p, err
+}
+
+// syncCover はカバー写真のストレージキーをオークションの photo_key に反映します（写真が無ければ空にします）
+func (s *PhotoService) syncCover(auctionID uint) error {
+	photos, err := s.repo.FindByAuction(auctionID)
+	if err != nil {
+		return err
+	}
+	key := ""
+	for _, p := range photos {
+		if p.IsCover {
+			key = p.StorageKey
+			break
+		}
+	}
+	return s.auctions.UpdateCoverPhoto(auctionID, key)
+}
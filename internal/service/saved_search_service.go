@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+// ErrSavedSearchNotFound は保存済み検索条件が存在しないか、他のユーザーのものである場合に返されます
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearchRequest は保存済み検索条件の作成・編集で受け取る DTO です
+type SavedSearchRequest struct {
+	Name        string             `json:"name"`
+	Filter      repo.AuctionFilter `json:"filter"`
+	NotifyEmail bool               `json:"notify_email"`
+}
+
+// SavedSearchView は保存済み検索条件と、デコードした検索条件を合わせたレスポンスです
+type SavedSearchView struct {
+	model.SavedSearch
+	Filter repo.AuctionFilter `json:"filter"`
+}
+
+// SavedSearchService は保存済み検索条件と新着出品のマッチングを担当します
+type SavedSearchService struct {
+	repo     *repo.SavedSearchRepo
+	auctions *repo.AuctionRepo
+	notifier *NotificationService
+	// queue は照合待ちの新規出品です（Run が順に処理します）
+	queue chan *model.Auction
+}
+
+// NewSavedSearchService はリポジトリと通知サービスを注入して SavedSearchService を生成します
+// 新着出品の照合を行うには Run をゴルーチンで起動してください
+func NewSavedSearchService(r *repo.SavedSearchRepo, auctions *repo.AuctionRepo, notifier *NotificationService) *SavedSearchService {
+	return &SavedSearchService{repo: r, auctions: auctions, notifier: notifier,
+		queue: make(chan *model.Auction, savedSearchQueueSize)}
+}
+
+// toView は保存された JSON の検索条件をデコードしてレスポンスに変換します
+func toView(s *model.SavedSearch) SavedSearchView {
+	v := SavedSearchView{SavedSearch: *s}
+	_ = json.Unmarshal([]byte(s.Filter), &v.Filter)
+	return v
+}
+
+// validateSavedSearch は名前と検索条件を検証し、検索条件を JSON に変換します
+// 並び順は新着通知に関係しないため保存しません
+func validateSavedSearch(req *SavedSearchRequest) (string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return "", fmt.Errorf("%w: name is required (max 100 bytes)", ErrInvalidFilter)
+	}
+	if err := ValidateFilter(req.Filter); err != nil {
+		return "", err
+	}
+	req.Filter.Sort = nil
+	b, err := json.Marshal(req.Filter)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Create は検索条件を保存します
+func (s *SavedSearchService) Create(userID uint, req SavedSearchRequest) (*SavedSearchView, error) {
+	filter, err := validateSavedSearch(&req)
+	if err != nil {
+		return nil, err
+	}
+	ss := &model.SavedSearch{
+		UserID:      userID,
+		Name:        req.Name,
+		Filter:      filter,
+		NotifyEmail: req.NotifyEmail,
+	}
+	if err := s.repo.Create(ss); err != nil {
+		return nil, err
+	}
+	v := toView(ss)
+	return &v, nil
+}
+
+// List は指定ユーザーの保存済み検索条件を返します
+func (s *SavedSearchService) List(userID uint) ([]SavedSearchView, error) {
+	list, err := s.repo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SavedSearchView, len(list))
+	for i := range list {
+		out[i] = toView(&list[i])
+	}
+	return out, nil
+}
+
+// find は所有者チェック付きで保存済み検索条件を取得します
+func (s *SavedSearchService) find(userID, id uint) (*model.SavedSearch, error) {
+	ss, err := s.repo.FindByUserAndID(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSavedSearchNotFound
+	}
+	return ss, err
+}
+
+// Update は保存済み検索条件の名前・検索条件・メール通知設定を置き換えます
+func (s *SavedSearchService) Update(userID, id uint, req SavedSearchRequest) (*SavedSearchView, error) {
+	ss, err := s.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := validateSavedSearch(&req)
+	if err != nil {
+		return nil, err
+	}
+	ss.Name = req.Name
+	ss.Filter = filter
+	ss.NotifyEmail = req.NotifyEmail
+	if err := s.repo.Save(ss); err != nil {
+		return nil, err
+	}
+	v := toView(ss)
+	return &v, nil
+}
+
+// SetPaused は保存済み検索条件の通知を一時停止・再開します
+func (s *SavedSearchService) SetPaused(userID, id uint, paused bool) (*SavedSearchView, error) {
+	ss, err := s.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	ss.Paused = paused
+	if err := s.repo.Save(ss); err != nil {
+		return nil, err
+	}
+	v := toView(ss)
+	return &v, nil
+}
+
+// Delete は保存済み検索条件を削除します
+func (s *SavedSearchService) Delete(userID, id uint) error {
+	n, err := s.repo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// savedSearchQueueSize は照合待ちの新規出品を溜めておける件数です
+const savedSearchQueueSize = 256
+
+// savedSearchBatchSize は 1 回のクエリでまとめて照合する検索条件の件数です
+const savedSearchBatchSize = 100
+
+// MatchNewAuction は新規出品を照合待ちのキューに入れます
+// AuctionService.OnCreate に登録して使用します。照合と通知は Run のゴルーチンで行うため、出品の応答を遅らせません
+// キューが満杯の場合はその出品の通知を諦めます
+func (s *SavedSearchService) MatchNewAuction(a *model.Auction) {
+	c := *a
+	select {
+	case s.queue <- &c:
+	default:
+		log.Printf("SAVED SEARCH: queue full, skipped auction id=%d", a.ID)
+	}
+}
+
+// Run はキューに入った新規出品を順に保存済み検索条件と照合します（ctx が終了するまで）
+func (s *SavedSearchService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-s.queue:
+			s.match(a)
+		}
+	}
+}
+
+// match は新規出品を有効な保存済み検索条件と照合し、一致したユーザーに通知します
+// 出品者自身の検索条件は対象外です
+func (s *SavedSearchService) match(a *model.Auction) {
+	list, err := s.repo.FindActive()
+	if err != nil {
+		log.Printf("SAVED SEARCH: load failed: %v", err)
+		return
+	}
+	var targets []*model.SavedSearch
+	var filters []repo.AuctionFilter
+	for i := range list {
+		ss := &list[i]
+		if ss.UserID == a.SellerID {
+			continue
+		}
+		var f repo.AuctionFilter
+		if err := json.Unmarshal([]byte(ss.Filter), &f); err != nil {
+			log.Printf("SAVED SEARCH: invalid filter id=%d: %v", ss.ID, err)
+			continue
+		}
+		targets = append(targets, ss)
+		filters = append(filters, f)
+	}
+	for start := 0; start < len(filters); start += savedSearchBatchSize {
+		end := min(start+savedSearchBatchSize, len(filters))
+		matched, err := s.auctions.MatchFilters(a.ID, filters[start:end])
+		if err != nil {
+			log.Printf("SAVED SEARCH: match failed auction id=%d: %v", a.ID, err)
+			continue
+		}
+		for i, ok := range matched {
+			if ok {
+				s.deliver(targets[start+i], a)
+			}
+		}
+	}
+}
+
+// deliver は一致した検索条件の持ち主に通知し、最後に通知した日時を記録します
+func (s *SavedSearchService) deliver(ss *model.SavedSearch, a *model.Auction) {
+	auctionID := a.ID
+	n := &model.Notification{
+		UserID:    ss.UserID,
+		Kind:      "saved_search_match",
+		Title:     fmt.Sprintf("「%s」に一致する新着車両: %s", ss.Name, a.Title),
+		Body:      fmt.Sprintf("%s %s (%d年, %dkm) が出品されました。開始価格: %d円\n/auctions/%d", a.Maker, a.ModelName, a.Year, a.Mileage, a.StartPrice, a.ID),
+		AuctionID: &auctionID,
+	}
+	if err := s.notifier.Notify(n, ss.NotifyEmail); err != nil {
+		log.Printf("SAVED SEARCH: notify failed id=%d: %v", ss.ID, err)
+		return
+	}
+	if err := s.repo.TouchMatched(ss.ID, time.Now()); err != nil {
+		log.Printf("SAVED SEARCH: update failed id=%d: %v", ss.ID, err)
+	}
+}
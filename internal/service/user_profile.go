@@ -0,0 +1,264 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ksj/car-auction/internal/model"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidProfile はプロフィールの項目が要件を満たさない場合に返されます（どの項目かはメッセージに含めます）
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrIncorrectPassword はパスワード変更・アカウント削除の申請などで現在のパスワードが一致しない場合に返されます
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrDeletionAlreadyRequested はアカウントの削除を申請済みのユーザーが再び申請した場合に返されます
+	ErrDeletionAlreadyRequested = errors.New("account deletion already requested")
+	// ErrNoDeletionRequest はアカウントの削除を申請していないユーザーが取り消そうとした場合に返されます
+	ErrNoDeletionRequest = errors.New("account deletion is not requested")
+)
+
+// 本人によるアカウント操作の監査ログのアクション
+const (
+	AuditProfileUpdate      = "user.profile_update"
+	AuditPasswordChange     = "user.password_change"
+	AuditEmailChangeRequest = "user.email_change_request"
+	AuditEmailChange        = "user.email_change"
+	AuditDeletionRequest    = "user.deletion_request"
+	AuditDeletionCancel     = "user.deletion_cancel"
+)
+
+// プロフィールの各項目・削除理由の最大文字数（model.User の列の長さに合わせています）
+const (
+	maxDisplayNameRunes    = 50
+	maxAddressRunes        = 255
+	maxCompanyRunes        = 100
+	maxDeletionReasonRunes = 255
+)
+
+// ProfileLanguages はプロフィールで選べる言語です
+var ProfileLanguages = []string{"ja", "en", "ko"}
+
+// ProfileCurrencies はプロフィールで選べる表示通貨です
+var ProfileCurrencies = []string{"JPY", "USD", "EUR", "KRW"}
+
+// phonePattern は電話番号として受け付ける形式です（先頭の + と数字・ハイフン・空白, 最大 20 文字）
+// 数字の桁数 (7〜15 桁, E.164) は別に確認します
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 -]{5,17}[0-9]$`)
+
+// UpdateProfileRequest はプロフィールの変更内容です（nil の項目は変更しません, 空文字で消去）
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	Phone       *string `json:"phone"`
+	Address     *string `json:"address"`
+	Company     *string `json:"company"`
+	Language    *string `json:"language"`
+	Currency    *string `json:"currency"`
+}
+
+// fields は項目を検証し、変更する列名と値の組を返します
+func (req UpdateProfileRequest) fields() (map[string]any, error) {
+	out := map[string]any{}
+	text := func(col string, v *string, max int) error {
+		if v == nil {
+			return nil
+		}
+		s := strings.TrimSpace(*v)
+		if utf8.RuneCountInString(s) > max {
+			return fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidProfile, col, max)
+		}
+		out[col] = s
+		return nil
+	}
+	if err := text("display_name", req.DisplayName, maxDisplayNameRunes); err != nil {
+		return nil, err
+	}
+	if err := text("address", req.Address, maxAddressRunes); err != nil {
+		return nil, err
+	}
+	if err := text("company", req.Company, maxCompanyRunes); err != nil {
+		return nil, err
+	}
+	if req.Phone != nil {
+		s := strings.TrimSpace(*req.Phone)
+		digits := len(strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s))
+		if s != "" && (!phonePattern.MatchString(s) || digits < 7 || digits > 15) {
+			return nil, fmt.Errorf("%w: phone must be 7 to 15 digits", ErrInvalidProfile)
+		}
+		out["phone"] = s
+	}
+	if req.Language != nil {
+		s := strings.ToLower(strings.TrimSpace(*req.Language))
+		if !slices.Contains(ProfileLanguages, s) {
+			return nil, fmt.Errorf("%w: language must be one of %s", ErrInvalidProfile, strings.Join(ProfileLanguages, ", "))
+		}
+		out["language"] = s
+	}
+	if req.Currency != nil {
+		s := strings.ToUpper(strings.TrimSpace(*req.Currency))
+		if !slices.Contains(ProfileCurrencies, s) {
+			return nil, fmt.Errorf("%w: currency must be one of %s", ErrInvalidProfile, strings.Join(ProfileCurrencies, ", "))
+		}
+		out["currency"] = s
+	}
+	return out, nil
+}
+
+// UseAudit は本人によるアカウント操作（プロフィール・パスワードの変更、削除の申請）を記録する監査ログを設定します
+func (s *UserService) UseAudit(a *AuditService) { s.audit = a }
+
+// audited は監査ログを tx で記録します（監査ログが未設定なら何もしません）
+func (s *UserService) audited(tx *gorm.DB, actor Actor, e AuditEntry) error {
+	if s.audit == nil {
+		return nil
+	}
+	e.TargetType, e.TargetID = AuditTargetUser, actor.UserID
+	return s.audit.record(tx, actor, e)
+}
+
+// Profile は本人のユーザー情報（付与ロールを含む）を返します
+func (s *UserService) Profile(userID uint) (*model.User, error) {
+	u, err := s.Repo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	return u, err
+}
+
+// UpdateProfile はプロフィールを検証して変更し、変更後のユーザーを返します
+// 監査ログには変更した項目名のみを記録します（値は個人情報のため記録しません）
+func (s *UserService) UpdateProfile(actor Actor, req UpdateProfileRequest) (*model.User, error) {
+	fields, err := req.fields()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		names := make([]string, 0, len(fields))
+		for k := range fields {
+			names = append(names, k)
+		}
+		slices.Sort(names)
+		err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+			if err := s.Repo.UpdateProfile(tx, actor.UserID, fields); err != nil {
+				return err
+			}
+			return s.audited(tx, actor, AuditEntry{Action: AuditProfileUpdate, Detail: map[string]any{"fields": names}})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.Profile(actor.UserID)
+}
+
+// ChangePassword は現在のパスワードを確認してパスワードを変更します
+// 他の端末のリフレッシュトークン・アクセストークンはすべて無効にし、この端末用に新しいトークンを発行します
+func (s *UserService) ChangePassword(actor Actor, current, password string) (*TokenPair, *model.User, error) {
+	if len(password) < minPasswordLen {
+		return nil, nil, ErrInvalidPassword
+	}
+	if _, err := s.checkPassword(actor.UserID, current); err != nil {
+		return nil, nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.Repo.UpdatePassword(tx, actor.UserID, string(hash)); err != nil {
+			return err
+		}
+		if err := s.tokens.RevokeUser(tx, actor.UserID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.Repo.BumpTokenVersion(tx, actor.UserID); err != nil {
+			return err
+		}
+		return s.audited(tx, actor, AuditEntry{Action: AuditPasswordChange})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// 世代番号を進めた後のユーザーでトークンを発行
+	u, err := s.Profile(actor.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pair, err := s.issueTokens(nil, u, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
+
+// RequestDeletion は現在のパスワードを確認してアカウントの削除を申請します
+// 削除は管理者が申請を確認して行い、それまでは本人が CancelDeletion で取り消せます
+func (s *UserService) RequestDeletion(actor Actor, password, reason string) (*model.User, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxDeletionReasonRunes {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidProfile, maxDeletionReasonRunes)
+	}
+	u, err := s.checkPassword(actor.UserID, password)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletionRequestedAt != nil {
+		return nil, ErrDeletionAlreadyRequested
+	}
+	now := time.Now()
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.Repo.SetDeletionRequest(tx, actor.UserID, &now, reason); err != nil {
+			return err
+		}
+		return s.audited(tx, actor, AuditEntry{Action: AuditDeletionRequest, Reason: reason})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Profile(actor.UserID)
+}
+
+// CancelDeletion はアカウントの削除の申請を取り消します
+func (s *UserService) CancelDeletion(actor Actor) (*model.User, error) {
+	u, err := s.Profile(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletionRequestedAt == nil {
+		return nil, ErrNoDeletionRequest
+	}
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.Repo.SetDeletionRequest(tx, actor.UserID, nil, ""); err != nil {
+			return err
+		}
+		return s.audited(tx, actor, AuditEntry{Action: AuditDeletionCancel})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Profile(actor.UserID)
+}
+
+// checkPassword はユーザーの現在のパスワードを確認します（一致しなければ ErrIncorrectPassword）
+func (s *UserService) checkPassword(userID uint, password string) (*model.User, error) {
+	u, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return nil, ErrIncorrectPassword
+	}
+	return u, nil
+}
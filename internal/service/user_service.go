@@ -1,91 +1,511 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/ksj/car-auction/internal/config"
+	"github.com/ksj/car-auction/internal/jwtkey"
 	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
 	"github.com/ksj/car-auction/internal/repo"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAccountSuspended は管理者により停止されたアカウントでログイン・API 利用した場合に返されます
+	ErrAccountSuspended = errors.New("account suspended")
+	// ErrAccountNotFound はトークンのユーザーが存在しない（削除済みなど）場合に返されます
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrTokenRevoked はログアウトなどでトークンの世代番号が進み、アクセストークンが無効になった場合に返されます
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrInvalidRefreshToken はリフレッシュトークンが存在しない・期限切れ・失効済みの場合に返されます
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrInvalidCredentials はメールアドレスまたはパスワードが一致しない場合に返されます
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidMFAToken は二要素認証の途中トークン (mfa_token) が不正・期限切れの場合に返されます
+	ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+	// ErrRefreshTokenReused は使用済みのリフレッシュトークンが再び使われた場合に返されます
+	// 盗用の可能性があるため、同じログインのトークンをすべて失効させ、発行済みのアクセストークンも無効にします
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
 )
 
 // UserService はユーザー関連のビジネスロジックを提供します
-type UserService struct{ Repo *repo.UserRepo }
+type UserService struct {
+	Repo   *repo.UserRepo
+	tokens *repo.RefreshTokenRepo
+	// onSignup は新規ユーザーの保存後に呼び出されるリスナーです
+	onSignup []func(*model.User)
+	// mfaVerify は二要素認証のコードを検証する関数です（nil なら二要素認証を有効にしたユーザーはログインできません）
+	mfaVerify func(u *model.User, code string) error
+	// loginGuard はログイン失敗の回数制限です（nil なら制限しません）
+	loginGuard *LoginGuard
+	// audit は本人によるアカウント操作の監査ログです（nil なら記録しません）
+	audit *AuditService
+}
+
+// mfaTokenTTL はパスワード確認後、二要素認証のコード入力までの有効期限です
+const mfaTokenTTL = 5 * time.Minute
+
+// mfaPendingType は二要素認証の途中トークンの typ クレームです（AuthMiddleware では受け付けません）
+const mfaPendingType = "mfa_pending"
 
 // NewUserService はリポジトリを注入して UserService を生成します
-func NewUserService(r *repo.UserRepo) *UserService { return &UserService{Repo: r} }
+func NewUserService(r *repo.UserRepo, tokens *repo.RefreshTokenRepo) *UserService {
+	return &UserService{Repo: r, tokens: tokens}
+}
+
+// OnSignup は新規ユーザーが保存された後に呼び出されるリスナーを登録します
+// リスナーは Signup の呼び出し元と同じゴルーチンで順に実行されます
+func (s *UserService) OnSignup(fn func(*model.User)) {
+	s.onSignup = append(s.onSignup, fn)
+}
+
+// UseMFA はログインの 2 段階目で二要素認証のコードを検証する関数を設定します
+func (s *UserService) UseMFA(fn func(u *model.User, code string) error) { s.mfaVerify = fn }
+
+// UseLoginGuard はログイン失敗に対する待ち時間・ロックと、ログイン試行の監査ログを設定します
+func (s *UserService) UseLoginGuard(g *LoginGuard) { s.loginGuard = g }
+
+// LoginResult はログインの結果です
+// 二要素認証が有効なユーザーは Tokens が nil となり、MFAToken とワンタイムコードを LoginMFA に渡してログインを完了します
+type LoginResult struct {
+	User     *model.User
+	Tokens   *TokenPair
+	MFAToken string
+}
+
+// TokenPair はログイン・再発行時に返すアクセストークンとリフレッシュトークンです
+type TokenPair struct {
+	// AccessToken は API 呼び出しに使う JWT です（旧クライアント互換のため JSON では token）
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn はアクセストークンの有効期限までの秒数です
+	ExpiresIn int `json:"expires_in"`
+}
 
 // CreateUserRequest はサインアップ時に受け取るリクエスト DTO です
 type CreateUserRequest struct {
 	Email    string
 	Password string
 	Role     string // "seller" or "bidder"
+	// Roles で複数のロールを同時に指定できます（Role と併用した場合は両方を付与します）
+	Roles []string
+}
+
+// signupRoles は Role と Roles をまとめ、重複を除いて検証します
+func (req CreateUserRequest) signupRoles() ([]string, error) {
+	var roles []string
+	for _, r := range append([]string{req.Role}, req.Roles...) {
+		if r == "" || slices.Contains(roles, r) {
+			continue
+		}
+		if !slices.Contains(rbac.SignupRoles, r) {
+			return nil, errors.New("invalid signup request")
+		}
+		roles = append(roles, r)
+	}
+	if len(roles) == 0 {
+		return nil, errors.New("invalid signup request")
+	}
+	return roles, nil
 }
 
-// Signup は新規ユーザーを登録し、JWT トークンを返却します
-func (s *UserService) Signup(req CreateUserRequest) (string, error) {
+// Signup は新規ユーザーを登録し、トークンを返却します
+func (s *UserService) Signup(req CreateUserRequest) (*TokenPair, error) {
 	// バリデーション: 必須項目とロールのチェック
-	if req.Email == "" || req.Password == "" || (req.Role != "bidder" && req.Role != "seller") {
-		return "", errors.New("invalid signup request")
+	roles, err := req.signupRoles()
+	if err != nil {
+		return nil, err
+	}
+	if req.Email == "" || req.Password == "" {
+		return nil, errors.New("invalid signup request")
 	}
 
 	// パスワードのハッシュ化
 	hashBytes, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// ユーザーモデルの組み立て
+	// ユーザーモデルの組み立て（最初のロールを主ロールとします）
+	now := time.Now()
 	u := &model.User{
 		Email:     req.Email,
 		Password:  string(hashBytes),
-		Role:      req.Role, // ← 저장
-		CreatedAt: time.Now(),
+		Role:      roles[0], // ← 저장
+		CreatedAt: now,
+	}
+	for _, r := range roles {
+		u.Roles = append(u.Roles, model.UserRole{Role: r, CreatedAt: now})
 	}
 	if err := s.Repo.Create(u); err != nil {
-		return "", err
+		return nil, err
 	}
+	for _, fn := range s.onSignup {
+		fn(u)
+	}
+	return s.issueTokens(nil, u, "")
+}
 
-	// JWT クレームの作成 (user_id, role, 有効期限)
-	claims := jwt.MapClaims{
-		"user_id": u.ID,
-		"role":    u.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+// Login はメールアドレスとパスワードを検証し、トークンとユーザー（付与ロールを含む）を返却します
+// 二要素認証が有効なユーザーにはトークンの代わりに短期間の mfa_token を返します
+// ip は接続元 IP で、失敗が続くアカウント・接続元からのログインは *LoginThrottledError で拒否します
+func (s *UserService) Login(email, password, ip string) (*LoginResult, error) {
+	// 1) 受付停止中のアカウント・接続元は、パスワードを検証せずに拒否
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(email, ip); err != nil {
+			return nil, err
+		}
+	}
+	// 2) ユーザー取得とパスワード検証
+	u, err := s.Repo.FindByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		var userID uint
+		if u != nil {
+			userID = u.ID
+		}
+		return nil, s.loginFailed(userID, email, ip, "password", ErrInvalidCredentials)
+	}
+	if u.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
 	}
-	tokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := tokenObj.SignedString([]byte(config.Cfg.JwtSecret))
+	// 3) 二要素認証が有効なら 2 段階目へ（失敗回数は 2 段階目の成功まで数え直しません）
+	if u.MFAEnabled() {
+		tok, err := signMFAToken(u)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: u, MFAToken: tok}, nil
+	}
+	if err := s.loginSucceeded(u, ip); err != nil {
+		return nil, err
+	}
+	// 4) トークン生成（ログインごとに新しいファミリーを開始）
+	pair, err := s.issueTokens(nil, u, "")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return signed, nil
+	return &LoginResult{User: u, Tokens: pair}, nil
 }
 
-// Login はメールアドレスとパスワードを検証し、JWT トークンとユーザーロールを返却します
-func (s *UserService) Login(email, password string) (token string, role string, err error) {
-	// 1) ユーザー取得
-	u, err := s.Repo.FindByEmail(email)
+// LoginMFA はログインの 2 段階目で、mfa_token とワンタイムコード（またはリカバリーコード）を検証してトークンを発行します
+// コードの誤りもログインの失敗として数えます
+func (s *UserService) LoginMFA(mfaToken, code, ip string) (*TokenPair, *model.User, error) {
+	userID, version, err := parseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	u, err := s.Repo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.SuspendedAt != nil {
+		return nil, nil, ErrAccountSuspended
+	}
+	// パスワード再設定・全端末ログアウト後は途中トークンも無効
+	if u.TokenVersion != version || !u.MFAEnabled() || s.mfaVerify == nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(u.Email, ip); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := s.mfaVerify(u, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return nil, nil, s.loginFailed(u.ID, u.Email, ip, "mfa", err)
+		}
+		return nil, nil, err
+	}
+	if err := s.loginSucceeded(u, ip); err != nil {
+		return nil, nil, err
+	}
+	pair, err := s.issueTokens(nil, u, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
+
+// LoginExternal は外部の IdP で認証済みのユーザーをログインさせます（パスワードは検証しません）
+// 二要素認証が有効なユーザーには Login と同じく mfa_token を返し、LoginMFA でログインを完了します
+func (s *UserService) LoginExternal(u *model.User, ip string) (*LoginResult, error) {
+	if u.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+	if u.MFAEnabled() {
+		tok, err := signMFAToken(u)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: u, MFAToken: tok}, nil
+	}
+	if err := s.loginSucceeded(u, ip); err != nil {
+		return nil, err
+	}
+	pair, err := s.issueTokens(nil, u, "")
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{User: u, Tokens: pair}, nil
+}
+
+// loginFailed はログインの失敗を記録し、呼び出し元に返すエラー cause をそのまま返します
+func (s *UserService) loginFailed(userID uint, email, ip, reason string, cause error) error {
+	if s.loginGuard == nil {
+		return cause
+	}
+	if err := s.loginGuard.Failed(userID, email, ip, reason); err != nil {
+		return err
+	}
+	return cause
+}
+
+// loginSucceeded はログインの成功を記録します
+func (s *UserService) loginSucceeded(u *model.User, ip string) error {
+	if s.loginGuard == nil {
+		return nil
+	}
+	return s.loginGuard.Succeeded(u, ip)
+}
+
+// Refresh はリフレッシュトークンを新しいトークンに置き換え、アクセストークンを再発行します
+// 使用済みのトークンが再び使われた場合は同じログインのトークンをすべて失効させ、ErrRefreshTokenReused を返します
+func (s *UserService) Refresh(raw string) (*TokenPair, *model.User, error) {
+	rt, err := s.tokens.FindByHash(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if rt.RotatedAt != nil {
+		return nil, nil, s.revokeReused(rt)
+	}
+	now := time.Now()
+	if rt.RevokedAt != nil || !now.Before(rt.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	u, err := s.Repo.FindByID(rt.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.SuspendedAt != nil {
+		return nil, nil, ErrAccountSuspended
+	}
+
+	var pair *TokenPair
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := s.tokens.Rotate(tx, rt.ID, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 同じトークンでの同時再発行: 先に成功した側以外は再利用として扱います
+			return ErrRefreshTokenReused
+		}
+		pair, err = s.issueTokens(tx, u, rt.FamilyID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, nil, s.revokeReused(rt)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
+
+// revokeReused はリフレッシュトークンの再利用を検知した際に、同じログインのトークンを失効させ、
+// トークンの世代番号を進めて発行済みのアクセストークンも無効にします
+func (s *UserService) revokeReused(rt *model.RefreshToken) error {
+	err := s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.tokens.RevokeFamily(tx, rt.FamilyID, time.Now()); err != nil {
+			return err
+		}
+		return s.Repo.BumpTokenVersion(tx, rt.UserID)
+	})
+	if err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout はリフレッシュトークンのログインを終了します
+// all が true の場合はユーザーのすべてのリフレッシュトークンを失効させ、発行済みのアクセストークンも無効にします
+func (s *UserService) Logout(raw string, all bool) error {
+	rt, err := s.tokens.FindByHash(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
 	if err != nil {
-		return "", "", errors.New("invalid credentials")
+		return err
+	}
+	if !all {
+		return s.tokens.RevokeFamily(nil, rt.FamilyID, time.Now())
 	}
-	// 2) パスワード検証
-	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
-		return "", "", errors.New("invalid credentials")
+	return s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.tokens.RevokeUser(tx, rt.UserID, time.Now()); err != nil {
+			return err
+		}
+		return s.Repo.BumpTokenVersion(tx, rt.UserID)
+	})
+}
+
+// issueTokens はアクセストークンと新しいリフレッシュトークンを発行します
+// familyID が空の場合は新しいログインとしてファミリーを開始します（tx が nil なら既定の DB を使用）
+func (s *UserService) issueTokens(tx *gorm.DB, u *model.User, familyID string) (*TokenPair, error) {
+	if familyID == "" {
+		id, err := randomToken(16)
+		if err != nil {
+			return nil, err
+		}
+		familyID = hex.EncodeToString(id)
+	}
+	raw, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+	if err := s.tokens.Create(tx, &model.RefreshToken{
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(config.Cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	access, err := signToken(u, config.Cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
 	}
-	// 3) JWT クレームの作成 (user_id, role, 有効期限)
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(config.Cfg.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// signingKeys はアプリが発行する JWT（アクセストークン・途中トークン）の署名鍵です
+var signingKeys *jwtkey.Set
+
+// UseSigningKeys はトークンの署名・検証に使う鍵を設定します（未設定ならトークンを発行できません）
+func UseSigningKeys(ks *jwtkey.Set) { signingKeys = ks }
+
+// signToken は user_id・主ロール・付与ロールの一覧・トークンの世代番号・有効期限を含む JWT を発行します
+// 権限はロールから都度求めるため、トークンにはロールのみを含めます
+func signToken(u *model.User, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": u.ID,
 		"role":    u.Role,
-		"exp":     time.Now().Add(config.Cfg.AuctionTTL).Unix(),
+		"roles":   u.RoleNames(),
+		"ver":     u.TokenVersion,
+		"exp":     time.Now().Add(ttl).Unix(),
+	}
+	return signingKeys.Sign(claims)
+}
+
+// signMFAToken は二要素認証の 2 段階目に使う短期間の途中トークンを発行します
+// ロールを含めず typ クレームを付けるため、API の認証には使えません
+func signMFAToken(u *model.User) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": u.ID,
+		"typ":     mfaPendingType,
+		"ver":     u.TokenVersion,
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
+	}
+	return signingKeys.Sign(claims)
+}
+
+// parseMFAToken は途中トークンを検証し、user_id とトークンの世代番号を返します
+func parseMFAToken(raw string) (uint, int, error) {
+	claims, err := signingKeys.Parse(raw)
+	if err != nil || claims["typ"] != mfaPendingType {
+		return 0, 0, ErrInvalidMFAToken
+	}
+	uid, ok1 := claims["user_id"].(float64)
+	ver, ok2 := claims["ver"].(float64)
+	if !ok1 || !ok2 {
+		return 0, 0, ErrInvalidMFAToken
+	}
+	return uint(uid), int(ver), nil
+}
+
+// randomToken は暗号論的乱数で n バイトを生成します
+func randomToken(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// hashToken はトークンを保存・照合用の SHA-256 (16 進) に変換します
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAccount はトークン発行後にアカウントが停止・削除されていないか、
+// トークンの世代番号 (ver クレーム) が現在の値と一致するかを確認します
+// AuthMiddleware から毎リクエスト呼ばれ、停止やログアウトは発行済みトークンにも即時に反映されます
+func (s *UserService) CheckAccount(userID uint, version int) error {
+	u, err := s.Repo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if u.SuspendedAt != nil {
+		return ErrAccountSuspended
+	}
+	if u.TokenVersion != version {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// EnsureAdmin は起動時に管理者アカウントを用意します
+// email のユーザーが無ければ admin ロールで作成し、既存ユーザーなら admin ロールを追加します（パスワードは変更しません）
+func (s *UserService) EnsureAdmin(email, password string) error {
+	u, err := s.Repo.FindByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if password == "" {
+			return errors.New("admin password is required to create the admin account")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		return s.Repo.Create(&model.User{
+			Email: email, Password: string(hash), Role: model.RoleAdmin, CreatedAt: now,
+			Roles: []model.UserRole{{Role: model.RoleAdmin, CreatedAt: now}},
+		})
 	}
-	// 4) トークン生成
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := t.SignedString(config.Cfg.JwtSecret)
 	if err != nil {
-		return "", "", err
+		return err
 	}
-	// 5) token と role を返却
-	return signed, u.Role, nil
+	return s.Repo.AddRole(u.ID, model.RoleAdmin)
 }
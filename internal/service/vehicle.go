@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/vin"
+)
+
+var (
+	// ErrInvalidVIN は VIN・車台番号の形式やチェックディジットが不正な場合に返されます
+	ErrInvalidVIN = errors.New("invalid vin")
+	// ErrDuplicateVIN は同じ車両で開催中のオークションが既にある場合に返されます
+	ErrDuplicateVIN = errors.New("a live auction already exists for this vehicle")
+)
+
+// modelYearTolerance は年式と出品者の入力の差として許容する年数です
+// VIN の年式（モデルイヤー）は初度登録年より 1 年進むことがあるため、1 年の差は矛盾とみなしません
+const modelYearTolerance = 1
+
+// decodeVehicle は VIN と車台番号をデコードします。両方指定された場合は VIN を優先します
+// どちらも未指定なら nil を返します
+func decodeVehicle(vinNumber, chassisNumber string, now time.Time) (*vin.Info, error) {
+	var spec *vin.Info
+	if chassisNumber != "" {
+		info, err := vin.DecodeChassis(chassisNumber)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidVIN, err)
+		}
+		spec = info
+	}
+	if vinNumber != "" {
+		info, err := vin.Decode(vinNumber, now)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidVIN, err)
+		}
+		if spec != nil {
+			info.ChassisNumber = spec.ChassisNumber
+			if info.ModelName == "" {
+				info.ModelName = spec.ModelName
+			}
+		}
+		spec = info
+	}
+	return spec, nil
+}
+
+// applyVehicleSpec はデコード結果で未入力のメーカー・車種・年式を補完し、
+// 出品者の入力と矛盾する項目を SpecMismatch に記録します
+func applyVehicleSpec(a *model.Auction, spec *vin.Info) {
+	a.VIN, a.ChassisNumber = spec.VIN, spec.ChassisNumber
+
+	var mismatch []string
+	switch {
+	case a.Maker == "":
+		a.Maker = spec.Maker
+	case !spec.SameMaker(a.Maker):
+		mismatch = append(mismatch, "maker")
+	}
+	switch {
+	case a.ModelName == "":
+		a.ModelName = spec.ModelName
+	case spec.ModelName != "" && !strings.EqualFold(spec.ModelName, a.ModelName):
+		mismatch = append(mismatch, "model_name")
+	}
+	switch {
+	case a.Year == 0:
+		a.Year = spec.ModelYear
+	case spec.ModelYear != 0 && abs(spec.ModelYear-a.Year) > modelYearTolerance:
+		mismatch = append(mismatch, "year")
+	}
+	a.SpecMismatch = strings.Join(mismatch, ",")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// DecodeVehicle は VIN または車台番号をデコードして車両情報を返します（出品フォームの自動入力用）
+func (s *AuctionService) DecodeVehicle(vinNumber, chassisNumber string) (*vin.Info, error) {
+	spec, err := decodeVehicle(vinNumber, chassisNumber, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("%w: vin or chassis_number is required", ErrInvalidVIN)
+	}
+	return spec, nil
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksj/car-auction/internal/media"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVerificationRequired はポリシーにより業者の本人確認の承認が必要な入札の場合に返されます
+	ErrVerificationRequired = errors.New("dealer verification is required for this bid")
+	// ErrVerificationNotFound は本人確認の申請・書類が存在しない場合に返されます
+	ErrVerificationNotFound = errors.New("verification not found")
+	// ErrVerificationConflict は現在の申請の状態では実行できない操作（審査中の書類の変更・審査済みの申請の再審査など）の場合に返されます
+	ErrVerificationConflict = errors.New("verification state conflict")
+	// ErrInvalidVerification は申請内容・書類・審査理由が不正な場合に返されます
+	ErrInvalidVerification = errors.New("invalid verification request")
+)
+
+// 監査ログの操作名と対象種別（本人確認の申請）
+const (
+	AuditVerificationSubmit  = "verification.submit"
+	AuditVerificationApprove = "verification.approve"
+	AuditVerificationReject  = "verification.reject"
+	AuditVerificationRevoke  = "verification.revoke"
+
+	AuditTargetVerification = "verification"
+)
+
+const (
+	// MaxVerificationDocuments は 1 件の申請に添付できる書類の上限です
+	MaxVerificationDocuments = 10
+	// maxCompanyNameRunes は商号（屋号）の最大文字数です
+	maxCompanyNameRunes = 100
+)
+
+var (
+	// licenceNumberPattern は古物商許可番号（公安委員会の番号 12 桁）です
+	licenceNumberPattern = regexp.MustCompile(`^[0-9]{12}$`)
+	// corporateNumberPattern は法人番号（13 桁）です
+	corporateNumberPattern = regexp.MustCompile(`^[0-9]{13}$`)
+)
+
+// VerificationPolicy は本人確認の承認を必須とする入札の設定です
+type VerificationPolicy struct {
+	// RequireForBidding が true の場合、すべての入札に本人確認の承認が必要です
+	RequireForBidding bool
+	// BidThreshold 以上の入札には本人確認の承認が必要です（0 は制限なし）
+	BidThreshold int
+}
+
+// VerificationSubmitRequest は本人確認の申請を提出する DTO です
+type VerificationSubmitRequest struct {
+	CompanyName     string `json:"company_name"`
+	LicenceNumber   string `json:"licence_number"`
+	CorporateNumber string `json:"corporate_number"`
+}
+
+// normalize は前後の空白と番号の区切り（ハイフン・空白）を取り除き、値を検証します
+func (req VerificationSubmitRequest) normalize() (VerificationSubmitRequest, error) {
+	digits := strings.NewReplacer("-", "", " ", "")
+	req.CompanyName = strings.TrimSpace(req.CompanyName)
+	req.LicenceNumber = digits.Replace(req.LicenceNumber)
+	req.CorporateNumber = digits.Replace(req.CorporateNumber)
+	switch {
+	case req.CompanyName == "":
+		return req, fmt.Errorf("%w: company_name is required", ErrInvalidVerification)
+	case len([]rune(req.CompanyName)) > maxCompanyNameRunes:
+		return req, fmt.Errorf("%w: company_name must be at most %d characters", ErrInvalidVerification, maxCompanyNameRunes)
+	case !licenceNumberPattern.MatchString(req.LicenceNumber):
+		return req, fmt.Errorf("%w: licence_number must be 12 digits", ErrInvalidVerification)
+	case req.CorporateNumber != "" && !corporateNumberPattern.MatchString(req.CorporateNumber):
+		return req, fmt.Errorf("%w: corporate_number must be 13 digits", ErrInvalidVerification)
+	}
+	return req, nil
+}
+
+// VerificationService は業者の本人確認（KYC）のワークフローを担当します
+// 本人が書類を添付して申請を提出し、管理者が審査して承認・却下します
+// 状態の変更は監査ログに記録し、申請者に通知します
+type VerificationService struct {
+	db       *gorm.DB
+	repo     *repo.VerificationRepo
+	users    *repo.UserRepo
+	store    PhotoStore
+	audit    *AuditService
+	notifier *NotificationService
+	policy   VerificationPolicy
+}
+
+// NewVerificationService はリポジトリ・ストレージ・監査ログ・通知サービスを注入して VerificationService を生成します
+func NewVerificationService(r *repo.VerificationRepo, users *repo.UserRepo, store PhotoStore,
+	audit *AuditService, notifier *NotificationService, policy VerificationPolicy) *VerificationService {
+	return &VerificationService{db: r.DB, repo: r, users: users, store: store,
+		audit: audit, notifier: notifier, policy: policy}
+}
+
+// Mine は本人の最新の申請を返します
+func (s *VerificationService) Mine(userID uint) (*model.DealerVerification, error) {
+	v, err := s.repo.Latest(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVerificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.resolveURLs(v)
+	return v, nil
+}
+
+// AddDocument は書類を保存して本人の申請に添付します
+// 申請が無い、または却下・取り消し済みの場合は新しい申請（下書き）を作ります。審査中・承認済みの申請には添付できません
+func (s *VerificationService) AddDocument(userID uint, kind string, file io.Reader) (*model.VerificationDocument, error) {
+	if !slices.Contains(model.VerificationDocumentKinds, kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %v", ErrInvalidVerification, model.VerificationDocumentKinds)
+	}
+	v, err := s.draft(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Documents) >= MaxVerificationDocuments {
+		return nil, fmt.Errorf("%w: at most %d documents per verification", ErrInvalidVerification, MaxVerificationDocuments)
+	}
+	d, err := storeDocument(s.store, fmt.Sprintf("verifications/%d", v.ID), file)
+	if err != nil {
+		return nil, err
+	}
+	// キーは内容から決まるため、同じ書類の重複登録はファイルを共有してしまう
+	for _, existing := range v.Documents {
+		if existing.StorageKey == d.StorageKey {
+			return nil, fmt.Errorf("%w: the same document is already attached", ErrInvalidVerification)
+		}
+	}
+	d.VerificationID, d.Kind = v.ID, kind
+	if err := s.repo.AddDocument(d); err != nil {
+		_ = deleteDocumentFile(s.store, d)
+		return nil, err
+	}
+	d.URL = s.store.URL(d.StorageKey)
+	return d, nil
+}
+
+// RemoveDocument は下書きの申請から書類を削除します
+func (s *VerificationService) RemoveDocument(userID, documentID uint) error {
+	v, err := s.Mine(userID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(v.Documents, func(d model.VerificationDocument) bool { return d.ID == documentID })
+	if i < 0 {
+		return ErrVerificationNotFound
+	}
+	if v.Status != model.VerificationDraft {
+		return fmt.Errorf("%w: documents cannot be changed once submitted", ErrVerificationConflict)
+	}
+	d := &v.Documents[i]
+	if err := s.repo.DeleteDocument(d); err != nil {
+		return err
+	}
+	return deleteDocumentFile(s.store, d)
+}
+
+// Submit は下書きの申請を審査待ちにします（必須の書類がすべて添付されている必要があります）
+func (s *VerificationService) Submit(actor Actor, req VerificationSubmitRequest) (*model.DealerVerification, error) {
+	req, err := req.normalize()
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.Mine(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != model.VerificationDraft {
+		return nil, fmt.Errorf("%w: verification is %s", ErrVerificationConflict, v.Status)
+	}
+	for _, kind := range model.RequiredVerificationDocuments {
+		if !slices.ContainsFunc(v.Documents, func(d model.VerificationDocument) bool { return d.Kind == kind }) {
+			return nil, fmt.Errorf("%w: %s document is required", ErrInvalidVerification, kind)
+		}
+	}
+	now := time.Now()
+	err = s.transition(actor, v, []string{model.VerificationDraft}, map[string]any{
+		"status":           model.VerificationPending,
+		"company_name":     req.CompanyName,
+		"licence_number":   req.LicenceNumber,
+		"corporate_number": req.CorporateNumber,
+		"submitted_at":     now,
+	}, AuditEntry{Action: AuditVerificationSubmit}, nil)
+	if err != nil {
+		return nil, err
+	}
+	v.Status, v.SubmittedAt = model.VerificationPending, &now
+	v.CompanyName, v.LicenceNumber, v.CorporateNumber = req.CompanyName, req.LicenceNumber, req.CorporateNumber
+	s.notify(v.UserID, "verification_submitted", "本人確認の申請を受け付けました",
+		"審査の結果はあらためてお知らせします")
+	return v, nil
+}
+
+// List は管理者向けに申請を状態で絞り込んで（空ならすべて）提出の古い順に返します
+func (s *VerificationService) List(status string, page, size int) ([]model.DealerVerification, int64, error) {
+	if status != "" && !slices.Contains([]string{model.VerificationDraft, model.VerificationPending,
+		model.VerificationApproved, model.VerificationRejected, model.VerificationRevoked}, status) {
+		return nil, 0, fmt.Errorf("%w: unknown status %q", ErrInvalidVerification, status)
+	}
+	list, total, err := s.repo.Search(status, page, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range list {
+		s.resolveURLs(&list[i])
+	}
+	return list, total, nil
+}
+
+// Get は管理者向けに申請を提出書類付きで返します
+func (s *VerificationService) Get(id uint) (*model.DealerVerification, error) {
+	v, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVerificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.resolveURLs(v)
+	return v, nil
+}
+
+// Approve は審査待ちの申請を承認し、申請者を本人確認済みにします（reason は任意）
+func (s *VerificationService) Approve(actor Actor, id uint, reason string) (*model.DealerVerification, error) {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > maxAdminReasonRunes {
+		return nil, fmt.Errorf("%w: reason is too long", ErrInvalidVerification)
+	}
+	v, err := s.review(actor, id, model.VerificationPending, model.VerificationApproved, reason, AuditVerificationApprove,
+		func(tx *gorm.DB, v *model.DealerVerification, now time.Time) error {
+			return s.users.SetDealerVerified(tx, v.UserID, &now)
+		})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(v.UserID, "verification_approved", "本人確認が承認されました", "すべてのオークションに入札できるようになりました")
+	return v, nil
+}
+
+// Reject は審査待ちの申請を却下します（理由は申請者に通知するため必須です）
+func (s *VerificationService) Reject(actor Actor, id uint, reason string) (*model.DealerVerification, error) {
+	reason, err := verificationReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.review(actor, id, model.VerificationPending, model.VerificationRejected, reason, AuditVerificationReject, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(v.UserID, "verification_rejected", "本人確認の申請が却下されました",
+		"理由: "+reason+"\n書類を添付し直して再度申請してください")
+	return v, nil
+}
+
+// Revoke は承認済みの申請を取り消します（許可の失効など）。申請者は本人確認済みではなくなります
+func (s *VerificationService) Revoke(actor Actor, id uint, reason string) (*model.DealerVerification, error) {
+	reason, err := verificationReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.review(actor, id, model.VerificationApproved, model.VerificationRevoked, reason, AuditVerificationRevoke,
+		func(tx *gorm.DB, v *model.DealerVerification, _ time.Time) error {
+			return s.users.SetDealerVerified(tx, v.UserID, nil)
+		})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(v.UserID, "verification_revoked", "本人確認の承認が取り消されました", "理由: "+reason)
+	return v, nil
+}
+
+// GuardBid は BidService に登録する入札時の検査で、ポリシーにより本人確認が承認されていないユーザーの入札を拒否します
+func (s *VerificationService) GuardBid(tx *gorm.DB, _ *model.Auction, bid *model.Bid) error {
+	if !s.policy.RequireForBidding && (s.policy.BidThreshold <= 0 || bid.Amount < s.policy.BidThreshold) {
+		return nil
+	}
+	u, err := s.users.Get(tx, bid.UserID)
+	if err != nil {
+		return err
+	}
+	if u.DealerVerifiedAt == nil {
+		return ErrVerificationRequired
+	}
+	return nil
+}
+
+// draft は書類を添付できる本人の申請を返します（必要なら新しい下書きを作ります）
+func (s *VerificationService) draft(userID uint) (*model.DealerVerification, error) {
+	v, err := s.repo.Latest(userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	case err != nil:
+		return nil, err
+	case v.Status == model.VerificationDraft:
+		return v, nil
+	case v.Status == model.VerificationPending:
+		return nil, fmt.Errorf("%w: verification is under review", ErrVerificationConflict)
+	case v.Status == model.VerificationApproved:
+		return nil, fmt.Errorf("%w: already verified", ErrVerificationConflict)
+	}
+	v = &model.DealerVerification{UserID: userID, Status: model.VerificationDraft}
+	if err := s.repo.Create(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// review は管理者の審査で申請を from から to に変更します
+// apply が nil でなければ同じトランザクションでユーザーの本人確認状況を更新します
+func (s *VerificationService) review(actor Actor, id uint, from, to, reason, action string,
+	apply func(tx *gorm.DB, v *model.DealerVerification, now time.Time) error) (*model.DealerVerification, error) {
+	v, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = s.transition(actor, v, []string{from}, map[string]any{
+		"status":        to,
+		"reviewer_id":   actor.UserID,
+		"review_reason": reason,
+		"reviewed_at":   now,
+	}, AuditEntry{Action: action, Reason: reason}, func(tx *gorm.DB) error {
+		if apply == nil {
+			return nil
+		}
+		return apply(tx, v, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reviewer := actor.UserID
+	v.Status, v.ReviewerID, v.ReviewReason, v.ReviewedAt = to, &reviewer, reason, &now
+	return v, nil
+}
+
+// transition は申請の状態の変更・fn による変更・監査ログの記録を 1 つのトランザクションで実行します
+// 申請が from のいずれの状態でもなければ ErrVerificationConflict を返します
+func (s *VerificationService) transition(actor Actor, v *model.DealerVerification, from []string,
+	updates map[string]any, e AuditEntry, fn func(tx *gorm.DB) error) error {
+	e.TargetType, e.TargetID = AuditTargetVerification, v.ID
+	e.Detail = map[string]any{"user_id": v.UserID, "from": v.Status, "to": updates["status"]}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := s.repo.Transition(tx, v.ID, from, updates)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: verification is no longer %s", ErrVerificationConflict, strings.Join(from, "/"))
+		}
+		if fn != nil {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		if s.audit == nil {
+			return nil
+		}
+		return s.audit.record(tx, actor, e)
+	})
+}
+
+// resolveURLs は提出書類に署名付きの配信用 URL を設定します
+func (s *VerificationService) resolveURLs(v *model.DealerVerification) {
+	for i := range v.Documents {
+		d := &v.Documents[i]
+		d.URL = s.store.URL(d.StorageKey)
+	}
+}
+
+// notify は申請の状態変化を申請者にアプリ内通知とメールで知らせます（失敗はワークフローを止めません）
+func (s *VerificationService) notify(userID uint, kind, title, body string) {
+	if s.notifier == nil {
+		return
+	}
+	_ = s.notifier.Notify(&model.Notification{UserID: userID, Kind: kind, Title: title, Body: body}, true)
+}
+
+// verificationReason は却下・取り消しの理由を検証します（申請者への通知と監査のため必須です）
+func verificationReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: reason is required", ErrInvalidVerification)
+	}
+	if len([]rune(reason)) > maxAdminReasonRunes {
+		return "", fmt.Errorf("%w: reason is too long", ErrInvalidVerification)
+	}
+	return reason, nil
+}
+
+// storeDocument は本人確認書類を保存します
+// 画像は画像パイプラインで処理し（位置情報などのメタデータも除去されます）、PDF は内容から決まるキーでそのまま保存します
+func storeDocument(store PhotoStore, prefix string, file io.Reader) (*model.VerificationDocument, error) {
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > media.MaxBytes {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVerification, media.ErrImageTooLarge)
+	}
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		sum := sha256.Sum256(data)
+		d := &model.VerificationDocument{StorageKey: prefix + "/" + hex.EncodeToString(sum[:]) + ".pdf", ContentType: "application/pdf"}
+		if err := store.Put(d.StorageKey, bytes.NewReader(data), d.ContentType); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	img, err := storeImage(store, prefix, bytes.NewReader(data))
+	if errors.Is(err, ErrInvalidPhoto) {
+		return nil, fmt.Errorf("%w: document must be an image or PDF: %w", ErrInvalidVerification, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	widths := make([]string, len(img.ThumbWidths))
+	for i, w := range img.ThumbWidths {
+		widths[i] = strconv.Itoa(w)
+	}
+	return &model.VerificationDocument{
+		StorageKey:  img.Key,
+		ContentType: mime.TypeByExtension(path.Ext(img.Key)),
+		ThumbWidths: strings.Join(widths, ","),
+	}, nil
+}
+
+// deleteDocumentFile は書類のファイル（画像はサムネイルも）を削除します
+func deleteDocumentFile(store PhotoStore, d *model.VerificationDocument) error {
+	return deleteImage(store, d.StorageKey, parseWidths(d.ThumbWidths))
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local はローカルディレクトリ（複数コンテナで動かす場合は共有ボリューム）にファイルを保存し、
+// BaseURL 配下で配信するストレージです
+type Local struct {
+	Dir     string
+	BaseURL string
+	// Signer が nil でなければ URL に署名と有効期限を付与します
+	Signer *Signer
+}
+
+// NewLocal は dir に保存し baseURL (例: "/static/") で配信する Local を生成します
+func NewLocal(dir, baseURL string, signer *Signer) *Local {
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/") + "/", Signer: signer}
+}
+
+// path はキーを保存先のファイルパスに変換します
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put は r の内容を key に保存します（既存のファイルは上書きされます）
+// Content-Type は配信時に拡張子から求めるため保存しません
+func (l *Local) Put(key string, r io.Reader, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// 書き込み途中のファイルが配信されないよう一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get は key のファイルを開きます
+func (l *Local) Get(key string) (io.ReadCloser, *Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, nil, ErrNotFound
+	}
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	return f, &Object{ContentType: ct, Size: st.Size(), ModTime: st.ModTime()}, nil
+}
+
+// Delete は key のファイルを削除します（存在しない場合はエラーにしません）
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL は key の配信用 URL を返します（Signer があれば署名付き）
+func (l *Local) URL(key string) string {
+	u := l.BaseURL + key
+	if l.Signer != nil {
+		u += "?" + l.Signer.Sign(key).Encode()
+	}
+	return u
+}
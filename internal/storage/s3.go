@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 は S3 互換 API (AWS S3, MinIO など) にファイルを保存するストレージです
+// リクエストは AWS Signature Version 4 で署名し、ダウンロード URL は事前署名 URL を返します
+type S3 struct {
+	// Endpoint は API のベース URL です (例: "https://s3.ap-northeast-1.amazonaws.com", "http://minio:9000")
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle が true なら "<endpoint>/<bucket>/<key>"、false なら "<bucket>.<host>/<key>" 形式でアクセスします
+	PathStyle bool
+	// URLTTL は事前署名 URL の有効期間です
+	URLTTL time.Duration
+
+	Client *http.Client
+	// Now は現在時刻を返します（テストで差し替え可能）
+	Now func() time.Time
+}
+
+// NewS3 は S3 互換ストレージを生成します（MinIO 等を想定してパススタイルで接続します）
+func NewS3(endpoint, region, bucket, accessKey, secretKey string, urlTTL time.Duration) *S3 {
+	return &S3{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PathStyle: true,
+		URLTTL:    urlTTL,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		Now:       time.Now,
+	}
+}
+
+// unsignedPayload は事前署名 URL でペイロードを署名対象にしないことを示します
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// objectURL は key のオブジェクト URL を返します
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if s.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	return u, nil
+}
+
+// Put は r の内容を key に保存します
+func (s *S3) Put(key string, r io.Reader, contentType string) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, key, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.statusError(http.MethodPut, key, resp)
+	}
+	return nil
+}
+
+// Get は key のオブジェクトを取得します
+func (s *S3) Get(key string) (io.ReadCloser, *Object, error) {
+	resp, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, nil, s.statusError(http.MethodGet, key, resp)
+	}
+	obj := &Object{ContentType: resp.Header.Get("Content-Type"), Size: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = t
+	}
+	return resp.Body, obj, nil
+}
+
+// Delete は key のオブジェクトを削除します（存在しない場合はエラーにしません）
+func (s *S3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return s.statusError(http.MethodDelete, key, resp)
+}
+
+// URL は key の事前署名付きダウンロード URL を返します
+// 署名時刻は分単位に切り捨て、同じ画像の URL がしばらく同一になるようにしてブラウザキャッシュを効かせます
+func (s *S3) URL(key string) string {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return ""
+	}
+	now := s.Now().UTC().Truncate(time.Minute)
+	q := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.AccessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(amzDateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(s.URLTTL / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	u.RawQuery = canonicalQuery(q)
+	sig := s.signature(now, http.MethodGet, u, map[string]string{"host": u.Host}, unsignedPayload)
+	u.RawQuery += "&X-Amz-Signature=" + sig
+	return u.String()
+}
+
+// do は Authorization ヘッダーで署名したリクエストを送信します
+func (s *S3) do(method, key string, body []byte, contentType string) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	now := s.Now().UTC()
+
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format(amzDateFormat),
+	}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+	for k, v := range headers {
+		if k != "host" {
+			req.Header.Set(k, v)
+		}
+	}
+	sig := s.signature(now, method, u, headers, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, s.scope(now), signedHeaders(headers), sig))
+	return s.Client.Do(req)
+}
+
+func (s *S3) statusError(method, key string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+const amzDateFormat = "20060102T150405Z"
+
+// scope は署名のクレデンシャルスコープ "<日付>/<リージョン>/s3/aws4_request" を返します
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature は Signature Version 4 の署名を計算します
+func (s *S3) signature(t time.Time, method string, u *url.URL, headers map[string]string, payloadHash string) string {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var ch strings.Builder
+	for _, k := range names {
+		ch.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	canonical := strings.Join([]string{
+		method,
+		uriEncode(u.Path, false),
+		u.RawQuery,
+		ch.String(),
+		signedHeaders(headers),
+		payloadHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDateFormat) + "\n" + s.scope(t) + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func signedHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// canonicalQuery はキー順に並べて RFC 3986 でエンコードしたクエリ文字列を返します
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode は SigV4 の規則 (非予約文字以外を %XX) でエンコードします。encodeSlash が false なら "/" はそのままにします
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,49 @@
+// Package storage はアップロードされたファイルの保存先を提供します。
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidKey はストレージキーにディレクトリ外を指すパスが含まれる場合に返されます
+	ErrInvalidKey = errors.New("invalid storage key")
+	// ErrNotFound は指定したキーのオブジェクトが存在しない場合に返されます
+	ErrNotFound = errors.New("object not found")
+)
+
+// BlobStore はアップロードファイルを保存・取得するバックエンドです
+// 複数コンテナで共有できるよう、保存先は Local (共有ボリューム) と S3 互換ストレージから選択します
+type BlobStore interface {
+	// Put は r の内容を key に保存します（既存のオブジェクトは上書きされます）
+	Put(key string, r io.Reader, contentType string) error
+	// Get は key のオブジェクトを返します。呼び出し側で Close する必要があります
+	Get(key string) (io.ReadCloser, *Object, error)
+	// Delete は key のオブジェクトを削除します（存在しない場合はエラーにしません）
+	Delete(key string) error
+	// URL は key の署名付き・期限付きダウンロード URL を返します
+	URL(key string) string
+}
+
+// Object は保存済みオブジェクトのメタデータです
+type Object struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// cleanKey はキーを検証し、ディレクトリ外を指すキーや空のキーを拒否します
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." || strings.Contains(seg, "\\") {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
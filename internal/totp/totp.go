@@ -0,0 +1,93 @@
+// Package totp は RFC 6238 の時間ベースのワンタイムパスワード (TOTP) を提供します。
+// Google Authenticator などの認証アプリと互換の HMAC-SHA1・6 桁・30 秒周期の設定のみを扱います。
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits はコードの桁数です
+	Digits = 6
+	// Period はコードが切り替わる周期です
+	Period = 30 * time.Second
+	// secretSize は共有鍵のバイト数です（RFC 4226 の推奨値 160 ビット）
+	secretSize = 20
+)
+
+// ErrInvalidSecret は共有鍵が Base32 として不正な場合に返されます
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret は新しい共有鍵を Base32（パディング無し）で返します
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Step は時刻 t の時間ステップ（Unix 時刻 / 周期）を返します
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code は時間ステップ step のコードを返します
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidSecret
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	// RFC 4226 5.3: 動的切り出し
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, v%1000000), nil
+}
+
+// Validate は code が時刻 t の前後 skew ステップ以内のコードと一致するかを判定し、一致したステップを返します
+// 呼び出し側は返されたステップを保存し、同じステップ以前のコードの再利用を拒否してください
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	now := Step(t)
+	for i := -skew; i <= skew; i++ {
+		want, err := Code(secret, now+int64(i))
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(want), []byte(code)) {
+			return now + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// URI は認証アプリに登録するための otpauth:// URI（QR コードにする文字列）を返します
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
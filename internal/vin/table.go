@@ -0,0 +1,59 @@
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+)
+
+// テーブルはオフラインでデコードできるよう CSV としてバイナリに埋め込みます
+var (
+	//go:embed wmi.csv
+	wmiCSV string
+	//go:embed plants.csv
+	plantsCSV string
+	//go:embed chassis.csv
+	chassisCSV string
+)
+
+type wmiEntry struct {
+	maker, country string
+	aliases        []string
+}
+
+type chassisEntry struct {
+	maker, model string
+}
+
+var (
+	wmiTable     = map[string]wmiEntry{}
+	plantTable   = map[string]string{}
+	chassisTable = map[string]chassisEntry{}
+	// makerAliases はメーカー名ごとの別名（日本語表記など）です
+	makerAliases = map[string][]string{}
+)
+
+func init() {
+	for _, r := range readCSV(wmiCSV) {
+		aliases := strings.Split(r[2], "|")
+		wmiTable[r[0]] = wmiEntry{maker: r[1], country: r[3], aliases: aliases}
+		makerAliases[r[1]] = aliases
+	}
+	for _, r := range readCSV(plantsCSV) {
+		plantTable[r[0]+r[1]] = r[2]
+	}
+	for _, r := range readCSV(chassisCSV) {
+		chassisTable[r[0]] = chassisEntry{maker: r[1], model: r[2]}
+	}
+}
+
+// readCSV は "#" で始まるコメント行を除いた CSV を読み込みます（埋め込みデータのため不正な行は panic します）
+func readCSV(data string) [][]string {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		panic("vin: broken embedded table: " + err.Error())
+	}
+	return rows
+}
@@ -0,0 +1,165 @@
+// Package vin は車台番号（ISO 3779 の VIN と国内の車台番号）の検証と、
+// 同梱のオフラインテーブルによる車両情報のデコードを提供します。
+package vin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidVIN は VIN の形式が不正な場合に返されます
+	ErrInvalidVIN = errors.New("invalid vin")
+	// ErrCheckDigit は VIN のチェックディジット（9 桁目）が一致しない場合に返されます
+	ErrCheckDigit = errors.New("vin check digit mismatch")
+	// ErrInvalidChassisNumber は国内の車台番号の形式が不正な場合に返されます
+	ErrInvalidChassisNumber = errors.New("invalid chassis number")
+)
+
+// Info はデコードした車両情報です。テーブルに無い項目は空になります
+type Info struct {
+	VIN           string `json:"vin,omitempty"`
+	ChassisNumber string `json:"chassis_number,omitempty"`
+	WMI           string `json:"wmi,omitempty"`
+	Maker         string `json:"maker,omitempty"`
+	ModelName     string `json:"model_name,omitempty"`
+	Country       string `json:"country,omitempty"`
+	ModelYear     int    `json:"model_year,omitempty"`
+	PlantCode     string `json:"plant_code,omitempty"`
+	Plant         string `json:"plant,omitempty"`
+	Serial        string `json:"serial"`
+
+	aliases []string
+}
+
+// SameMaker は name がデコードしたメーカー名（または別名）と一致するかを大文字小文字を区別せずに判定します
+// メーカーが不明な場合は true を返します
+func (i *Info) SameMaker(name string) bool {
+	if i.Maker == "" {
+		return true
+	}
+	name = strings.TrimSpace(name)
+	for _, m := range append([]string{i.Maker}, i.aliases...) {
+		if strings.EqualFold(m, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize は空白とハイフンを除いて大文字に揃えます
+func Normalize(v string) string {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	return strings.NewReplacer(" ", "", "-", "").Replace(v)
+}
+
+// transliteration は ISO 3779 の文字→数値の対応です（I, O, Q は使用不可）
+var transliteration = map[byte]int{
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+var weights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// CheckDigit は 17 桁の VIN から 9 桁目に入るべきチェックディジットを計算します
+func CheckDigit(v string) (byte, error) {
+	if len(v) != 17 {
+		return 0, fmt.Errorf("%w: must be 17 characters", ErrInvalidVIN)
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		c := v[i]
+		var n int
+		switch {
+		case c >= '0' && c <= '9':
+			n = int(c - '0')
+		default:
+			var ok bool
+			if n, ok = transliteration[c]; !ok {
+				return 0, fmt.Errorf("%w: invalid character %q", ErrInvalidVIN, c)
+			}
+		}
+		sum += n * weights[i]
+	}
+	r := sum % 11
+	if r == 10 {
+		return 'X', nil
+	}
+	return byte('0' + r), nil
+}
+
+// requiresCheckDigit は WMI の地域がチェックディジットを義務付けているか（北米・中国）を返します
+// チェックディジットは ISO 3779 では任意で、義務付けているのは北米（49 CFR 565）と中国（GB 16735）だけです
+// 欧州・日本などのメーカーは 9 桁目を車両記述部の一部（"Z" の埋め字など）として使うことが多く、
+// 検証すると正規の輸入車の VIN を拒否してしまうため、これらの地域では形式と文字種だけを検証します
+func requiresCheckDigit(wmi string) bool {
+	return strings.ContainsRune("12345L", rune(wmi[0]))
+}
+
+// Decode は VIN を検証し、WMI・年式・工場をデコードします
+// 年式コードは 30 年周期のため、now から見て未来になりすぎない最新の年を選びます
+func Decode(v string, now time.Time) (*Info, error) {
+	v = Normalize(v)
+	check, err := CheckDigit(v)
+	if err != nil {
+		return nil, err
+	}
+	wmi := v[:3]
+	if requiresCheckDigit(wmi) && v[8] != check {
+		return nil, fmt.Errorf("%w: expected %q, got %q", ErrCheckDigit, check, v[8])
+	}
+
+	info := &Info{VIN: v, WMI: wmi, PlantCode: v[10:11], Serial: v[11:]}
+	if m, ok := wmiTable[wmi]; ok {
+		info.Maker, info.Country, info.aliases = m.maker, m.country, m.aliases
+	}
+	info.Plant = plantTable[wmi+v[10:11]]
+	info.ModelYear = modelYear(v, now)
+	return info, nil
+}
+
+// yearCodes は 10 桁目の年式コードです（1980 年の A から 30 年周期）
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// modelYear は 10 桁目から年式を求めます
+// 北米の VIN では 7 桁目が英字なら 2010 年以降の周期を表します
+func modelYear(v string, now time.Time) int {
+	idx := strings.IndexByte(yearCodes, v[9])
+	if idx < 0 {
+		return 0
+	}
+	year := 1980 + idx
+	if requiresCheckDigit(v[:3]) {
+		if v[6] >= 'A' && v[6] <= 'Z' {
+			year += 30
+		}
+		return year
+	}
+	for year+30 <= now.Year()+1 {
+		year += 30
+	}
+	return year
+}
+
+// chassisPattern は国内の車台番号（型式-連番）の形式です
+var chassisPattern = regexp.MustCompile(`^([A-Z0-9]{2,8})-([0-9]{4,8})$`)
+
+// DecodeChassis は国内の車台番号（例: "ZVW30-1234567"）を検証し、型式からメーカー・車種をデコードします
+// 国内の車台番号にはチェックディジットや年式コードがありません
+func DecodeChassis(number string) (*Info, error) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	m := chassisPattern.FindStringSubmatch(number)
+	if m == nil {
+		return nil, fmt.Errorf("%w: expected <model code>-<serial>", ErrInvalidChassisNumber)
+	}
+	info := &Info{ChassisNumber: number, Serial: m[2], Country: "Japan"}
+	if c, ok := chassisTable[m[1]]; ok {
+		info.Maker, info.ModelName = c.maker, c.model
+		info.aliases = makerAliases[c.maker]
+	}
+	return info, nil
+}
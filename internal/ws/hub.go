@@ -1,16 +1,40 @@
 package ws
 
 import (
+	"encoding/json"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// DefaultPresenceInterval は viewer_count イベントを配信する既定の間隔です
+const DefaultPresenceInterval = 10 * time.Second
+
 // Client は WebSocket クライアント接続情報を表します
+// UserID が 0 の場合は未認証（匿名）の閲覧者です
 type Client struct {
-	Conn *websocket.Conn
-	Send chan []byte
+	Conn   *websocket.Conn
+	Send   chan []byte
+	UserID uint
+	// Bidder は入札権限を持つユーザーの接続かどうかです
+	Bidder bool
+}
+
+// Presence はオークションごとの閲覧状況を表します
+type Presence struct {
+	Viewers   int `json:"viewers"`   // 接続数の合計
+	Users     int `json:"users"`     // 認証済みユーザー数（重複除外）
+	Bidders   int `json:"bidders"`   // 認証済み bidder 数（重複除外）
+	Anonymous int `json:"anonymous"` // 匿名接続数
+}
+
+// PresenceEvent は購読者へ定期的に送信される viewer_count イベントです
+type PresenceEvent struct {
+	Type      string `json:"type"`
+	AuctionID uint   `json:"auction_id"`
+	Presence
 }
 
 // Hub はオークションごとにクライアントを管理するハブです
@@ -20,15 +44,17 @@ type Hub struct {
 	register   chan subscription
 	unregister chan subscription
 	broadcast  chan event
+
+	presenceInterval time.Duration
 }
 
-// event はブロードキャスト対象データを表します
+// subscription はハブへの登録／解除リクエストを表します
 type subscription struct {
 	AuctionID uint
 	Client    *Client
 }
 
-// subscription はハブへの登録／解除リクエストを表します
+// event はブロードキャスト対象データを表します
 type event struct {
 	AuctionID uint
 	Data      []byte
@@ -37,10 +63,18 @@ type event struct {
 // NewHub は新しい Hub を生成します
 func NewHub() *Hub {
 	return &Hub{
-		clients:    make(map[uint]map[*Client]bool),
-		register:   make(chan subscription),
-		unregister: make(chan subscription),
-		broadcast:  make(chan event),
+		clients:          make(map[uint]map[*Client]bool),
+		register:         make(chan subscription),
+		unregister:       make(chan subscription),
+		broadcast:        make(chan event),
+		presenceInterval: DefaultPresenceInterval,
+	}
+}
+
+// SetPresenceInterval は viewer_count イベントの配信間隔を設定します（Run の前に呼び出してください）
+func (h *Hub) SetPresenceInterval(d time.Duration) {
+	if d > 0 {
+		h.presenceInterval = d
 	}
 }
 
@@ -51,9 +85,39 @@ func (h *Hub) Clients(auctionID uint) int {
 	return len(h.clients[auctionID])
 }
 
+// Presence は指定オークションIDの閲覧状況を集計して返します
+func (h *Hub) Presence(auctionID uint) Presence {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.presenceLocked(auctionID)
+}
+
+// presenceLocked は mu を保持した状態で閲覧状況を集計します
+func (h *Hub) presenceLocked(auctionID uint) Presence {
+	var p Presence
+	users := make(map[uint]bool)
+	bidders := make(map[uint]bool)
+	for c := range h.clients[auctionID] {
+		p.Viewers++
+		if c.UserID == 0 {
+			p.Anonymous++
+			continue
+		}
+		users[c.UserID] = true
+		if c.Bidder {
+			bidders[c.UserID] = true
+		}
+	}
+	p.Users = len(users)
+	p.Bidders = len(bidders)
+	return p
+}
+
 // Run はハブの内部イベントループを開始します
-// register, unregister, broadcast チャンネルのイベントを処理します
+// register, unregister, broadcast チャンネルのイベントと viewer_count の定期配信を処理します
 func (h *Hub) Run() {
+	ticker := time.NewTicker(h.presenceInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case sub := <-h.register:
@@ -87,7 +151,35 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.Unlock()
+		case <-ticker.C:
+			h.BroadcastPresence()
+		}
+	}
+}
+
+// BroadcastPresence は接続中の全オークションに viewer_count イベントを送信します
+func (h *Hub) BroadcastPresence() {
+	h.mu.Lock()
+	events := make([]PresenceEvent, 0, len(h.clients))
+	for aid, conns := range h.clients {
+		if len(conns) == 0 {
+			continue
+		}
+		events = append(events, PresenceEvent{
+			Type:      "viewer_count",
+			AuctionID: aid,
+			Presence:  h.presenceLocked(aid),
+		})
+	}
+	h.mu.Unlock()
+
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			log.Printf("WS HUB: presence marshal error: %v", err)
+			continue
 		}
+		h.Broadcast(ev.AuctionID, data)
 	}
 }
 
@@ -104,12 +196,18 @@ func (h *Hub) Register(auctionID uint, c *Client) {
 }
 
 // Unregister は hub からクライアントを解除し、チャネルを閉じます
+// Broadcast で既に切断されたクライアントのチャネルは二重に閉じません
 func (h *Hub) Unregister(auctionID uint, c *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if conns := h.clients[auctionID]; conns != nil {
-		delete(conns, c)
-		close(c.Send)
+	conns := h.clients[auctionID]
+	if conns == nil || !conns[c] {
+		return
+	}
+	delete(conns, c)
+	close(c.Send)
+	if len(conns) == 0 {
+		delete(h.clients, auctionID)
 	}
 }
 
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tokenInLink = regexp.MustCompile(`\?token=([A-Za-z0-9_-]+)`)
+
+// lastToken은 제목이 subject 인 마지막 메일의 링크에서 토큰을 꺼냅니다.
+func lastToken(t *testing.T, app *testApp, subject string) string {
+	t.Helper()
+	sent := app.Mail.SentWithSubject(subject)
+	if len(sent) == 0 {
+		t.Fatalf("%q 메일 없음", subject)
+	}
+	m := tokenInLink.FindStringSubmatch(sent[len(sent)-1].Body)
+	if m == nil {
+		t.Fatalf("메일 본문에 토큰 없음: %s", sent[len(sent)-1].Body)
+	}
+	return m[1]
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	t.Setenv("REQUIRE_EMAIL_VERIFICATION", "true")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	const verifySubject, resetSubject = "メールアドレスの確認", "パスワードの再設定"
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	auc := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, auc.ID)
+
+	// 1) 회원가입 시 인증 메일 발송, 인증 전에는 입찰 불가
+	sent := app.Mail.SentWithSubject(verifySubject)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "bidder@b.com", sent[1].To)
+		assert.Contains(t, sent[1].Body, "http://app.test/verify-email?token=")
+	}
+	first := lastToken(t, app, verifySubject)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 150}, nil))
+
+	// 2) 재발송하면 이전 링크는 무효, 새 링크는 한 번만 사용 가능
+	assert.Equal(t, http.StatusAccepted, doJSON(t, "POST", server.URL+"/users/me/verify-email", bidderToken, nil, nil))
+	second := lastToken(t, app, verifySubject)
+	assert.NotEqual(t, first, second)
+	verify := func(token string) int {
+		return doJSON(t, "POST", server.URL+"/users/verify-email", "", map[string]string{"token": token}, nil)
+	}
+	assert.Equal(t, http.StatusBadRequest, verify(first))
+	assert.Equal(t, http.StatusNoContent, verify(second))
+	assert.Equal(t, http.StatusBadRequest, verify(second))
+	assert.Equal(t, http.StatusBadRequest, verify(""))
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", server.URL+"/users/me/verify-email", bidderToken, nil, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 150}, nil))
+
+	// 3) 비밀번호 재설정: 미등록 이메일도 같은 응답, 메일은 발송하지 않음
+	forgot := func(email string) int {
+		return doJSON(t, "POST", server.URL+"/users/forgot-password", "", map[string]string{"email": email}, nil)
+	}
+	assert.Equal(t, http.StatusAccepted, forgot("nobody@b.com"))
+	assert.Empty(t, app.Mail.SentWithSubject(resetSubject))
+	var before session
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "seller@b.com", "password": "pw"}, &before))
+	assert.Equal(t, http.StatusAccepted, forgot("seller@b.com"))
+	reset := lastToken(t, app, resetSubject)
+
+	resetPassword := func(token, password string) int {
+		return doJSON(t, "POST", server.URL+"/users/reset-password", "",
+			map[string]string{"token": token, "password": password}, nil)
+	}
+	assert.Equal(t, http.StatusBadRequest, resetPassword(reset, "short"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(second, "new-password"))
+	assert.Equal(t, http.StatusNoContent, resetPassword(reset, "new-password"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(reset, "other-password"))
+
+	// 이전 비밀번호·세션은 모두 무효, 새 비밀번호로 로그인 가능
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "seller@b.com", "password": "pw"}, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "POST", server.URL+"/users/refresh", "",
+		map[string]string{"refresh_token": before.RefreshToken}, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", server.URL+"/users/me/notifications", before.Token, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "seller@b.com", "password": "new-password"}, nil))
+}
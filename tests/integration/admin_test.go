@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginAdmin은 ADMIN_EMAIL 부트스트랩과 같은 방식으로 관리자 계정을 만든 뒤 로그인하여 토큰을 반환합니다.
+func loginAdmin(t *testing.T, app *testApp, baseURL string) string {
+	t.Helper()
+	usvc := service.NewUserService(repo.NewUserRepo(app.DB), repo.NewRefreshTokenRepo(app.DB))
+	if err := usvc.EnsureAdmin("admin@b.com", "pw"); err != nil {
+		t.Fatalf("관리자 계정 생성 실패: %v", err)
+	}
+	b, _ := json.Marshal(map[string]string{"email": "admin@b.com", "password": "pw"})
+	resp, err := http.Post(baseURL+"/users/login", "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("로그인 요청 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	var res struct{ Token, Role string }
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("로그인 응답 파싱 실패: %v", err)
+	}
+	assert.Equal(t, model.RoleAdmin, res.Role)
+	return res.Token
+}
+
+func TestAdminBackOffice(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	otherBidder := signupAndLogin(t, server.URL, "bidder2@b.com", "bidder")
+	adminToken := loginAdmin(t, app, server.URL)
+
+	// 1) admin 역할은 회원가입으로 만들 수 없고, 일반 사용자는 /admin 에 접근 불가
+	b, _ := json.Marshal(map[string]string{"email": "x@b.com", "password": "pw", "role": "admin"})
+	resp, _ := http.Post(server.URL+"/users/signup", "application/json", bytes.NewReader(b))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", server.URL+"/admin/stats", "", nil, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "GET", server.URL+"/admin/stats", sellerToken, nil, nil))
+
+	// 2) 사용자 검색
+	var users struct {
+		Data       []model.User `json:"data"`
+		TotalCount int64        `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?q=bidder&role=bidder", adminToken, nil, &users))
+	assert.EqualValues(t, 2, users.TotalCount)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "GET", server.URL+"/admin/users?role=root", adminToken, nil, nil))
+	var bidder model.User
+	for _, u := range users.Data {
+		if u.Email == "bidder@b.com" {
+			bidder = u
+		}
+	}
+
+	// 3) 입찰 후 부정 입찰을 무효화 → 현재가가 유효한 최고 입찰로 돌아감
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Admin", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	auctionURL := fmt.Sprintf("%s/auctions/%d", server.URL, a.ID)
+	var first, fraud model.Bid
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/bids", otherBidder, map[string]int{"amount": 150}, &first))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/bids", bidderToken, map[string]int{"amount": 900}, &fraud))
+
+	voidURL := fmt.Sprintf("%s/admin/bids/%d/void", server.URL, fraud.ID)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", voidURL, adminToken, nil, nil))
+	var voided model.Bid
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", voidURL, adminToken, map[string]string{"reason": "shill bidding"}, &voided))
+	assert.NotNil(t, voided.VoidedAt)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", voidURL, adminToken, map[string]string{"reason": "again"}, nil))
+	var detail model.Auction
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", auctionURL, "", nil, &detail))
+	assert.Equal(t, 150, detail.CurrentPrice)
+
+	// 4) 계정 정지 → 발급된 토큰도 즉시 거부, 로그인 불가 / 재개 후 다시 이용 가능
+	userURL := fmt.Sprintf("%s/admin/users/%d", server.URL, bidder.ID)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", userURL+"/suspend", adminToken, map[string]string{"reason": "fraud"}, nil))
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", userURL+"/suspend", adminToken, map[string]string{"reason": "fraud"}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", auctionURL+"/bids", bidderToken, map[string]int{"amount": 200}, nil))
+	lb, _ := json.Marshal(map[string]string{"email": "bidder@b.com", "password": "pw"})
+	resp, _ = http.Post(server.URL+"/users/login", "application/json", bytes.NewReader(lb))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	var suspended struct {
+		TotalCount int64 `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?suspended=true", adminToken, nil, &suspended))
+	assert.EqualValues(t, 1, suspended.TotalCount)
+
+	var admins struct {
+		Data []model.User `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?role=admin", adminToken, nil, &admins))
+	adminURL := fmt.Sprintf("%s/admin/users/%d", server.URL, admins.Data[0].ID)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", adminURL+"/suspend", adminToken, map[string]string{"reason": "x"}, nil))
+
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", userURL+"/reinstate", adminToken, nil, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/bids", bidderToken, map[string]int{"amount": 200}, nil))
+
+	// 5) 경매 강제 종료 / 취소
+	var closed model.Auction
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", fmt.Sprintf("%s/admin/auctions/%d/close", server.URL, a.ID),
+		adminToken, map[string]string{"reason": "seller request"}, &closed))
+	assert.False(t, closed.EndAt.After(time.Now()))
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", fmt.Sprintf("%s/admin/auctions/%d/close", server.URL, a.ID),
+		adminToken, map[string]string{"reason": "again"}, nil))
+	assert.NotEqual(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/bids", otherBidder, map[string]int{"amount": 300}, nil))
+
+	b2 := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Stolen", "start_price": 100, "maker": "Honda", "model_name": "Fit",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	cancelURL := fmt.Sprintf("%s/admin/auctions/%d/cancel", server.URL, b2.ID)
+	var cancelled model.Auction
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", cancelURL, adminToken, map[string]string{"reason": "stolen vehicle"}, &cancelled))
+	assert.NotNil(t, cancelled.CancelledAt)
+	assert.Equal(t, "stolen vehicle", cancelled.CancelReason)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", cancelURL, adminToken, map[string]string{"reason": "again"}, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "POST", server.URL+"/admin/auctions/9999/cancel",
+		adminToken, map[string]string{"reason": "x"}, nil))
+
+	// 6) 통계
+	var stats service.AdminStats
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/stats", adminToken, nil, &stats))
+	assert.EqualValues(t, 4, stats.Users.Total)
+	assert.EqualValues(t, 2, stats.Users.ByRole[model.RoleBidder])
+	assert.EqualValues(t, 0, stats.Users.Suspended)
+	assert.EqualValues(t, 2, stats.Auctions.Total)
+	assert.EqualValues(t, 0, stats.Auctions.Live)
+	assert.EqualValues(t, 1, stats.Auctions.Cancelled)
+	assert.EqualValues(t, 3, stats.Bids.Total)
+	assert.EqualValues(t, 1, stats.Bids.Voided)
+
+	// 7) 모든 관리 조작이 감사 로그에 기록됨 (최신순, 로그인 시도 기록은 조작자 없이 남으므로 제외)
+	var logs []model.AuditLog
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET",
+		fmt.Sprintf("%s/admin/audit-logs?actor_id=%d", server.URL, admins.Data[0].ID), adminToken, nil, &logs))
+	actions := make([]string, len(logs))
+	for i, l := range logs {
+		actions[i] = l.Action
+		assert.Equal(t, admins.Data[0].ID, l.ActorID)
+		assert.NotEmpty(t, l.IP)
+	}
+	assert.Equal(t, []string{
+		service.AuditAuctionCancel, service.AuditAuctionClose, service.AuditUserReinstate,
+		service.AuditUserSuspend, service.AuditBidVoid,
+	}, actions)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET",
+		fmt.Sprintf("%s/admin/audit-logs?target_type=user&target_id=%d", server.URL, bidder.ID), adminToken, nil, &logs))
+	assert.Len(t, logs, 2)
+	assert.Equal(t, "fraud", logs[1].Reason)
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type createdAPIKey struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+func TestAPIKeys(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", server.URL+"/users/signup", "",
+		map[string]any{"email": "dealer@b.com", "password": "pw", "roles": []string{"bidder", "seller"}}, nil))
+	dealerToken := loginAs(t, server.URL, "dealer@b.com").Token
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	auction := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Test", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	keysURL := server.URL + "/users/me/api-keys"
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, auction.ID)
+
+	// 1) 검증: 이름·권한 필수, 보유하지 않은 권한·잘못된 IP·지난 만료일은 거부
+	for _, body := range []map[string]any{
+		{"name": "", "scopes": []string{"bid:place"}},
+		{"name": "DMS"},
+		{"name": "DMS", "scopes": []string{"user:manage"}},
+		{"name": "DMS", "scopes": []string{"bid:place"}, "allowed_ips": []string{"not-an-ip"}},
+		{"name": "DMS", "scopes": []string{"bid:place"}, "expires_at": time.Now().Add(-time.Hour)},
+	} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", keysURL, dealerToken, body, nil), "%v", body)
+	}
+
+	// 2) 입찰 권한만 가진 키 생성: 키 본문은 생성 시에만 반환
+	var key createdAPIKey
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", keysURL, dealerToken,
+		map[string]any{"name": "DMS", "scopes": []string{"bid:place", "bid:place"}, "allowed_ips": []string{"127.0.0.0/8"}}, &key))
+	assert.True(t, strings.HasPrefix(key.Key, key.Prefix+"_"))
+	assert.Equal(t, []string{"bid:place"}, key.Scopes)
+
+	// 3) ApiKey 헤더로 입찰 가능, 키에 없는 권한(출품)과 세션 전용 조작은 거부
+	var bid model.Bid
+	assert.Equal(t, http.StatusCreated, sendJSON(t, "POST", bidsURL, "ApiKey "+key.Key, map[string]int{"amount": 150}, &bid).StatusCode)
+	assert.Equal(t, key.UserID, bid.UserID)
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "POST", server.URL+"/auctions", "ApiKey "+key.Key, map[string]any{
+		"title": "Via key", "start_price": 100, "maker": "Honda", "model_name": "Fit", "end_at": time.Now().Add(time.Hour),
+	}, nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "GET", keysURL, "ApiKey "+key.Key, nil, nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "GET", server.URL+"/users/me/mfa", "ApiKey "+key.Key, nil, nil).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", bidsURL, "ApiKey "+key.Key+"x", map[string]int{"amount": 160}, nil).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", bidsURL, "ApiKey garbage", map[string]int{"amount": 160}, nil).StatusCode)
+
+	// 4) 목록에는 접두사와 최종 사용 시각만 표시 (키 본문 없음)
+	var list []map[string]any
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", keysURL, dealerToken, nil, &list))
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, key.Prefix, list[0]["prefix"])
+		assert.NotContains(t, list[0], "key")
+		assert.NotNil(t, list[0]["last_used_at"])
+	}
+
+	// 5) IP 허용 목록 밖에서의 사용은 거부
+	var remote createdAPIKey
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", keysURL, dealerToken,
+		map[string]any{"name": "Remote", "scopes": []string{"bid:place"}, "allowed_ips": []string{"203.0.113.10"}}, &remote))
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "POST", bidsURL, "ApiKey "+remote.Key, map[string]int{"amount": 170}, nil).StatusCode)
+
+	// 6) 만료된 키와 폐기된 키는 인증 실패
+	app.DB.Model(&model.APIKey{}).Where("id = ?", remote.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", bidsURL, "ApiKey "+remote.Key, map[string]int{"amount": 170}, nil).StatusCode)
+	revokeURL := fmt.Sprintf("%s/%d", keysURL, key.ID)
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "DELETE", revokeURL, sellerToken, nil, nil))
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", revokeURL, dealerToken, nil, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "DELETE", revokeURL, dealerToken, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", bidsURL, "ApiKey "+key.Key, map[string]int{"amount": 180}, nil).StatusCode)
+}
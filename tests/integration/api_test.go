@@ -2,11 +2,14 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,9 +17,12 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/ksj/car-auction/internal/api"
 	"github.com/ksj/car-auction/internal/config"
+	"github.com/ksj/car-auction/internal/jwtkey"
 	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
 	"github.com/ksj/car-auction/internal/repo"
 	"github.com/ksj/car-auction/internal/service"
+	"github.com/ksj/car-auction/internal/storage"
 	"github.com/ksj/car-auction/internal/ws"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -24,25 +30,102 @@ import (
 
 // mustOpenInMemoryDB는 순수 Go SQLite 드라이버로 메모리 DB를 열고
 // AutoMigrate까지 완료한 *gorm.DB를 반환합니다. 실패 시 t.Fatal로 종료.
+// 테스트끼리 데이터가 섞이지 않도록 테스트 이름별로 별도의 DB를 사용합니다.
 func mustOpenInMemoryDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	dsn := "file:" + t.Name() + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("메모리 DB 열기 실패: %v", err)
 	}
 	// 모델 순서: User → Auction → Bid
-	if err := db.AutoMigrate(&model.User{}, &model.Auction{}, &model.Bid{}); err != nil {
+	if err := db.AutoMigrate(
+		&model.User{}, &model.Auction{}, &model.Bid{},
+		&model.SavedSearch{}, &model.Notification{}, &model.AuctionPhoto{},
+		&model.ConditionReport{}, &model.PanelDamage{}, &model.Inspection{},
+		&model.AuditLog{}, &model.UserRole{}, &model.Organization{}, &model.OrgMember{}, &model.RefreshToken{},
+		&model.UserToken{}, &model.RecoveryCode{}, &model.LoginAttempt{}, &model.RateLimitBucket{}, &model.APIKey{},
+		&model.UserIdentity{}, &model.SigningKey{}, &model.DealerVerification{}, &model.VerificationDocument{},
+		&model.VehicleLock{},
+	); err != nil {
 		t.Fatalf("AutoMigrate 실패: %v", err)
 	}
 	return db
 }
 
+// captureMailer는 보낸 메일을 메모리에 기록하는 테스트용 mail.Sender 입니다.
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+type sentMail struct{ To, Subject, Body string }
+
+func (m *captureMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return nil
+}
+
+func (m *captureMailer) Sent() []sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentMail(nil), m.sent...)
+}
+
+// SentWithSubject는 제목이 subject 인 메일만 반환합니다.
+func (m *captureMailer) SentWithSubject(subject string) []sentMail {
+	var out []sentMail
+	for _, s := range m.Sent() {
+		if s.Subject == subject {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// accountSubjects는 회원가입 인증·비밀번호 재설정·이메일 변경 등 계정 관련 메일의 제목입니다.
+var accountSubjects = []string{"メールアドレスの確認", "パスワードの再設定", "メールアドレスの変更の確認", "メールアドレスの変更"}
+
+// Notifications는 계정 관련 메일을 제외한 알림 메일만 반환합니다.
+func (m *captureMailer) Notifications() []sentMail {
+	var out []sentMail
+	for _, s := range m.Sent() {
+		if !slices.Contains(accountSubjects, s.Subject) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// testApp은 테스트에서 라우터와 함께 DB, 메일 기록 등에 접근하기 위한 묶음입니다.
+type testApp struct {
+	Router *mux.Router
+	DB     *gorm.DB
+	Mail   *captureMailer
+	Signer *storage.Signer
+	Users  *service.UserService
+	Audit  *service.AuditService
+	Keys   *jwtkey.Set
+	// Limiter 의 Now 를 고정하면 실행 속도와 무관하게 레이트 리밋을 검증할 수 있습니다.
+	Limiter *api.RateLimiter
+}
+
 func setupRouter(t *testing.T) *mux.Router {
+	return setupApp(t).Router
+}
+
+func setupApp(t *testing.T) *testApp {
 	// 테스트용 env 세팅
 	os.Setenv("DISABLE_AUTH", "true")
 	// 2) config.Load() 호출 (JWT_SECRET, AUCTION_TTL 등 세팅)
 	os.Setenv("DATABASE_DSN", "file::memory:?cache=shared")
 	os.Setenv("JWT_SECRET", "test-secret")
 	os.Setenv("AUCTION_TTL_MINUTES", "60")
+	// 이메일 인증 전 입찰 차단은 기본으로 끔 (검증하는 테스트에서는 t.Setenv 로 켬)
+	if os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "" {
+		t.Setenv("REQUIRE_EMAIL_VERIFICATION", "false")
+	}
 	config.Load()
 
 	// 1) in-memory DB + AutoMigrate
@@ -50,52 +133,193 @@ func setupRouter(t *testing.T) *mux.Router {
 
 	hub := ws.NewHub()
 
+	// JWT 서명 키 (JWT_ALG 기본값 HS256, RS256/EdDSA 는 DB 에 키 저장)
+	keys, err := jwtkey.New(repo.NewSigningKeyRepo(db), jwtkey.Options{
+		Alg: config.Cfg.JwtAlg, Issuer: config.Cfg.JwtIssuer, Audience: config.Cfg.JwtAudience,
+		Secret: config.Cfg.JwtSecret, RotationInterval: config.Cfg.JwtKeyRotation, Retention: config.Cfg.AccessTokenTTL,
+	})
+	if err != nil {
+		t.Fatalf("서명 키 설정 실패: %v", err)
+	}
+	if err := keys.Maintain(time.Now()); err != nil {
+		t.Fatalf("서명 키 생성 실패: %v", err)
+	}
+	service.UseSigningKeys(keys)
+	api.UseSigningKeys(keys)
+
 	// 2) 레포 + 서비스
 	auctionRepo := repo.NewAuctionRepo(db)
+	if err := auctionRepo.Search.Migrate(); err != nil {
+		t.Fatalf("전문 검색 인덱스 생성 실패: %v", err)
+	}
 	bidRepo := repo.NewBidRepo(db)
 	userRepo := repo.NewUserRepo(db)
 
-	asvc := service.NewAuctionService(auctionRepo)
+	signer := storage.NewSigner(config.Cfg.StorageSigningKey, time.Hour)
+	store := storage.NewLocal(t.TempDir(), "/static/", signer)
+	asvc := service.NewAuctionService(auctionRepo, hub, store)
 	bsvc := service.NewBidService(bidRepo, hub)
-	usvc := service.NewUserService(userRepo)
+	usvc := service.NewUserService(userRepo, repo.NewRefreshTokenRepo(db))
+	api.UseAccountCheck(usvc.CheckAccount)
+	aksvc := service.NewAPIKeyService(repo.NewAPIKeyRepo(db), userRepo)
+	api.UseAPIKeyAuth(aksvc.Authenticate)
+
+	mailer := &captureMailer{}
+	nsvc := service.NewNotificationService(repo.NewNotificationRepo(db), userRepo, mailer)
+	acsvc := service.NewAccountService(userRepo, repo.NewUserTokenRepo(db), repo.NewRefreshTokenRepo(db),
+		mailer, "http://app.test", config.Cfg.RequireEmailVerification)
+	usvc.OnSignup(acsvc.SignupListener)
+	bsvc.AddGuard(acsvc.GuardBid)
+	msvc := service.NewMFAService(userRepo, repo.NewRecoveryCodeRepo(db), "car-auction", service.MFAPolicy{
+		BidThreshold:      config.Cfg.MFABidThreshold,
+		RequireForSelling: config.Cfg.MFARequiredForSelling,
+	})
+	usvc.UseMFA(msvc.Verify)
+	bsvc.AddGuard(msvc.GuardBid)
+	asvc.AddGuard(msvc.GuardCreateAuction)
+	ssvc := service.NewSavedSearchService(repo.NewSavedSearchRepo(db), auctionRepo, nsvc)
+	asvc.OnCreate(ssvc.MatchNewAuction)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go ssvc.Run(ctx)
+	inspectionRepo := repo.NewInspectionRepo(db)
+	reportRepo := repo.NewConditionReportRepo(db)
+	psvc := service.NewPhotoService(repo.NewPhotoRepo(db), auctionRepo, inspectionRepo, store)
+	crsvc := service.NewConditionReportService(reportRepo, auctionRepo, inspectionRepo)
+	isvc := service.NewInspectionService(inspectionRepo, auctionRepo, reportRepo, userRepo, nsvc)
+	audsvc := service.NewAuditService(repo.NewAuditRepo(db))
+	adsvc := service.NewAdminService(userRepo, auctionRepo, repo.NewStatsRepo(db), audsvc, nsvc, hub)
+	// 로그인 실패 기록은 DB 구현을 사용 (메모리 구현과 동일한 인터페이스)
+	guard := service.NewLoginGuard(repo.NewLoginAttemptRepo(db), service.LoginPolicy{
+		FreeAttempts:         config.Cfg.LoginFreeAttempts,
+		AccountLockThreshold: config.Cfg.LoginAccountLockThreshold,
+		IPLockThreshold:      config.Cfg.LoginIPLockThreshold,
+		LockoutDuration:      config.Cfg.LoginLockoutDuration,
+	}, audsvc)
+	usvc.UseLoginGuard(guard)
+	adsvc.UseLoginGuard(guard)
+	usvc.UseAudit(audsvc)
+	acsvc.UseAudit(audsvc)
+	osvc := service.NewOrganizationService(repo.NewOrganizationRepo(db), userRepo, audsvc, 0)
+	bsvc.AddGuard(osvc.GuardBid)
+	asvc.OnCreate(osvc.AttributeAuction)
+	vsvc := service.NewVerificationService(repo.NewVerificationRepo(db), userRepo, store, audsvc, nsvc, service.VerificationPolicy{
+		RequireForBidding: config.Cfg.KYCRequiredForBidding,
+		BidThreshold:      config.Cfg.KYCBidThreshold,
+	})
+	bsvc.AddGuard(vsvc.GuardBid)
+
+	// 레이트 리밋: 테스트마다 새 버킷 (DB 구현)
+	limiter := api.NewRateLimiter(repo.NewRateLimitRepo(db),
+		api.RateLimitPolicy{Name: api.RateLimitAPI, Limit: config.Cfg.RateLimitAPIPerMinute, Period: time.Minute},
+		api.RateLimitPolicy{Name: api.RateLimitAuth, Limit: config.Cfg.RateLimitAuthPerMinute, Period: time.Minute},
+		api.RateLimitPolicy{Name: api.RateLimitBids, Limit: config.Cfg.RateLimitBidsPerMinute, Period: time.Minute},
+	)
+	api.UseRateLimiter(limiter)
 
 	// 3) 라우터
 	r := mux.NewRouter()
+	r.Use(api.RateLimit(api.RateLimitAPI))
 	api.RegisterUserRoutes(r, usvc)
+	api.RegisterAccountRoutes(r, acsvc)
+	api.RegisterProfileRoutes(r, usvc)
+	api.RegisterMFARoutes(r, msvc)
+	api.RegisterAPIKeyRoutes(r, aksvc)
 	api.RegisterAuctionRoutes(r, asvc)
 	api.RegisterBidRoutes(r, bsvc)
-	return r
+	api.RegisterWSRoutes(r, hub)
+	api.RegisterSavedSearchRoutes(r, ssvc)
+	api.RegisterNotificationRoutes(r, nsvc)
+	api.RegisterPhotoRoutes(r, psvc)
+	api.RegisterConditionReportRoutes(r, crsvc)
+	api.RegisterInspectionRoutes(r, isvc)
+	api.RegisterAdminRoutes(r, adsvc)
+	api.RegisterOrganizationRoutes(r, osvc)
+	api.RegisterVerificationRoutes(r, vsvc)
+	api.RegisterJWKSRoute(r, keys)
+	r.Handle("/upload", api.AuthMiddleware(api.RequirePermission(rbac.AuctionDocument)(api.UploadHandler(psvc)))).Methods("POST")
+	r.PathPrefix("/static/").Handler(api.StaticHandler(store, signer))
+	return &testApp{Router: r, DB: db, Mail: mailer, Signer: signer, Users: usvc, Audit: audsvc, Keys: keys, Limiter: limiter}
 }
 
-func TestBidPagination(t *testing.T) {
-	router := setupRouter(t)
-	server := httptest.NewServer(router)
-	defer server.Close()
+// sendJSON은 Authorization 헤더(auth 가 비어 있으면 생략)를 붙여 JSON 요청을 보내고, out 이 주어지면 응답을 디코딩합니다.
+// 반환한 응답의 본문은 이미 닫혀 있으므로 상태 코드와 헤더만 확인할 수 있습니다.
+func sendJSON(t *testing.T, method, url, auth string, body, out any) *http.Response {
+	t.Helper()
+	var rd *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = bytes.NewReader(b)
+	} else {
+		rd = bytes.NewReader(nil)
+	}
+	req, _ := http.NewRequest(method, url, rd)
+	req.Header.Set("Content-Type", "application/json")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s 요청 실패: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		_ = json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp
+}
 
-	// 1) 회원가입
-	signupBody := map[string]string{"email": "a@b.com", "password": "pw"}
-	b, _ := json.Marshal(signupBody)
-	resp, _ := http.Post(server.URL+"/users/signup", "application/json", bytes.NewReader(b))
+// doJSON은 토큰을 Bearer 로 붙여 sendJSON 을 호출하고 상태 코드를 반환합니다.
+func doJSON(t *testing.T, method, url, token string, body, out any) int {
+	t.Helper()
+	auth := ""
+	if token != "" {
+		auth = "Bearer " + token
+	}
+	return sendJSON(t, method, url, auth, body, out).StatusCode
+}
+
+// signupAndLogin은 지정한 역할로 회원가입한 뒤 로그인하여 토큰을 반환합니다.
+func signupAndLogin(t *testing.T, baseURL, email, role string) string {
+	t.Helper()
+	b, _ := json.Marshal(map[string]string{"email": email, "password": "pw", "role": role})
+	resp, err := http.Post(baseURL+"/users/signup", "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("회원가입 요청 실패: %v", err)
+	}
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 
-	// 2) 로그인 → 토큰 획득
-	resp, _ = http.Post(server.URL+"/users/login", "application/json", bytes.NewReader(b))
+	resp, err = http.Post(baseURL+"/users/login", "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("로그인 요청 실패: %v", err)
+	}
 	var loginRes struct{ Token string }
 	if err := json.NewDecoder(resp.Body).Decode(&loginRes); err != nil {
 		t.Fatalf("로그인 응답 파싱 실패: %v", err)
 	}
-	token := loginRes.Token
+	return loginRes.Token
+}
+
+func TestBidPagination(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// 1) 회원가입 + 로그인 → 토큰 획득 (판매자 / 입찰자)
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	token := signupAndLogin(t, server.URL, "a@b.com", "bidder")
 
 	// 3) 경매 생성
 	reqA := map[string]any{
 		"title": "Test", "description": "D", "start_price": 100,
+		"maker": "Toyota", "model_name": "Prius",
 		"end_at": time.Now().Add(time.Hour),
 	}
 	bA, _ := json.Marshal(reqA)
 	rA, _ := http.NewRequest("POST", server.URL+"/auctions", bytes.NewReader(bA))
-	rA.Header.Set("Authorization", "Bearer "+token)
+	rA.Header.Set("Authorization", "Bearer "+sellerToken)
 	rA.Header.Set("Content-Type", "application/json")
-	resp, _ = http.DefaultClient.Do(rA)
+	resp, _ := http.DefaultClient.Do(rA)
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 	var aRes model.Auction
 	if err := json.NewDecoder(resp.Body).Decode(&aRes); err != nil {
@@ -130,6 +354,6 @@ func TestBidPagination(t *testing.T) {
 	assert.Equal(t, 5, pr.Size)
 	assert.Equal(t, int64(12), pr.TotalCount)
 	assert.Len(t, pr.Data, 5)
-	// 첫 페이지(1): bids[0..4], 두 번째 페이지(2): bids[5..9]
-	assert.Equal(t, 6, pr.Data[0].Amount)
+	// 최신순: 첫 페이지(1)는 112..108, 두 번째 페이지(2)는 107..103
+	assert.Equal(t, 107, pr.Data[0].Amount)
 }
@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionReport(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	otherToken := signupAndLogin(t, server.URL, "other@b.com", "seller")
+	end := time.Now().Add(time.Hour)
+	prius := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius", "start_price": 100, "maker": "Toyota", "model_name": "Prius", "end_at": end,
+	})
+	gtr := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "GT-R", "start_price": 100, "maker": "Nissan", "model_name": "GT-R", "end_at": end,
+	})
+	createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "No report", "start_price": 100, "maker": "Honda", "model_name": "Fit", "end_at": end,
+	})
+	reportURL := func(a model.Auction) string {
+		return fmt.Sprintf("%s/auctions/%d/condition-report", server.URL, a.ID)
+	}
+
+	report := map[string]any{
+		"transmission": "cvt", "fuel_type": "hybrid", "body_type": "hatchback", "drivetrain": "fwd",
+		"engine_displacement": 1800, "colour": "white", "owners": 1, "grade": "4.5",
+		"damages": []map[string]any{{"panel": "front_bumper", "code": "A1"}},
+	}
+
+	// 1) 출품자 이외는 제출 불가, 없는 경매는 404, 열거값이 잘못되거나 메모가 너무 길면 400
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "PUT", reportURL(prius), otherToken, report, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "PUT", reportURL(model.Auction{ID: 9999}), sellerToken, report, nil))
+	bad := map[string]any{"transmission": "manual", "fuel_type": "hybrid", "body_type": "hatchback", "drivetrain": "fwd", "colour": "white"}
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", reportURL(prius), sellerToken, bad, nil))
+	repaired := map[string]any{"transmission": "mt", "fuel_type": "gasoline", "body_type": "coupe", "drivetrain": "awd", "colour": "gray", "grade": "R"}
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", reportURL(gtr), sellerToken, repaired, nil))
+	longNote := map[string]any{"transmission": "cvt", "fuel_type": "hybrid", "body_type": "hatchback", "drivetrain": "fwd", "colour": "white",
+		"damages": []map[string]any{{"panel": "hood", "code": "A1", "note": strings.Repeat("傷", 256)}}}
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", reportURL(prius), sellerToken, longNote, nil))
+
+	// 2) 제출 → 재제출 시 손상 맵이 교체됨
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", reportURL(prius), sellerToken, report, nil))
+	report["damages"] = []map[string]any{{"panel": "hood", "code": "U2"}, {"panel": "roof", "code": "A2"}}
+	var saved model.ConditionReport
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", reportURL(prius), sellerToken, report, &saved))
+	repaired["engine_displacement"] = 3800
+	repaired["accident_history"] = true
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", reportURL(gtr), sellerToken, repaired, nil))
+
+	var got model.ConditionReport
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", reportURL(prius), "", nil, &got))
+	assert.Equal(t, saved.ID, got.ID)
+	if assert.Len(t, got.Damages, 2) {
+		assert.Equal(t, "hood", got.Damages[0].Panel)
+	}
+
+	// 3) 목록에서 상태표 항목으로 필터링
+	_, items := listAuctions(t, server.URL, "fuel_type=hybrid,electric")
+	assert.Equal(t, []string{"Prius"}, titles(items))
+	_, items = listAuctions(t, server.URL, "accident_history=false")
+	assert.Equal(t, []string{"Prius"}, titles(items))
+	_, items = listAuctions(t, server.URL, "displacement_min=2000&drivetrain=awd")
+	assert.Equal(t, []string{"GT-R"}, titles(items))
+	_, items = listAuctions(t, server.URL, "grade_min=4")
+	if assert.Len(t, items, 1) && assert.NotNil(t, items[0].ConditionReport) {
+		assert.Equal(t, "4.5", items[0].ConditionReport.Grade)
+	}
+	code, _ := listAuctions(t, server.URL, "body_type=spaceship")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// placeBid는 입찰자 토큰으로 입찰을 등록합니다.
+func placeBid(t *testing.T, baseURL, token string, auctionID uint, amount int) {
+	t.Helper()
+	b, _ := json.Marshal(map[string]int{"amount": amount})
+	req, _ := http.NewRequest("POST", baseURL+"/auctions/"+strconv.Itoa(int(auctionID))+"/bids", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("입찰 요청 실패: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+type bidCursorPage struct {
+	Data       []model.Bid `json:"data"`
+	Limit      int         `json:"limit"`
+	NextCursor *string     `json:"next_cursor"`
+	PrevCursor *string     `json:"prev_cursor"`
+}
+
+func getBidPage(t *testing.T, baseURL string, auctionID uint, cursor string) bidCursorPage {
+	t.Helper()
+	u := baseURL + "/auctions/" + strconv.Itoa(int(auctionID)) + "/bids?limit=3"
+	if cursor != "" {
+		u += "&cursor=" + url.QueryEscape(cursor)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatalf("입찰 목록 조회 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var pg bidCursorPage
+	_ = json.NewDecoder(resp.Body).Decode(&pg)
+	return pg
+}
+
+func amounts(bids []model.Bid) []int {
+	out := make([]int, len(bids))
+	for i, b := range bids {
+		out[i] = b.Amount
+	}
+	return out
+}
+
+func TestBidCursorPagination(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Cursor", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	for i := 1; i <= 7; i++ {
+		placeBid(t, server.URL, bidderToken, a.ID, 100+i)
+	}
+
+	// 1) 첫 페이지: 최신 3건, 이전 페이지 없음
+	p1 := getBidPage(t, server.URL, a.ID, "")
+	assert.Equal(t, []int{107, 106, 105}, amounts(p1.Data))
+	assert.Nil(t, p1.PrevCursor)
+	if !assert.NotNil(t, p1.NextCursor) {
+		t.FailNow()
+	}
+
+	// 2) 페이지 사이에 새 입찰이 들어와도 중복·누락 없이 이어짐
+	placeBid(t, server.URL, bidderToken, a.ID, 200)
+	p2 := getBidPage(t, server.URL, a.ID, *p1.NextCursor)
+	assert.Equal(t, []int{104, 103, 102}, amounts(p2.Data))
+	p3 := getBidPage(t, server.URL, a.ID, *p2.NextCursor)
+	assert.Equal(t, []int{101}, amounts(p3.Data))
+	assert.Nil(t, p3.NextCursor)
+
+	// 3) 이전 페이지로 되돌아가기
+	back := getBidPage(t, server.URL, a.ID, *p2.PrevCursor)
+	assert.Equal(t, []int{107, 106, 105}, amounts(back.Data))
+	assert.NotNil(t, back.PrevCursor) // 새로 들어온 200 이 더 앞에 있음
+
+	// 4) 변조된 커서는 400
+	resp, _ := http.Get(server.URL + "/auctions/" + strconv.Itoa(int(a.ID)) + "/bids?cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegWithOrientation은 EXIF Orientation 태그가 포함된 JPEG 이미지를 생성합니다.
+func jpegWithOrientation(w, h int, orientation byte) []byte {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil)
+	src := buf.Bytes()
+
+	// APP1(Exif) 세그먼트: 빅엔디언 TIFF 헤더 + IFD0 에 Orientation 엔트리 1개
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	seg = append(seg, payload...)
+
+	out := append([]byte{}, src[:2]...)
+	out = append(out, seg...)
+	return append(out, src[2:]...)
+}
+
+// uploadImage는 /upload 엔드포인트로 파일을 업로드합니다.
+func uploadImage(t *testing.T, url, token, filename string, data []byte) (int, map[string]any) {
+	t.Helper()
+	var res map[string]any
+	return uploadMultipart(t, url+"/upload", token, filename, data, nil, &res), res
+}
+
+func TestImagePipeline(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+
+	// 1) 이미지가 아닌 파일은 확장자와 관계없이 거부
+	code, _ := uploadImage(t, server.URL, token, "evil.png", []byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = uploadImage(t, server.URL, token, "logo.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 2) EXIF 방향이 적용되고 내용 기반 키로 저장됨 (원본 파일명은 사용하지 않음)
+	code, res := uploadImage(t, server.URL, token, "../../photo.jpg", jpegWithOrientation(40, 20, 6))
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, float64(20), res["width"])
+	assert.Equal(t, float64(40), res["height"])
+	assert.NotContains(t, res["key"], "photo")
+
+	// 3) 저장된 파일은 nosniff 헤더와 함께 재인코딩된 JPEG 로 배포되며 EXIF 는 제거됨
+	resp, err := http.Get(server.URL + res["url"].(string))
+	if err != nil {
+		t.Fatalf("정적 파일 요청 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	var served bytes.Buffer
+	_, _ = served.ReadFrom(resp.Body)
+	assert.False(t, bytes.Contains(served.Bytes(), []byte("Exif")))
+
+	// 4) 경매 사진은 반응형 썸네일 srcset 을 포함하고, 같은 사진의 중복 등록은 거부
+	a := createAuction(t, server.URL, token, map[string]any{
+		"title": "Thumbs", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	photosURL := fmt.Sprintf("%s/auctions/%d/photos", server.URL, a.ID)
+	big := testPNG(700, 400)
+	code, p := uploadPhoto(t, photosURL, token, "big.png", big, nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 700, p.Width)
+	assert.Contains(t, p.Srcset, " 320w")
+	assert.Contains(t, p.Srcset, " 640w")
+	assert.True(t, strings.HasSuffix(p.Srcset, " 700w"))
+	code, _ = uploadPhoto(t, photosURL, token, "copy.png", big, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
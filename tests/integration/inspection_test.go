@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+// statusFacets는 경매 목록의 상태 패싯을 값별 건수로 반환합니다.
+func statusFacets(t *testing.T, baseURL string) map[string]int64 {
+	t.Helper()
+	var body struct {
+		Facets struct {
+			Status []repo.FacetCount `json:"status"`
+		} `json:"facets"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", baseURL+"/auctions?facets=true", "", nil, &body))
+	m := make(map[string]int64)
+	for _, f := range body.Facets.Status {
+		m[f.Value] = f.Count
+	}
+	return m
+}
+
+func TestInspectionWorkflow(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	signupAndLogin(t, server.URL, "inspector@b.com", "bidder")
+	signupAndLogin(t, server.URL, "inspector2@b.com", "bidder")
+	inspectorToken := grantRoles(t, app, server.URL, "inspector@b.com", model.RoleInspector)
+	otherInspector := grantRoles(t, app, server.URL, "inspector2@b.com", model.RoleInspector)
+
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Inspected", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	auctionURL := fmt.Sprintf("%s/auctions/%d", server.URL, a.ID)
+	bid := func(amount int) int {
+		return doJSON(t, "POST", auctionURL+"/bids", bidderToken, map[string]int{"amount": amount}, nil)
+	}
+
+	// 1) 출품자가 검사를 의뢰 (없는 경매는 404) → 평가점 확정 전에는 입찰 불가, live 목록에서도 제외
+	var in model.Inspection
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", auctionURL+"/inspections", bidderToken, nil, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "POST", server.URL+"/auctions/9999/inspections", sellerToken, nil, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "GET", server.URL+"/auctions/9999/inspections", sellerToken, nil, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/inspections", sellerToken, nil, &in))
+	assert.Equal(t, model.InspectionRequested, in.Status)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", auctionURL+"/inspections", sellerToken, nil, nil))
+	assert.Equal(t, http.StatusConflict, bid(200))
+	_, items := listAuctions(t, server.URL, "status=live")
+	assert.Empty(t, items)
+	assert.Equal(t, map[string]int64{"live": 0, "ending_soon": 0, "ended": 0}, statusFacets(t, server.URL))
+
+	// 2) 검사원이 의뢰를 인수 → 출품자에게 알림
+	var queue []model.Inspection
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/inspections", inspectorToken, nil, &queue))
+	assert.Len(t, queue, 1)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "GET", server.URL+"/inspections", sellerToken, nil, nil))
+	inURL := fmt.Sprintf("%s/inspections/%d", server.URL, in.ID)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", inURL+"/claim", inspectorToken, nil, &in))
+	assert.Equal(t, model.InspectionAssigned, in.Status)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", inURL+"/claim", otherInspector, nil, nil))
+
+	// 3) 검사 중에는 담당 검사원만 상태표·사진을 올릴 수 있음
+	report := map[string]any{
+		"transmission": "cvt", "fuel_type": "hybrid", "body_type": "hatchback", "drivetrain": "fwd",
+		"colour": "white", "grade": "3",
+	}
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "PUT", auctionURL+"/condition-report", sellerToken, report, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "PUT", auctionURL+"/condition-report", otherInspector, report, nil))
+	code, _ := uploadPhoto(t, auctionURL+"/photos", inspectorToken, "engine.png", testPNG(20, 20), nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	// 4) 상태표 없이 확정 불가 → 상태표 제출 후 평가점 확정
+	signOff := map[string]any{"grade": "4.5", "notes": "外装良好"}
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", inURL+"/complete", inspectorToken, signOff, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", auctionURL+"/condition-report", inspectorToken, report, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", inURL+"/complete", otherInspector, signOff, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", inURL+"/complete", inspectorToken, signOff, &in))
+	assert.Equal(t, model.InspectionCompleted, in.Status)
+
+	// 5) 확정 후: 상태표의 평가점이 확정값으로 고정되고 입찰 가능
+	var got model.ConditionReport
+	doJSON(t, "GET", auctionURL+"/condition-report", "", nil, &got)
+	assert.Equal(t, "4.5", got.Grade)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "PUT", auctionURL+"/condition-report", sellerToken, report, nil))
+	assert.Equal(t, http.StatusCreated, bid(200))
+	assert.Equal(t, map[string]int64{"live": 1, "ending_soon": 1, "ended": 0}, statusFacets(t, server.URL))
+
+	// 6) 출품자는 검사 상태를 확인할 수 있고, 인수·완료 알림을 받음
+	var history []model.Inspection
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", auctionURL+"/inspections", sellerToken, nil, &history))
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "4.5", history[0].Grade)
+	}
+	var notes []model.Notification
+	doJSON(t, "GET", server.URL+"/users/me/notifications", sellerToken, nil, &notes)
+	kinds := []string{}
+	for _, n := range notes {
+		kinds = append(kinds, n.Kind)
+	}
+	assert.ElementsMatch(t, []string{"inspection_assigned", "inspection_completed"}, kinds)
+}
+
+func TestInspectionCancel(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Cancel", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	auctionURL := fmt.Sprintf("%s/auctions/%d", server.URL, a.ID)
+
+	var in model.Inspection
+	doJSON(t, "POST", auctionURL+"/inspections", sellerToken, nil, &in)
+	inURL := fmt.Sprintf("%s/inspections/%d", server.URL, in.ID)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", inURL+"/cancel", sellerToken, nil, &in))
+	assert.Equal(t, model.InspectionCancelled, in.Status)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", inURL+"/cancel", sellerToken, nil, nil))
+
+	// 취소 후에는 검사 없이 입찰 가능
+	placeBid(t, server.URL, bidderToken, a.ID, 200)
+}
+
+func TestInspectionRejectsSelfInspection(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// 관리자가 검사원 역할도 부여한 출품자
+	signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	sellerToken := grantRoles(t, app, server.URL, "seller@b.com", model.RoleSeller, model.RoleInspector)
+	signupAndLogin(t, server.URL, "inspector@b.com", "bidder")
+	inspectorToken := grantRoles(t, app, server.URL, "inspector@b.com", model.RoleInspector)
+	var me model.User
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/users/me", sellerToken, nil, &me))
+
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Self", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	auctionURL := fmt.Sprintf("%s/auctions/%d", server.URL, a.ID)
+
+	// 1) 자기 자신을 검사원으로 지정할 수 없음
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", auctionURL+"/inspections", sellerToken,
+		map[string]uint{"inspector_id": me.ID}, nil))
+
+	// 2) 자기 경매의 의뢰를 인수할 수 없음, 다른 검사원은 인수 가능
+	var in model.Inspection
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", auctionURL+"/inspections", sellerToken, nil, &in))
+	inURL := fmt.Sprintf("%s/inspections/%d", server.URL, in.ID)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", inURL+"/claim", sellerToken, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", inURL+"/claim", inspectorToken, nil, &in))
+	assert.Equal(t, model.InspectionAssigned, in.Status)
+}
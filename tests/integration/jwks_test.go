@@ -0,0 +1,220 @@
+package integration
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ksj/car-auction/internal/jwtkey"
+	"github.com/ksj/car-auction/internal/kdf"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fetchJWKS는 /.well-known/jwks.json 의 공개 키를 kid 별로 반환합니다.
+func fetchJWKS(t *testing.T, baseURL string) map[string]jwtkey.JWK {
+	t.Helper()
+	var set struct {
+		Keys []jwtkey.JWK `json:"keys"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", baseURL+"/.well-known/jwks.json", "", nil, &set))
+	out := map[string]jwtkey.JWK{}
+	for _, k := range set.Keys {
+		out[k.Kid] = k
+	}
+	return out
+}
+
+// verifyWithJWKS는 다른 서비스처럼 JWKS 의 공개 키만으로 토큰을 검증하고 kid 를 반환합니다.
+func verifyWithJWKS(t *testing.T, keys map[string]jwtkey.JWK, raw string) (string, error) {
+	t.Helper()
+	var kid string
+	_, err := jwt.Parse(raw, func(tok *jwt.Token) (any, error) {
+		kid, _ = tok.Header["kid"].(string)
+		k := keys[kid]
+		switch k.Kty {
+		case "RSA":
+			n, _ := base64.RawURLEncoding.DecodeString(k.N)
+			e, _ := base64.RawURLEncoding.DecodeString(k.E)
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+		case "OKP":
+			x, _ := base64.RawURLEncoding.DecodeString(k.X)
+			return ed25519.PublicKey(x), nil
+		}
+		return nil, jwtkey.ErrNoSigningKey
+	}, jwt.WithValidMethods([]string{"RS256", "EdDSA"}), jwt.WithIssuer("car-auction"), jwt.WithAudience("car-auction-api"))
+	return kid, err
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	t.Setenv("JWT_ALG", "RS256")
+	t.Setenv("JWT_KEY_ROTATION_HOURS", "1")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	meURL := server.URL + "/users/me/notifications"
+	setActivation := func(kid string, at time.Time) {
+		app.DB.Model(&model.SigningKey{}).Where("kid = ?", kid).Update("activates_at", at)
+	}
+
+	// 1) 첫 키로 서명, 다른 서비스는 JWKS 로 검증 가능
+	oldToken := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+	keys := fetchJWKS(t, server.URL)
+	assert.Len(t, keys, 1)
+	oldKid, err := verifyWithJWKS(t, keys, oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "RSA", keys[oldKid].Kty)
+
+	// 2) 교체 시기가 되면 다음 키를 먼저 JWKS 에 공개하고, 사용 시작 전까지는 기존 키로 서명
+	now := time.Now()
+	setActivation(oldKid, now.Add(-2*time.Hour))
+	assert.NoError(t, app.Keys.Maintain(now))
+	keys = fetchJWKS(t, server.URL)
+	assert.Len(t, keys, 2)
+	kid, err := verifyWithJWKS(t, keys, loginAs(t, server.URL, "a@b.com").Token)
+	assert.NoError(t, err)
+	assert.Equal(t, oldKid, kid)
+
+	// 3) 사용 시작 후에는 새 키로 서명, 기존 키의 토큰도 만료 전까지 유효
+	var newKid string
+	for k := range keys {
+		if k != oldKid {
+			newKid = k
+		}
+	}
+	setActivation(newKid, now.Add(-time.Second))
+	assert.NoError(t, app.Keys.Maintain(now))
+	newToken := loginAs(t, server.URL, "a@b.com").Token
+	kid, err = verifyWithJWKS(t, keys, newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, newKid, kid)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, oldToken, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, newToken, nil, nil))
+
+	// 4) 교체 후 보존 기간(액세스 토큰 유효 기간)이 지나면 기존 키는 삭제되어 그 토큰은 거부
+	setActivation(newKid, now.Add(-30*time.Minute))
+	assert.NoError(t, app.Keys.Maintain(now))
+	keys = fetchJWKS(t, server.URL)
+	assert.Len(t, keys, 1)
+	assert.Contains(t, keys, newKid)
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, oldToken, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, newToken, nil, nil))
+}
+
+func TestJWTStrictValidation(t *testing.T) {
+	claims := func(userID uint, extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"user_id": userID, "roles": []string{"bidder"}, "iss": "car-auction", "aud": "car-auction-api",
+			"exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+	hs256 := func(c jwt.MapClaims) string {
+		s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("test-secret"))
+		return s
+	}
+
+	t.Run("HS256", func(t *testing.T) {
+		app := setupApp(t)
+		server := httptest.NewServer(app.Router)
+		defer server.Close()
+		signupAndLogin(t, server.URL, "a@b.com", "bidder")
+		var u model.User
+		app.DB.Where("email = ?", "a@b.com").First(&u)
+		meURL := server.URL + "/users/me/notifications"
+
+		assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, hs256(claims(u.ID, nil)), nil, nil))
+		// iss / aud 불일치, exp 누락, alg=none 은 거부
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, hs256(claims(u.ID, jwt.MapClaims{"iss": "other"})), nil, nil))
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, hs256(claims(u.ID, jwt.MapClaims{"aud": "other-api"})), nil, nil))
+		noExp := claims(u.ID, nil)
+		delete(noExp, "exp")
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, hs256(noExp), nil, nil))
+		none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims(u.ID, nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, none, nil, nil))
+		// HS256 에서는 JWKS 로 공개할 키가 없음
+		assert.Empty(t, fetchJWKS(t, server.URL))
+	})
+
+	t.Run("EdDSA", func(t *testing.T) {
+		t.Setenv("JWT_ALG", "EdDSA")
+		app := setupApp(t)
+		server := httptest.NewServer(app.Router)
+		defer server.Close()
+		token := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+		var u model.User
+		app.DB.Where("email = ?", "a@b.com").First(&u)
+		meURL := server.URL + "/users/me/notifications"
+
+		keys := fetchJWKS(t, server.URL)
+		kid, err := verifyWithJWKS(t, keys, token)
+		assert.NoError(t, err)
+		assert.Equal(t, "Ed25519", keys[kid].Crv)
+		assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, token, nil, nil))
+
+		// 설정과 다른 알고리즘은 서명이 올바르더라도 거부 (JWT_SECRET 의 HS256, 공개 키를 HMAC 키로 쓴 위조)
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, hs256(claims(u.ID, nil)), nil, nil))
+		x, _ := base64.RawURLEncoding.DecodeString(keys[kid].X)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(u.ID, nil))
+		forged.Header["kid"] = kid
+		forgedRaw, _ := forged.SignedString(x)
+		assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, forgedRaw, nil, nil))
+	})
+}
+
+func TestJWTKeySealing(t *testing.T) {
+	secret := []byte("test-secret")
+	opts := jwtkey.Options{Alg: jwtkey.AlgEdDSA, Issuer: "car-auction", Audience: "car-auction-api", Secret: secret, Retention: time.Hour}
+	openWith := func(key, sealed []byte) error {
+		block, _ := aes.NewCipher(key)
+		gcm, _ := cipher.NewGCM(block)
+		_, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+		return err
+	}
+	legacy := sha256.Sum256(append([]byte("jwt-signing-key:"), secret...))
+
+	// 1) 새 키는 JWT_SECRET 에서 용도별로 HKDF 파생한 키로 암호화 (이전 방식의 키로는 복호화 불가)
+	store := jwtkey.NewMemoryStore()
+	set, err := jwtkey.New(store, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, set.Maintain(time.Now()))
+	stored, _ := store.List()
+	if assert.Len(t, stored, 1) {
+		sealKey, _ := kdf.Key(secret, "car-auction jwt private key sealing")
+		assert.NoError(t, openWith(sealKey, stored[0].PrivateKey))
+		assert.Error(t, openWith(legacy[:], stored[0].PrivateKey))
+	}
+
+	// 2) 이전 방식으로 암호화해 저장한 키도 계속 읽어서 서명에 사용
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	block, _ := aes.NewCipher(legacy[:])
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = rand.Read(nonce)
+	old := jwtkey.NewMemoryStore()
+	assert.NoError(t, old.Create(&model.SigningKey{KID: "legacy", Alg: jwtkey.AlgEdDSA,
+		PrivateKey: gcm.Seal(nonce, nonce, der, nil), ActivatesAt: time.Now().Add(-time.Hour)}))
+	set, err = jwtkey.New(old, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, set.Maintain(time.Now()))
+	token, err := set.Sign(jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Hour).Unix()})
+	if assert.NoError(t, err) {
+		parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.Equal(t, "legacy", parsed.Header["kid"])
+		_, err = set.Parse(token)
+		assert.NoError(t, err)
+	}
+}
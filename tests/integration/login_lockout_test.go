@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLockout(t *testing.T) {
+	// 2회까지는 바로 재시도 가능, 계정은 4회, IP 는 7회 연속 실패로 잠금
+	t.Setenv("LOGIN_FREE_ATTEMPTS", "2")
+	t.Setenv("LOGIN_ACCOUNT_LOCK_THRESHOLD", "4")
+	t.Setenv("LOGIN_IP_LOCK_THRESHOLD", "7")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	adminToken := loginAdmin(t, app, server.URL)
+	signupAndLogin(t, server.URL, "victim@b.com", "bidder")
+	signupAndLogin(t, server.URL, "other@b.com", "bidder")
+	var users struct {
+		Data []model.User `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?q=victim", adminToken, nil, &users))
+	victimID := users.Data[0].ID
+
+	// login은 로그인 요청의 상태 코드와 Retry-After 헤더(초)를 반환합니다.
+	login := func(email, password string) (int, int) {
+		b, _ := json.Marshal(map[string]string{"email": email, "password": password})
+		resp, err := http.Post(server.URL+"/users/login", "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("로그인 요청 실패: %v", err)
+		}
+		defer resp.Body.Close()
+		retry, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return resp.StatusCode, retry
+	}
+
+	// 1) 허용 횟수를 넘긴 실패부터 대기 시간이 생기고, 대기 중에는 올바른 비밀번호도 거부
+	for i := 0; i < 3; i++ {
+		status, _ := login("victim@b.com", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+	status, retry := login("victim@b.com", "pw")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, 1, retry)
+
+	// 2) 대기 후 다시 실패하면 임계값에 도달하여 잠금
+	time.Sleep(1100 * time.Millisecond)
+	status, _ = login("victim@b.com", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, retry = login("victim@b.com", "pw")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Greater(t, retry, 60)
+
+	// 3) 관리자가 잠금을 해제하면 로그인 가능 (감사 로그 기록)
+	unlockURL := fmt.Sprintf("%s/admin/users/%d/unlock", server.URL, victimID)
+	otherToken := loginAs(t, server.URL, "other@b.com").Token
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", unlockURL, otherToken, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", unlockURL, adminToken, map[string]string{"reason": "verified by phone"}, nil))
+	status, _ = login("victim@b.com", "pw")
+	assert.Equal(t, http.StatusOK, status)
+
+	var logs []model.AuditLog
+	auditURL := func(action string) string {
+		return fmt.Sprintf("%s/admin/audit-logs?action=%s&target_type=%s&target_id=%d",
+			server.URL, action, service.AuditTargetLogin, victimID)
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", auditURL(service.AuditLoginFailed), adminToken, nil, &logs))
+	if assert.Len(t, logs, 4) {
+		assert.Equal(t, "password", logs[0].Reason)
+		assert.NotEmpty(t, logs[0].IP)
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", auditURL(service.AuditLoginSucceeded), adminToken, nil, &logs))
+	assert.Len(t, logs, 2)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/audit-logs?action="+service.AuditLoginBlocked, adminToken, nil, &logs))
+	assert.Len(t, logs, 2)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/audit-logs?action="+service.AuditUserUnlock, adminToken, nil, &logs))
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, victimID, logs[0].TargetID)
+		assert.Equal(t, "verified by phone", logs[0].Reason)
+	}
+
+	// 4) 같은 IP 에서 여러 계정으로 실패가 이어지면 IP 단위로 잠금 (존재하지 않는 계정도 집계)
+	for i := 0; i < 3; i++ {
+		status, _ := login("ghost@b.com", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+	status, retry = login("other@b.com", "pw")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Greater(t, retry, 60)
+}
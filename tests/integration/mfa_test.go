@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+type mfaLogin struct {
+	session
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+func TestTOTPTwoFactorAuthentication(t *testing.T) {
+	t.Setenv("MFA_BID_THRESHOLD", "1000")
+	t.Setenv("MFA_REQUIRED_FOR_SELLING", "true")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	newAuction := map[string]any{
+		"title": "Prius", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	}
+
+	// 1) 정책: 2FA 없이는 출품 불가
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", server.URL+"/auctions", sellerToken, newAuction, nil))
+
+	// 2) 등록: 공유 키와 QR 용 URI → 인증 앱의 코드로 활성화
+	mfaURL := server.URL + "/users/me/mfa"
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", mfaURL+"/totp/activate", sellerToken, map[string]string{"code": "123456"}, nil))
+	var enroll struct{ Secret, URI string }
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", mfaURL+"/totp", sellerToken, nil, &enroll))
+	assert.True(t, strings.HasPrefix(enroll.URI, "otpauth://totp/car-auction:seller@b.com?"), enroll.URI)
+	assert.Contains(t, enroll.URI, "secret="+enroll.Secret)
+
+	step := totp.Step(time.Now())
+	code := func(s int64) string {
+		c, err := totp.Code(enroll.Secret, s)
+		if err != nil {
+			t.Fatalf("코드 생성 실패: %v", err)
+		}
+		return c
+	}
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", mfaURL+"/totp/activate", sellerToken, map[string]string{"code": code(step + 5)}, nil))
+	var activated struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", mfaURL+"/totp/activate", sellerToken, map[string]string{"code": code(step)}, &activated))
+	assert.Len(t, activated.RecoveryCodes, 10)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", mfaURL+"/totp", sellerToken, nil, nil))
+
+	// 3) 2FA 활성화 후에는 출품 가능
+	auc := createAuction(t, server.URL, sellerToken, newAuction)
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, auc.ID)
+
+	// 기준 금액 이상의 입찰은 2FA 필요
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 999}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 1000}, nil))
+
+	// 4) 2단계 로그인: 비밀번호 확인 후 mfa_token 발급, 코드 확인 후 토큰 발급
+	login := func() mfaLogin {
+		var res mfaLogin
+		assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+			map[string]string{"email": "seller@b.com", "password": "pw"}, &res))
+		return res
+	}
+	loginMFA := func(mfaToken, c string, out *session) int {
+		return doJSON(t, "POST", server.URL+"/users/login/mfa", "", map[string]string{"mfa_token": mfaToken, "code": c}, out)
+	}
+	pending := login()
+	assert.True(t, pending.MFARequired)
+	assert.Empty(t, pending.Token)
+	assert.NotEmpty(t, pending.MFAToken)
+	// mfa_token 으로는 API 를 이용할 수 없음
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", mfaURL, pending.MFAToken, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, loginMFA("bogus", code(step+1), nil))
+	// 활성화에 사용한 코드는 재사용 불가
+	assert.Equal(t, http.StatusUnauthorized, loginMFA(pending.MFAToken, code(step), nil))
+	var full session
+	assert.Equal(t, http.StatusOK, loginMFA(pending.MFAToken, code(step+1), &full))
+	assert.NotEmpty(t, full.Token)
+	assert.Equal(t, http.StatusUnauthorized, loginMFA(pending.MFAToken, code(step+1), nil))
+
+	// 5) 복구 코드는 한 번만 사용 가능 (구분자·대소문자 무시)
+	recovery := activated.RecoveryCodes[0]
+	assert.Equal(t, http.StatusOK, loginMFA(login().MFAToken, strings.ToUpper(strings.ReplaceAll(recovery, "-", "")), nil))
+	assert.Equal(t, http.StatusUnauthorized, loginMFA(login().MFAToken, recovery, nil))
+	var status struct {
+		Enabled                bool  `json:"enabled"`
+		RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", mfaURL, full.Token, nil, &status))
+	assert.True(t, status.Enabled)
+	assert.EqualValues(t, 9, status.RecoveryCodesRemaining)
+
+	// 6) 비활성화하면 비밀번호만으로 로그인
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "DELETE", mfaURL+"/totp", full.Token, map[string]string{"code": "000000"}, nil))
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", mfaURL+"/totp", full.Token, map[string]string{"code": activated.RecoveryCodes[1]}, nil))
+	plain := login()
+	assert.False(t, plain.MFARequired)
+	assert.NotEmpty(t, plain.Token)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", server.URL+"/auctions", plain.Token, newAuction, nil))
+}
@@ -0,0 +1,250 @@
+package integration
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ksj/car-auction/internal/api"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/oidc"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockIdP는 테스트 프로세스 안에서 동작하는 OIDC 공급자입니다.
+// /authorize 는 로그인 화면 없이 Next 의 클레임으로 즉시 승인하고, /token 은 PKCE 를 검증한 뒤 RS256 ID 토큰을 발급합니다.
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	Next   jwt.MapClaims
+	grants map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge, nonce, redirect string
+	claims                     jwt.MapClaims
+}
+
+const (
+	mockClientID     = "car-auction"
+	mockClientSecret = "s3cret"
+)
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("RSA 키 생성 실패: %v", err)
+	}
+	idp := &mockIdP{key: key, grants: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != mockClientID || q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+			http.Error(w, "bad authorize request", http.StatusBadRequest)
+			return
+		}
+		code, _ := oidc.RandomString()
+		idp.mu.Lock()
+		idp.grants[code] = mockGrant{q.Get("code_challenge"), q.Get("nonce"), q.Get("redirect_uri"), idp.Next}
+		idp.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id, secret, _ := r.BasicAuth()
+		idp.mu.Lock()
+		g, ok := idp.grants[r.FormValue("code")]
+		delete(idp.grants, r.FormValue("code"))
+		idp.mu.Unlock()
+		if id != mockClientID || secret != mockClientSecret || !ok ||
+			oidc.Challenge(r.FormValue("code_verifier")) != g.challenge || r.FormValue("redirect_uri") != g.redirect {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{"iss": idp.URL, "aud": mockClientID, "nonce": g.nonce,
+			"iat": time.Now().Unix(), "exp": time.Now().Add(5 * time.Minute).Unix()}
+		for k, v := range g.claims {
+			claims[k] = v
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "k1"
+		signed, _ := tok.SignedString(idp.key)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := idp.key.PublicKey
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// setupOIDC는 mockIdP 를 사용하는 OIDC 로그인 라우트를 등록합니다.
+func setupOIDC(t *testing.T, app *testApp, idp *mockIdP, serverURL string, autoProvision bool) {
+	client := oidc.New(oidc.Config{
+		Issuer: idp.URL, ClientID: mockClientID, ClientSecret: mockClientSecret,
+		RedirectURL: serverURL + "/auth/oidc/callback", Scopes: []string{"openid", "email", "groups"},
+	}, nil)
+	roleMap, err := service.ParseOIDCRoleMap("auction-sellers=seller, auction-buyers=bidder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewOIDCService(client, repo.NewUserIdentityRepo(app.DB), app.Users, app.Audit, service.OIDCPolicy{
+		RoleClaim: "groups", RoleMap: roleMap, AutoProvision: autoProvision, DefaultRole: model.RoleBidder,
+	})
+	api.RegisterOIDCRoutes(app.Router, svc)
+}
+
+// oidcLogin은 쿠키를 유지하는 브라우저처럼 로그인을 시작하고, 프런트엔드(app.test)로 돌아오기 직전의 응답을 반환합니다.
+// 성공 시 프래그먼트의 값을 함께 반환합니다.
+func oidcLogin(t *testing.T, serverURL string, claims jwt.MapClaims, idp *mockIdP) (*http.Response, url.Values) {
+	t.Helper()
+	idp.mu.Lock()
+	idp.Next = claims
+	idp.mu.Unlock()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+		if req.URL.Host == "app.test" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}}
+	resp, err := client.Get(serverURL + "/auth/oidc/login")
+	if err != nil {
+		t.Fatalf("OIDC 로그인 요청 실패: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return resp, nil
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	frag, _ := url.ParseQuery(loc.Fragment)
+	return resp, frag
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Setenv("APP_URL", "http://app.test")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	idp := newMockIdP(t)
+	setupOIDC(t, app, idp, server.URL, true)
+
+	// 1) 처음 로그인한 IdP 계정은 자동 생성: groups 로 역할 매핑, 메일 인증 완료 상태
+	resp, frag := oidcLogin(t, server.URL, jwt.MapClaims{
+		"sub": "emp-1", "email": "taro@corp.example", "email_verified": true, "groups": []string{"auction-sellers", "staff"},
+	}, idp)
+	if assert.Equal(t, http.StatusFound, resp.StatusCode) {
+		assert.Contains(t, resp.Header.Get("Location"), "http://app.test/oidc/callback#")
+		assert.NotEmpty(t, frag.Get("refresh_token"))
+		assert.Equal(t, "900", frag.Get("expires_in"))
+	}
+	var u model.User
+	app.DB.Preload("Roles").Where("email = ?", "taro@corp.example").First(&u)
+	assert.Equal(t, []string{model.RoleSeller}, u.RoleNames())
+	assert.NotNil(t, u.EmailVerifiedAt)
+	// 발급된 토큰은 기존 앱 JWT 이므로 그대로 출품 가능
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", server.URL+"/auctions", frag.Get("token"), map[string]any{
+		"title": "Via IdP", "start_price": 100, "maker": "Toyota", "model_name": "Prius", "end_at": time.Now().Add(time.Hour),
+	}, nil))
+
+	// 2) 같은 sub 로 다시 로그인하면 같은 사용자, IdP 측 소속이 바뀌면 역할도 교체
+	_, frag = oidcLogin(t, server.URL, jwt.MapClaims{
+		"sub": "emp-1", "email": "taro@corp.example", "email_verified": true, "groups": "auction-buyers",
+	}, idp)
+	assert.NotEmpty(t, frag.Get("token"))
+	var roles []model.UserRole
+	app.DB.Where("user_id = ?", u.ID).Find(&roles)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, model.RoleBidder, roles[0].Role)
+	}
+	var identities int64
+	app.DB.Model(&model.UserIdentity{}).Where("user_id = ?", u.ID).Count(&identities)
+	assert.Equal(t, int64(1), identities)
+
+	// 3) 기존 비밀번호 계정은 IdP 가 인증한 같은 이메일이면 연결, 미인증 이메일은 거부
+	signupAndLogin(t, server.URL, "dealer@b.com", "bidder")
+	var dealer model.User
+	app.DB.Where("email = ?", "dealer@b.com").First(&dealer)
+	resp, _ = oidcLogin(t, server.URL, jwt.MapClaims{"sub": "emp-2", "email": "dealer@b.com", "email_verified": false}, idp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_, frag = oidcLogin(t, server.URL, jwt.MapClaims{"sub": "emp-2", "email": "dealer@b.com", "email_verified": true}, idp)
+	assert.NotEmpty(t, frag.Get("token"))
+	var link model.UserIdentity
+	app.DB.Where("subject = ?", "emp-2").First(&link)
+	assert.Equal(t, dealer.ID, link.UserID)
+
+	// 4) 정지된 계정은 IdP 로그인도 거부
+	app.DB.Model(&model.User{}).Where("id = ?", dealer.ID).Update("suspended_at", time.Now())
+	resp, _ = oidcLogin(t, server.URL, jwt.MapClaims{"sub": "emp-2", "email": "dealer@b.com", "email_verified": true}, idp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 5) state 쿠키가 없거나 state 가 다르면 400
+	resp, err := http.Get(server.URL + "/auth/oidc/callback?state=x&code=y")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+	jar, _ := cookiejar.New(nil)
+	stopAtIdP := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = stopAtIdP.Get(server.URL + "/auth/oidc/login")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		authURL, _ := url.Parse(resp.Header.Get("Location"))
+		assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+		resp, err = stopAtIdP.Get(server.URL + "/auth/oidc/callback?state=forged&code=y")
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+
+	// 6) 감사 로그에 IdP 계정 생성·연결 기록
+	var actions []string
+	app.DB.Model(&model.AuditLog{}).Where("action LIKE ?", "auth.identity_%").Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{service.AuditIdentityProvisioned, service.AuditIdentityLinked}, actions)
+}
+
+func TestOIDCLoginWithoutAutoProvision(t *testing.T) {
+	t.Setenv("APP_URL", "http://app.test")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	idp := newMockIdP(t)
+	setupOIDC(t, app, idp, server.URL, false)
+
+	// 자동 생성이 꺼져 있으면 로컬 계정이 없는 IdP 계정은 403
+	resp, _ := oidcLogin(t, server.URL, jwt.MapClaims{"sub": "emp-9", "email": "nobody@corp.example", "email_verified": true}, idp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	var n int64
+	app.DB.Model(&model.User{}).Where("email = ?", "nobody@corp.example").Count(&n)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+type orgDetail struct {
+	model.Organization
+	Exposure        int  `json:"exposure"`
+	AvailableCredit *int `json:"available_credit"`
+}
+
+// memberID는 조직 상세에서 이메일로 멤버의 사용자 ID를 찾습니다.
+func memberID(t *testing.T, org orgDetail, email string) uint {
+	t.Helper()
+	for _, m := range org.Members {
+		if m.User != nil && m.User.Email == email {
+			return m.UserID
+		}
+	}
+	t.Fatalf("멤버 %s 없음", email)
+	return 0
+}
+
+func TestDealerOrganization(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// owner 는 출품과 입찰을 모두 하는 딜러
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", server.URL+"/users/signup", "",
+		map[string]any{"email": "owner@dealer.com", "password": "pw", "roles": []string{"bidder", "seller"}}, nil))
+	ownerToken := loginAs(t, server.URL, "owner@dealer.com").Token
+	buyerToken := signupAndLogin(t, server.URL, "buyer@dealer.com", "bidder")
+	viewerToken := signupAndLogin(t, server.URL, "viewer@dealer.com", "bidder")
+	outsiderToken := signupAndLogin(t, server.URL, "outsider@b.com", "bidder")
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+
+	// 1) 조직 생성 → 생성자가 owner
+	var org orgDetail
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", server.URL+"/orgs", ownerToken, map[string]string{"name": "ACME Motors"}, &org))
+	assert.Equal(t, "ACME Motors", org.Name)
+	assert.Len(t, org.Members, 1)
+	assert.Equal(t, model.OrgRoleOwner, org.Members[0].Role)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", server.URL+"/orgs", outsiderToken, map[string]string{"name": "ACME Motors"}, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "GET", server.URL+"/orgs/me", outsiderToken, nil, nil))
+
+	// 2) 멤버 추가: owner 만 가능, 잘못된 역할은 거부
+	members := server.URL + "/orgs/me/members"
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", members, ownerToken, map[string]string{"email": "buyer@dealer.com", "role": "buyer"}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", members, ownerToken, map[string]string{"email": "nobody@dealer.com", "role": "buyer"}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", members, ownerToken, map[string]string{"email": "viewer@dealer.com", "role": "root"}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", members, buyerToken, map[string]string{"email": "viewer@dealer.com", "role": "viewer"}, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", members, ownerToken, map[string]string{"email": "viewer@dealer.com", "role": "viewer"}, &org))
+	assert.Len(t, org.Members, 3)
+	// 이미 조직에 소속된 사용자는 다른 조직을 만들 수 없음
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", server.URL+"/orgs", buyerToken, map[string]string{"name": "Other"}, nil))
+
+	// 3) 조직 멤버의 출품은 조직에 귀속되고, 같은 조직 멤버는 입찰할 수 없음
+	own := createAuction(t, server.URL, ownerToken, map[string]any{
+		"title": "Dealer stock", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	var orgAuctions struct {
+		Data       []model.Auction `json:"data"`
+		TotalCount int64           `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/orgs/me/auctions", viewerToken, nil, &orgAuctions))
+	assert.EqualValues(t, 1, orgAuctions.TotalCount)
+	assert.Equal(t, own.ID, orgAuctions.Data[0].ID)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", fmt.Sprintf("%s/auctions/%d/bids", server.URL, own.ID), buyerToken, map[string]int{"amount": 150}, nil))
+
+	// 4) 외부 경매: viewer 는 입찰 불가, 같은 조직 멤버끼리 경쟁 불가
+	other := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Private sale", "start_price": 100, "maker": "Honda", "model_name": "Fit",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, other.ID)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, viewerToken, map[string]int{"amount": 150}, nil))
+	var bid model.Bid
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, buyerToken, map[string]int{"amount": 150}, &bid))
+	if assert.NotNil(t, bid.OrgID) {
+		assert.Equal(t, org.ID, *bid.OrgID)
+	}
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", bidsURL, ownerToken, map[string]int{"amount": 200}, nil))
+	// 본인의 입찰 갱신과 외부인의 입찰은 허용
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, buyerToken, map[string]int{"amount": 200}, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, outsiderToken, map[string]int{"amount": 300}, nil))
+	// 외부인이 최고가가 되면 조직의 다른 멤버도 다시 입찰 가능
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", bidsURL, ownerToken, map[string]int{"amount": 400}, nil))
+
+	// 5) 관리자가 여신 한도 설정 (감사 로그 기록) → 한도를 넘는 입찰은 거부
+	adminToken := loginAdmin(t, app, server.URL)
+	creditURL := fmt.Sprintf("%s/admin/orgs/%d/credit-limit", server.URL, org.ID)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "PUT", creditURL, ownerToken, map[string]any{"credit_limit": 1000}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", creditURL, adminToken, map[string]any{"credit_limit": -1}, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", creditURL, adminToken, map[string]any{"credit_limit": 1000, "reason": "credit review"}, &org))
+	assert.Equal(t, 1000, org.CreditLimit)
+	assert.Equal(t, 400, org.Exposure)
+	if assert.NotNil(t, org.AvailableCredit) {
+		assert.Equal(t, 600, *org.AvailableCredit)
+	}
+	var logs []model.AuditLog
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/audit-logs?action="+service.AuditOrgCreditLimit, adminToken, nil, &logs))
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, org.ID, logs[0].TargetID)
+		assert.Equal(t, "credit review", logs[0].Reason)
+	}
+
+	third := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Trade-in", "start_price": 100, "maker": "Mazda", "model_name": "CX-5",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	thirdBids := fmt.Sprintf("%s/auctions/%d/bids", server.URL, third.ID)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", thirdBids, buyerToken, map[string]int{"amount": 700}, nil))
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", thirdBids, buyerToken, map[string]int{"amount": 500}, nil))
+	// 같은 경매에서 자신의 최고가를 올리는 경우 기존 금액은 이중으로 계산하지 않음 (400 + 600 = 1000)
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", thirdBids, buyerToken, map[string]int{"amount": 600}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", thirdBids, buyerToken, map[string]int{"amount": 601}, nil))
+
+	var orgBids struct {
+		TotalCount int64 `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/orgs/me/bids", viewerToken, nil, &orgBids))
+	assert.EqualValues(t, 5, orgBids.TotalCount)
+
+	// 6) 역할 변경과 멤버 제거: 마지막 owner 는 제거할 수 없음
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/orgs/me", ownerToken, nil, &org))
+	ownerID := memberID(t, org, "owner@dealer.com")
+	viewerID := memberID(t, org, "viewer@dealer.com")
+	assert.Equal(t, http.StatusConflict, doJSON(t, "DELETE", fmt.Sprintf("%s/%d", members, ownerID), ownerToken, nil, nil))
+	assert.Equal(t, http.StatusConflict, doJSON(t, "PUT", fmt.Sprintf("%s/%d", members, ownerID), ownerToken, map[string]string{"role": "buyer"}, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", fmt.Sprintf("%s/%d", members, viewerID), ownerToken, map[string]string{"role": "buyer"}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "DELETE", fmt.Sprintf("%s/%d", members, viewerID), buyerToken, nil, nil))
+	// 멤버는 스스로 탈퇴할 수 있음
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", fmt.Sprintf("%s/%d", members, viewerID), viewerToken, nil, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "GET", server.URL+"/orgs/me", viewerToken, nil, nil))
+}
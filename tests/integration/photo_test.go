@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPNG는 지정한 크기의 단색 PNG 이미지를 생성합니다.
+func testPNG(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// uploadMultipart는 multipart 로 파일을 업로드하고 응답 본문을 out 에 디코딩합니다.
+func uploadMultipart(t *testing.T, url, token, filename string, data []byte, fields map[string]string, out any) int {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	_, _ = fw.Write(data)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("업로드 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	_ = json.NewDecoder(resp.Body).Decode(out)
+	return resp.StatusCode
+}
+
+// uploadPhoto는 multipart 로 경매 사진을 업로드합니다.
+func uploadPhoto(t *testing.T, url, token, filename string, data []byte, fields map[string]string) (int, model.AuctionPhoto) {
+	t.Helper()
+	var p model.AuctionPhoto
+	return uploadMultipart(t, url, token, filename, data, fields, &p), p
+}
+
+func TestAuctionPhotoGallery(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	otherToken := signupAndLogin(t, server.URL, "other@b.com", "seller")
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Gallery", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	photosURL := fmt.Sprintf("%s/auctions/%d/photos", server.URL, a.ID)
+
+	// 1) 판매자만 사진을 추가할 수 있고, 첫 사진이 커버가 됨
+	code, p1 := uploadPhoto(t, photosURL, sellerToken, "front.png", testPNG(40, 30), map[string]string{"caption": "正面"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, p1.IsCover)
+	_, p2 := uploadPhoto(t, photosURL, sellerToken, "rear.png", testPNG(41, 30), nil)
+	_, p3 := uploadPhoto(t, photosURL, sellerToken, "inside.png", testPNG(42, 30), map[string]string{"is_cover": "true"})
+	code, _ = uploadPhoto(t, photosURL, otherToken, "x.png", testPNG(10, 10), nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = uploadPhoto(t, server.URL+"/auctions/9999/photos", sellerToken, "x.png", testPNG(10, 10), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 2) 순서 변경
+	var photos []model.AuctionPhoto
+	code = doJSON(t, "PUT", photosURL+"/order", sellerToken, map[string]any{"photo_ids": []uint{p3.ID, p1.ID, p2.ID}}, &photos)
+	assert.Equal(t, http.StatusOK, code)
+	code = doJSON(t, "PUT", photosURL+"/order", sellerToken, map[string]any{"photo_ids": []uint{p3.ID, p1.ID}}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 3) 커버 사진 삭제 → 다음 사진이 커버가 됨
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", fmt.Sprintf("%s/%d", photosURL, p3.ID), sellerToken, nil, nil))
+
+	// 4) 경매 상세에 정렬된 갤러리가 포함됨
+	var detail service.AuctionDetail
+	doJSON(t, "GET", fmt.Sprintf("%s/auctions/%d", server.URL, a.ID), "", nil, &detail)
+	if assert.Len(t, detail.Photos, 2) {
+		assert.Equal(t, []uint{p1.ID, p2.ID}, []uint{detail.Photos[0].ID, detail.Photos[1].ID})
+		assert.True(t, detail.Photos[0].IsCover)
+		assert.Equal(t, "正面", detail.Photos[0].Caption)
+		assert.Equal(t, detail.Photos[0].URL, detail.PhotoURL)
+	}
+}
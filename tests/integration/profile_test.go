@@ -0,0 +1,192 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// profile은 GET /users/me 응답 중 테스트에서 확인하는 필드입니다.
+type profile struct {
+	ID                  uint     `json:"id"`
+	Email               string   `json:"email"`
+	DisplayName         string   `json:"display_name"`
+	Phone               string   `json:"phone"`
+	Company             string   `json:"company"`
+	Language            string   `json:"language"`
+	Currency            string   `json:"currency"`
+	Roles               []string `json:"roles"`
+	Permissions         []string `json:"permissions"`
+	EmailVerified       bool     `json:"email_verified"`
+	DeletionRequestedAt *string  `json:"deletion_requested_at"`
+}
+
+// auditActions는 사용자 userID 에 대한 user.* 감사 로그의 action 을 기록 순으로 반환합니다.
+func auditActions(app *testApp, userID uint) []string {
+	var actions []string
+	app.DB.Model(&model.AuditLog{}).Where("target_type = ? AND target_id = ? AND action LIKE ?", "user", userID, "user.%").
+		Order("id").Pluck("action", &actions)
+	return actions
+}
+
+func TestProfile(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	meURL := server.URL + "/users/me"
+	token := signupAndLogin(t, server.URL, "a@b.com", "seller")
+
+	// 1) 로그인한 사용자 정보: 기본 언어·통화와 역할·권한
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", meURL, "", nil, nil))
+	var me profile
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", meURL, token, nil, &me))
+	assert.Equal(t, "a@b.com", me.Email)
+	assert.Equal(t, "ja", me.Language)
+	assert.Equal(t, "JPY", me.Currency)
+	assert.Equal(t, []string{"seller"}, me.Roles)
+	assert.NotEmpty(t, me.Permissions)
+
+	// 2) 지정한 항목만 변경, 잘못된 값은 400
+	assert.Equal(t, http.StatusOK, doJSON(t, "PATCH", meURL, token, map[string]string{
+		"display_name": " 山田 ", "phone": "+81 90-1234-5678", "company": "山田自動車", "currency": "usd",
+	}, &me))
+	assert.Equal(t, "山田", me.DisplayName)
+	assert.Equal(t, "+81 90-1234-5678", me.Phone)
+	assert.Equal(t, "USD", me.Currency)
+	assert.Equal(t, "ja", me.Language)
+	assert.Equal(t, http.StatusOK, doJSON(t, "PATCH", meURL, token, map[string]string{"company": ""}, &me))
+	assert.Empty(t, me.Company)
+	assert.Equal(t, "山田", me.DisplayName)
+	for _, bad := range []map[string]string{
+		{"language": "fr"}, {"currency": "BTC"}, {"phone": "abc"}, {"phone": "123"},
+		{"display_name": string(make([]rune, 51))},
+	} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, "PATCH", meURL, token, bad, nil), "%v", bad)
+	}
+
+	// 3) 감사 로그에는 변경한 항목 이름만 기록
+	var log model.AuditLog
+	app.DB.Where("action = ?", "user.profile_update").Order("id").First(&log)
+	assert.JSONEq(t, `{"fields":["company","currency","display_name","phone"]}`, log.Detail)
+	assert.Equal(t, []string{"user.profile_update", "user.profile_update"}, auditActions(app, me.ID))
+}
+
+func TestChangePassword(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	pwURL := server.URL + "/users/me/password"
+	token := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+	other := loginAs(t, server.URL, "a@b.com").Token
+
+	// 1) 현재 비밀번호 불일치는 403, 짧은 비밀번호는 400
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", pwURL, token,
+		map[string]string{"current_password": "wrong", "password": "new-password"}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", pwURL, token,
+		map[string]string{"current_password": "pw", "password": "short"}, nil))
+
+	// 2) 변경하면 새 토큰을 받고, 다른 단말의 토큰은 무효
+	var res struct {
+		Token string `json:"token"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", pwURL, token,
+		map[string]string{"current_password": "pw", "password": "new-password"}, &res))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/users/me", res.Token, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", server.URL+"/users/me", token, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", server.URL+"/users/me", other, nil, nil))
+
+	// 3) 새 비밀번호로만 로그인 가능
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "a@b.com", "password": "pw"}, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "a@b.com", "password": "new-password"}, nil))
+	var u model.User
+	app.DB.Where("email = ?", "a@b.com").First(&u)
+	assert.Equal(t, []string{"user.password_change"}, auditActions(app, u.ID))
+}
+
+func TestChangeEmail(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	const confirmSubject = "メールアドレスの変更の確認"
+	emailURL := server.URL + "/users/me/email"
+	token := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+	signupAndLogin(t, server.URL, "taken@b.com", "bidder")
+
+	// 1) 비밀번호 불일치·형식 오류·사용 중인 주소는 거부
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", emailURL, token, map[string]string{"password": "x", "email": "new@b.com"}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", emailURL, token, map[string]string{"password": "pw", "email": "Bob <new@b.com>"}, nil))
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", emailURL, token, map[string]string{"password": "pw", "email": "taken@b.com"}, nil))
+
+	// 2) 새 주소로 확인 메일, 기존 주소로 알림 메일. 확인 전까지는 변경되지 않음
+	assert.Equal(t, http.StatusAccepted, doJSON(t, "POST", emailURL, token, map[string]string{"password": "pw", "email": "new@b.com"}, nil))
+	if sent := app.Mail.SentWithSubject(confirmSubject); assert.Len(t, sent, 1) {
+		assert.Equal(t, "new@b.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "http://app.test/confirm-email?token=")
+	}
+	if sent := app.Mail.SentWithSubject("メールアドレスの変更"); assert.Len(t, sent, 1) {
+		assert.Equal(t, "a@b.com", sent[0].To)
+	}
+	assert.Empty(t, app.Mail.Notifications())
+	var me profile
+	doJSON(t, "GET", server.URL+"/users/me", token, nil, &me)
+	assert.Equal(t, "a@b.com", me.Email)
+	assert.False(t, me.EmailVerified)
+
+	// 3) 확인하면 새 주소로 변경되고 확인 완료, 토큰은 한 번만 사용 가능
+	raw := lastToken(t, app, confirmSubject)
+	confirmURL := server.URL + "/users/confirm-email"
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "POST", confirmURL, "", map[string]string{"token": raw}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", confirmURL, "", map[string]string{"token": raw}, nil))
+	doJSON(t, "GET", server.URL+"/users/me", token, nil, &me)
+	assert.Equal(t, "new@b.com", me.Email)
+	assert.True(t, me.EmailVerified)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "new@b.com", "password": "pw"}, nil))
+
+	// 4) 신청 후 다른 사용자가 같은 주소를 등록했다면 확인 시 409
+	assert.Equal(t, http.StatusAccepted, doJSON(t, "POST", emailURL, token, map[string]string{"password": "pw", "email": "race@b.com"}, nil))
+	signupAndLogin(t, server.URL, "race@b.com", "bidder")
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", confirmURL, "",
+		map[string]string{"token": lastToken(t, app, confirmSubject)}, nil))
+	assert.Equal(t, []string{"user.email_change_request", "user.email_change", "user.email_change_request"}, auditActions(app, me.ID))
+}
+
+func TestAccountDeletionRequest(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	adminToken := loginAdmin(t, app, server.URL)
+	delURL := server.URL + "/users/me/deletion"
+	token := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+
+	// 1) 비밀번호 확인 후 신청, 중복 신청은 409
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", delURL, token, map[string]string{"password": "x"}, nil))
+	var me profile
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", delURL, token, map[string]string{"password": "pw", "reason": "no longer needed"}, &me))
+	assert.NotNil(t, me.DeletionRequestedAt)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", delURL, token, map[string]string{"password": "pw"}, nil))
+
+	// 2) 관리자는 삭제 신청한 사용자를 검색 가능
+	var users struct {
+		Data []model.User `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?deletion_requested=true", adminToken, nil, &users))
+	if assert.Len(t, users.Data, 1) {
+		assert.Equal(t, "a@b.com", users.Data[0].Email)
+		assert.Equal(t, "no longer needed", users.Data[0].DeletionReason)
+	}
+
+	// 3) 본인이 취소하면 검색 대상에서 제외, 신청이 없으면 409
+	var cancelled profile
+	assert.Equal(t, http.StatusOK, doJSON(t, "DELETE", delURL, token, nil, &cancelled))
+	assert.Nil(t, cancelled.DeletionRequestedAt)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "DELETE", delURL, token, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?deletion_requested=true", adminToken, nil, &users))
+	assert.Empty(t, users.Data)
+	assert.Equal(t, []string{"user.deletion_request", "user.deletion_cancel"}, auditActions(app, me.ID))
+}
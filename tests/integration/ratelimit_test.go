@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	// 입찰은 사용자별 분당 3회, 계정 조작은 IP 별 분당 7회
+	t.Setenv("RATE_LIMIT_BIDS_PER_MINUTE", "3")
+	t.Setenv("RATE_LIMIT_AUTH_PER_MINUTE", "7")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	// 시계를 고정해 느린 환경(-race 등)에서도 토큰이 보충되지 않도록 함
+	now := time.Now()
+	app.Limiter.Now = func() time.Time { return now }
+
+	// 회원가입 + 로그인 3명 = 계정 조작 6회
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+	otherToken := signupAndLogin(t, server.URL, "c@b.com", "bidder")
+	auction := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Test", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+
+	// 1) API 전체 제한은 모든 응답에 RateLimit-* 헤더로 표시
+	resp := sendJSON(t, "GET", server.URL+"/auctions", "", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "600", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "600;w=60", resp.Header.Get("RateLimit-Policy"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Remaining"))
+
+	// 2) 입찰은 사용자별 버킷: 3회까지 허용 후 429 + Retry-After
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, auction.ID)
+	for i := 1; i <= 3; i++ {
+		resp = sendJSON(t, "POST", bidsURL, "Bearer "+bidderToken, map[string]int{"amount": 100 + i}, nil)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "3", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(3-i), resp.Header.Get("RateLimit-Remaining"))
+	}
+	resp = sendJSON(t, "POST", bidsURL, "Bearer "+bidderToken, map[string]int{"amount": 110}, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retry, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+	assert.InDelta(t, 20, retry, 1)
+	// 다른 사용자는 영향 없음
+	resp = sendJSON(t, "POST", bidsURL, "Bearer "+otherToken, map[string]int{"amount": 120}, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// 3) 계정 조작은 IP 별 버킷: 7회째까지 허용
+	login := map[string]string{"email": "a@b.com", "password": "pw"}
+	assert.Equal(t, http.StatusOK, sendJSON(t, "POST", server.URL+"/users/login", "", login, nil).StatusCode)
+	resp = sendJSON(t, "POST", server.URL+"/users/login", "", login, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	// 공개 조회는 계정 조작 제한과 무관
+	assert.Equal(t, http.StatusOK, sendJSON(t, "GET", server.URL+"/auctions", "", nil, nil).StatusCode)
+}
+
+func TestRateLimitMemoryStore(t *testing.T) {
+	now := time.Now()
+	limiter := api.NewRateLimiter(api.NewMemoryRateLimitStore(),
+		api.RateLimitPolicy{Name: api.RateLimitAPI, Limit: 2, Period: time.Second})
+	limiter.Now = func() time.Time { return now }
+	api.UseRateLimiter(limiter)
+	t.Cleanup(func() { api.UseRateLimiter(nil) })
+	h := api.RateLimit(api.RateLimitAPI)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	// 초당 2회: 연속 2회 후 거부, 0.5초 뒤 토큰 1개 보충
+	assert.Equal(t, http.StatusNoContent, sendJSON(t, "GET", server.URL, "", nil, nil).StatusCode)
+	assert.Equal(t, http.StatusNoContent, sendJSON(t, "GET", server.URL, "", nil, nil).StatusCode)
+	resp := sendJSON(t, "GET", server.URL, "", nil, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	now = now.Add(550 * time.Millisecond)
+	assert.Equal(t, http.StatusNoContent, sendJSON(t, "GET", server.URL, "", nil, nil).StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, sendJSON(t, "GET", server.URL, "", nil, nil).StatusCode)
+}
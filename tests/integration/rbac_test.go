@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/rbac"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginAs는 로그인 응답(토큰, 역할, 권한)을 그대로 반환합니다.
+func loginAs(t *testing.T, baseURL, email string) (res struct {
+	Token       string   `json:"token"`
+	Role        string   `json:"role"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}) {
+	t.Helper()
+	b, _ := json.Marshal(map[string]string{"email": email, "password": "pw"})
+	resp, err := http.Post(baseURL+"/users/login", "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("로그인 요청 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("로그인 응답 파싱 실패: %v", err)
+	}
+	return res
+}
+
+// grantRoles는 관리자 API 로 email 사용자의 역할을 roles 로 바꾼 뒤 다시 로그인한 토큰을 반환합니다.
+// 검사원처럼 가입 시 직접 선택할 수 없는 역할을 테스트에서 부여할 때 사용합니다.
+func grantRoles(t *testing.T, app *testApp, baseURL, email string, roles ...string) string {
+	t.Helper()
+	adminToken := loginAdmin(t, app, baseURL)
+	var found struct {
+		Data []model.User `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", baseURL+"/admin/users?q="+email, adminToken, nil, &found))
+	if len(found.Data) != 1 {
+		t.Fatalf("사용자를 찾을 수 없음: %s", email)
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", fmt.Sprintf("%s/admin/users/%d/roles", baseURL, found.Data[0].ID),
+		adminToken, map[string]any{"roles": roles, "reason": "test"}, nil))
+	return loginAs(t, baseURL, email).Token
+}
+
+func TestMultipleRolesAndPermissions(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// 1) 한 계정으로 구매와 판매를 모두 하는 딜러
+	b, _ := json.Marshal(map[string]any{"email": "dealer@b.com", "password": "pw", "roles": []string{"bidder", "seller"}})
+	resp, _ := http.Post(server.URL+"/users/signup", "application/json", bytes.NewReader(b))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	dealer := loginAs(t, server.URL, "dealer@b.com")
+	assert.Equal(t, model.RoleBidder, dealer.Role)
+	assert.ElementsMatch(t, []string{model.RoleBidder, model.RoleSeller}, dealer.Roles)
+	assert.Contains(t, dealer.Permissions, rbac.AuctionCreate)
+	assert.Contains(t, dealer.Permissions, rbac.BidPlace)
+	assert.NotContains(t, dealer.Permissions, rbac.UserManage)
+
+	// 알 수 없는 역할이나 admin, 검사원은 가입 시 지정할 수 없음
+	for _, roles := range [][]string{{"bidder", "root"}, {"seller", "admin"}, {"inspector"}, {"seller", "inspector"}, {}} {
+		b, _ := json.Marshal(map[string]any{"email": "x@b.com", "password": "pw", "roles": roles})
+		resp, _ := http.Post(server.URL+"/users/signup", "application/json", bytes.NewReader(b))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "roles=%v", roles)
+	}
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	signupAndLogin(t, server.URL, "inspector@b.com", "seller")
+	inspectorToken := grantRoles(t, app, server.URL, "inspector@b.com", model.RoleInspector)
+
+	// 2) 딜러는 출품도 하고 다른 사람의 경매에 입찰도 할 수 있음
+	mine := createAuction(t, server.URL, dealer.Token, map[string]any{
+		"title": "Dealer stock", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	assert.NotZero(t, mine.ID)
+	other := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Private sale", "start_price": 100, "maker": "Honda", "model_name": "Fit",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	otherURL := fmt.Sprintf("%s/auctions/%d", server.URL, other.ID)
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", otherURL+"/bids", dealer.Token, map[string]int{"amount": 150}, nil))
+
+	// 권한이 없는 역할은 거부
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", otherURL+"/bids", sellerToken, map[string]int{"amount": 200}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", otherURL+"/bids", inspectorToken, map[string]int{"amount": 200}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "PUT", otherURL, inspectorToken, map[string]any{"title": "x"}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "GET", server.URL+"/admin/users", dealer.Token, nil, nil))
+	// 모든 역할에 공통인 권한
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/users/me/notifications", inspectorToken, nil, nil))
+
+	// 3) role 클레임만 있는 기존 형식의 토큰도 계속 사용 가능
+	var seller struct {
+		Data []model.User `json:"data"`
+	}
+	adminToken := loginAdmin(t, app, server.URL)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?q=seller@", adminToken, nil, &seller))
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": seller.Data[0].ID, "role": "seller", "exp": time.Now().Add(time.Hour).Unix(),
+		"iss": "car-auction", "aud": "car-auction-api",
+	}).SignedString([]byte("test-secret"))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", otherURL+"/bids", legacy, map[string]int{"amount": 200}, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", otherURL, legacy, map[string]any{
+		"title": "Private sale", "start_price": 100, "end_at": time.Now().Add(time.Hour),
+	}, nil))
+
+	// 4) 관리자가 역할을 변경 → 다시 로그인하면 새 권한이 토큰에 반영
+	rolesURL := fmt.Sprintf("%s/admin/users/%d/roles", server.URL, seller.Data[0].ID)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", rolesURL, adminToken, map[string]any{"roles": []string{"root"}}, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", rolesURL, adminToken, map[string]any{"roles": []string{}}, nil))
+	var updated model.User
+	assert.Equal(t, http.StatusOK, doJSON(t, "PUT", rolesURL, adminToken,
+		map[string]any{"roles": []string{"seller", "bidder"}, "reason": "dealer upgrade"}, &updated))
+	assert.ElementsMatch(t, []string{model.RoleSeller, model.RoleBidder}, updated.RoleNames())
+	relogin := loginAs(t, server.URL, "seller@b.com")
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", otherURL+"/bids", relogin.Token, map[string]int{"amount": 200}, nil))
+
+	var bidders struct {
+		TotalCount int64 `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?role=bidder", adminToken, nil, &bidders))
+	assert.EqualValues(t, 2, bidders.TotalCount)
+
+	// 관리자는 자신의 admin 역할을 제거할 수 없음
+	var admins struct {
+		Data []model.User `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/admin/users?role=admin", adminToken, nil, &admins))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "PUT", fmt.Sprintf("%s/admin/users/%d/roles", server.URL, admins.Data[0].ID),
+		adminToken, map[string]any{"roles": []string{"bidder"}}, nil))
+}
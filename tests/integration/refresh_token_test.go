@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type session struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func TestRefreshTokenRotationAndLogout(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	notifications := server.URL + "/users/me/notifications"
+	refresh := func(raw string, out *session) int {
+		return doJSON(t, "POST", server.URL+"/users/refresh", "", map[string]string{"refresh_token": raw}, out)
+	}
+
+	// 1) 회원가입·로그인 시 액세스 토큰과 리프레시 토큰 발급
+	var signed session
+	assert.Equal(t, http.StatusCreated, doJSON(t, "POST", server.URL+"/users/signup", "",
+		map[string]string{"email": "bidder@b.com", "password": "pw", "role": "bidder"}, &signed))
+	assert.NotEmpty(t, signed.Token)
+	assert.NotEmpty(t, signed.RefreshToken)
+	assert.Equal(t, 15*60, signed.ExpiresIn)
+
+	var first session
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "bidder@b.com", "password": "pw"}, &first))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", notifications, first.Token, nil, nil))
+
+	// 2) 재발급 시 리프레시 토큰도 교체됨
+	var second session
+	assert.Equal(t, http.StatusOK, refresh(first.RefreshToken, &second))
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", notifications, second.Token, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh("unknown", nil))
+	assert.Equal(t, http.StatusBadRequest, refresh("", nil))
+
+	// 3) 사용된 리프레시 토큰 재사용 → 같은 로그인의 토큰 전부 폐기 + 발급된 액세스 토큰 무효화
+	assert.Equal(t, http.StatusUnauthorized, refresh(first.RefreshToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh(second.RefreshToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", notifications, second.Token, nil, nil))
+	// 다른 로그인(회원가입 시 발급)의 리프레시 토큰은 계속 사용 가능
+	var other session
+	assert.Equal(t, http.StatusOK, refresh(signed.RefreshToken, &other))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", notifications, other.Token, nil, nil))
+
+	// 4) 로그아웃: 해당 로그인의 리프레시 토큰만 폐기
+	var phone session
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "bidder@b.com", "password": "pw"}, &phone))
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "POST", server.URL+"/users/logout", "",
+		map[string]any{"refresh_token": phone.RefreshToken}, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh(phone.RefreshToken, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", notifications, other.Token, nil, nil))
+
+	// 5) 전체 로그아웃: 모든 리프레시 토큰과 발급된 액세스 토큰 무효화
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "POST", server.URL+"/users/logout", "",
+		map[string]any{"refresh_token": other.RefreshToken, "all": true}, nil))
+	assert.Equal(t, http.StatusUnauthorized, doJSON(t, "GET", notifications, other.Token, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh(other.RefreshToken, nil))
+
+	// 다시 로그인하면 새 세대의 토큰으로 이용 가능
+	var again session
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", server.URL+"/users/login", "",
+		map[string]string{"email": "bidder@b.com", "password": "pw"}, &again))
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", notifications, again.Token, nil, nil))
+}
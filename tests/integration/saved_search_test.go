@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedSearchAlerts(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	dealerToken := signupAndLogin(t, server.URL, "dealer@b.com", "bidder")
+	base := server.URL + "/users/me/saved-searches"
+
+	// 1) "Prius 2018–2020, 60,000km 이하" 저장 (메일 알림 포함)
+	var ss service.SavedSearchView
+	code := doJSON(t, "POST", base, dealerToken, map[string]any{
+		"name":         "Prius 2018-2020",
+		"filter":       map[string]any{"model_names": []string{"Prius"}, "year_min": 2018, "year_max": 2020, "mileage_max": 60000},
+		"notify_email": true,
+	}, &ss)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 2018, ss.Filter.YearMin)
+
+	// 잘못된 조건은 저장할 수 없음
+	code = doJSON(t, "POST", base, dealerToken, map[string]any{
+		"name": "bad", "filter": map[string]any{"year_min": 2021, "year_max": 2018},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 2) 일치하는 매물 / 일치하지 않는 매물 등록
+	end := time.Now().Add(time.Hour)
+	match := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius S", "maker": "Toyota", "model_name": "Prius", "year": 2019, "mileage": 45000, "start_price": 100, "end_at": end,
+	})
+	createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius old", "maker": "Toyota", "model_name": "Prius", "year": 2012, "mileage": 45000, "start_price": 100, "end_at": end,
+	})
+
+	// 조회·발송은 백그라운드에서 처리되므로 일치한 매물의 알림이 도착할 때까지 대기
+	var notes []model.Notification
+	unread := func() int {
+		doJSON(t, "GET", server.URL+"/users/me/notifications?unread=true", dealerToken, nil, &notes)
+		return len(notes)
+	}
+	assert.Eventually(t, func() bool { return unread() > 0 }, 2*time.Second, 10*time.Millisecond)
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, "saved_search_match", notes[0].Kind)
+		assert.Equal(t, match.ID, *notes[0].AuctionID)
+	}
+	if sent := app.Mail.Notifications(); assert.Len(t, sent, 1) {
+		assert.Equal(t, "dealer@b.com", sent[0].To)
+	}
+
+	// 3) 읽음 처리
+	code = doJSON(t, "POST", server.URL+"/users/me/notifications/"+strconv.Itoa(int(notes[0].ID))+"/read", dealerToken, nil, nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	doJSON(t, "GET", server.URL+"/users/me/notifications?unread=true", dealerToken, nil, &notes)
+	assert.Len(t, notes, 0)
+
+	// 마지막 일치 시각만 갱신되고, 사용자가 저장한 내용은 그대로 유지
+	var list []service.SavedSearchView
+	doJSON(t, "GET", base, dealerToken, nil, &list)
+	if assert.Len(t, list, 1) {
+		assert.NotNil(t, list[0].LastMatchedAt)
+		assert.Equal(t, "Prius 2018-2020", list[0].Name)
+		assert.True(t, list[0].NotifyEmail)
+	}
+
+	// 4) 일시정지 중에는 알림 없음, 다른 사용자는 수정 불가
+	id := strconv.Itoa(int(ss.ID))
+	code = doJSON(t, "POST", base+"/"+id+"/pause", dealerToken, nil, &ss)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, ss.Paused)
+	createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius T", "maker": "Toyota", "model_name": "Prius", "year": 2020, "mileage": 1000, "start_price": 100, "end_at": end,
+	})
+	// 출품은 순서대로 처리되므로, 뒤이어 등록한 매물이 다른 사용자에게 알림된 뒤에 확인
+	otherToken := signupAndLogin(t, server.URL, "other@b.com", "bidder")
+	code = doJSON(t, "POST", base, otherToken, map[string]any{"name": "Fit", "filter": map[string]any{"model_names": []string{"Fit"}}}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+	createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Fit", "maker": "Honda", "model_name": "Fit", "year": 2020, "mileage": 1000, "start_price": 100, "end_at": end,
+	})
+	assert.Eventually(t, func() bool {
+		var other []model.Notification
+		doJSON(t, "GET", server.URL+"/users/me/notifications?unread=true", otherToken, nil, &other)
+		return len(other) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, unread())
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "DELETE", base+"/"+id, sellerToken, nil, nil))
+
+	// 5) 목록, 삭제
+	doJSON(t, "GET", base, dealerToken, nil, &list)
+	assert.Len(t, list, 1)
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", base+"/"+id, dealerToken, nil, nil))
+}
@@ -0,0 +1,194 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+// listAuctions는 GET /auctions 를 호출해 상태 코드와 결과 목록을 반환합니다.
+func listAuctions(t *testing.T, baseURL, query string) (int, []model.Auction) {
+	t.Helper()
+	resp, err := http.Get(baseURL + "/auctions?" + query)
+	if err != nil {
+		t.Fatalf("목록 조회 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	var pr struct {
+		Data       []model.Auction `json:"data"`
+		TotalCount int64           `json:"total_count"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&pr)
+	return resp.StatusCode, pr.Data
+}
+
+func titles(items []model.Auction) []string {
+	out := make([]string, len(items))
+	for i, a := range items {
+		out[i] = a.Title
+	}
+	return out
+}
+
+func TestAuctionVehicleSearch(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	now := time.Now()
+	cars := []map[string]any{
+		{"title": "Prius A", "maker": "Toyota", "model_name": "Prius", "year": 2018, "mileage": 55000, "start_price": 900000, "end_at": now.Add(30 * time.Minute)},
+		{"title": "Prius B", "maker": "Toyota", "model_name": "Prius", "year": 2021, "mileage": 20000, "start_price": 1500000, "end_at": now.Add(48 * time.Hour)},
+		{"title": "Fit", "maker": "Honda", "model_name": "Fit", "year": 2019, "mileage": 40000, "start_price": 700000, "end_at": now.Add(-time.Hour)},
+	}
+	for _, c := range cars {
+		createAuction(t, server.URL, token, c)
+	}
+
+	// 1) 제조사 + 연식 범위 + 주행거리 상한
+	code, items := listAuctions(t, server.URL, "maker=Toyota&year_min=2018&year_max=2020&mileage_max=60000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Prius A"}, titles(items))
+
+	// 2) 상태 필터
+	_, items = listAuctions(t, server.URL, "status=ending_soon")
+	assert.Equal(t, []string{"Prius A"}, titles(items))
+	_, items = listAuctions(t, server.URL, "status=ended")
+	assert.Equal(t, []string{"Fit"}, titles(items))
+
+	// 3) 가격 범위 + 다중 정렬
+	_, items = listAuctions(t, server.URL, "price_max=1000000&sort=-price")
+	assert.Equal(t, []string{"Prius A", "Fit"}, titles(items))
+	_, items = listAuctions(t, server.URL, "sort=mileage")
+	assert.Equal(t, []string{"Prius B", "Fit", "Prius A"}, titles(items))
+	_, items = listAuctions(t, server.URL, "maker=Toyota,Honda&sort=end_at")
+	assert.Equal(t, []string{"Fit", "Prius A", "Prius B"}, titles(items))
+
+	// 4) 커서 페이징에서도 필터가 적용됨
+	_, items = listAuctions(t, server.URL, "maker=Toyota&limit=1")
+	assert.Equal(t, []string{"Prius B"}, titles(items))
+
+	// 5) 잘못된 필터는 400
+	for _, q := range []string{"year_min=abc", "year_min=2021&year_max=2018", "status=sold", "sort=color", "limit=2&sort=price"} {
+		code, _ = listAuctions(t, server.URL, q)
+		assert.Equal(t, http.StatusBadRequest, code, q)
+	}
+}
+
+func TestAuctionPriceFollowsHighestBid(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderA := signupAndLogin(t, server.URL, "a@b.com", "bidder")
+	bidderB := signupAndLogin(t, server.URL, "b@b.com", "bidder")
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius", "maker": "Toyota", "model_name": "Prius", "start_price": 100, "end_at": time.Now().Add(time.Hour),
+	})
+
+	// 가격 필터·정렬은 입찰이 반영된 현재가(최고 입찰액)를 기준으로 함
+	_, items := listAuctions(t, server.URL, "price_min=400")
+	assert.Empty(t, items)
+	placeBid(t, server.URL, bidderA, a.ID, 500)
+	_, items = listAuctions(t, server.URL, "price_min=400")
+	assert.Equal(t, []string{"Prius"}, titles(items))
+
+	// 최고가보다 낮은 입찰이 들어와도 현재가는 내려가지 않음 (입찰 규칙은 그대로)
+	placeBid(t, server.URL, bidderB, a.ID, 300)
+	_, items = listAuctions(t, server.URL, "price_min=400&price_max=500")
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, 500, items[0].CurrentPrice)
+	}
+}
+
+func TestAuctionFullTextSearch(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	end := time.Now().Add(time.Hour)
+	for _, c := range []map[string]any{
+		{"title": "トヨタ ランドクルーザー 4WD", "description": "通称ランクル。ガソリン車", "maker": "Toyota", "model_name": "Land Cruiser"},
+		{"title": "Land Cruiser Prado", "description": "Clean diesel 4WD, one owner", "maker": "Toyota", "model_name": "Prado"},
+		{"title": "Honda Fit", "description": "Compact hatchback, diesel-free city car", "maker": "Honda", "model_name": "Fit"},
+		{"title": "ランクル 70 4WD", "description": "ランクル 再販モデル ランクル", "maker": "Toyota", "model_name": "Land Cruiser 70"},
+	} {
+		c["start_price"] = 100
+		c["end_at"] = end
+		createAuction(t, server.URL, token, c)
+	}
+
+	// 1) 설명문까지 검색 + 관련도 순 (ランクル 가 더 많이 등장하는 쪽이 먼저)
+	_, items := listAuctions(t, server.URL, "q="+url.QueryEscape("ランクル 4WD"))
+	assert.Equal(t, []string{"ランクル 70 4WD", "トヨタ ランドクルーザー 4WD"}, titles(items))
+
+	// 2) 영문 다중 단어는 모든 단어를 포함해야 함 (대소문자 무시)
+	_, items = listAuctions(t, server.URL, "q="+url.QueryEscape("land cruiser DIESEL"))
+	assert.Equal(t, []string{"Land Cruiser Prado"}, titles(items))
+
+	// 3) 다른 필터와 조합
+	_, items = listAuctions(t, server.URL, "q=diesel&maker=Honda")
+	assert.Equal(t, []string{"Honda Fit"}, titles(items))
+}
+
+func TestAuctionFacets(t *testing.T) {
+	router := setupRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	now := time.Now()
+	for _, c := range []map[string]any{
+		{"maker": "Toyota", "model_name": "Prius", "year": 2018, "mileage": 55000, "end_at": now.Add(30 * time.Minute)},
+		{"maker": "Toyota", "model_name": "Prius", "year": 2021, "mileage": 5000, "end_at": now.Add(48 * time.Hour)},
+		{"maker": "Toyota", "model_name": "Aqua", "year": 2016, "mileage": 120000, "end_at": now.Add(-time.Hour)},
+		{"maker": "Honda", "model_name": "Fit", "year": 2019, "mileage": 40000, "end_at": now.Add(48 * time.Hour)},
+	} {
+		c["title"] = c["model_name"]
+		c["start_price"] = 100
+		createAuction(t, server.URL, token, c)
+	}
+
+	resp, err := http.Get(server.URL + "/auctions?facets=true&maker=Toyota&status=live")
+	if err != nil {
+		t.Fatalf("목록 조회 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		TotalCount int64 `json:"total_count"`
+		Facets     struct {
+			Maker     []repo.FacetCount `json:"maker"`
+			ModelName []repo.FacetCount `json:"model_name"`
+			Year      []repo.FacetCount `json:"year"`
+			Mileage   []repo.FacetCount `json:"mileage"`
+			Status    []repo.FacetCount `json:"status"`
+		} `json:"facets"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, int64(2), body.TotalCount)
+
+	counts := func(fs []repo.FacetCount) map[string]int64 {
+		m := make(map[string]int64)
+		for _, f := range fs {
+			m[f.Value] = f.Count
+		}
+		return m
+	}
+	// 제조사 패싯은 자신의 조건(maker)을 제외하고, 나머지 조건(status=live)은 적용
+	assert.Equal(t, map[string]int64{"Toyota": 2, "Honda": 1}, counts(body.Facets.Maker))
+	assert.Equal(t, map[string]int64{"Prius": 2}, counts(body.Facets.ModelName))
+	assert.Equal(t, map[string]int64{"2015-2019": 1, "2020-2024": 1}, counts(body.Facets.Year))
+	assert.Equal(t, map[string]int64{"0-9999": 1, "50000-99999": 1}, counts(body.Facets.Mileage))
+	assert.Equal(t, map[string]int64{"live": 2, "ending_soon": 1, "ended": 1}, counts(body.Facets.Status))
+}
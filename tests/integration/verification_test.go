@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPDF는 본인 확인 서류로 업로드할 최소한의 PDF 입니다.
+var testPDF = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")
+
+// uploadDocument는 multipart 로 본인 확인 서류를 첨부합니다.
+func uploadDocument(t *testing.T, baseURL, token, kind string, data []byte) (int, model.VerificationDocument) {
+	t.Helper()
+	var d model.VerificationDocument
+	return uploadMultipart(t, baseURL+"/users/me/verification/documents", token, "doc", data, map[string]string{"kind": kind}, &d), d
+}
+
+func TestDealerVerification(t *testing.T) {
+	t.Setenv("KYC_BID_THRESHOLD", "1000")
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	adminToken := loginAdmin(t, app, server.URL)
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	auc := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Prius", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+	bidsURL := fmt.Sprintf("%s/auctions/%d/bids", server.URL, auc.ID)
+	myURL := server.URL + "/users/me/verification"
+	submit := map[string]string{"company_name": " 山田自動車 ", "licence_number": "3011-2345-6789"}
+
+	// 1) 정책: 기준 금액 미만은 입찰 가능, 이상은 본인 확인 승인 필요
+	placeBid(t, server.URL, bidderToken, auc.ID, 500)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 1500}, nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(t, "GET", myURL, bidderToken, nil, nil))
+
+	// 2) 서류 첨부: 종류·형식이 잘못되면 400, 이미지와 PDF 는 서명된 URL 로 배포
+	status, _ := uploadDocument(t, server.URL, bidderToken, "passport", testPNG(10, 10))
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = uploadDocument(t, server.URL, bidderToken, model.DocIdentity, []byte("<html>not a document</html>"))
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, licence := uploadDocument(t, server.URL, bidderToken, model.DocAntiqueDealerLicence, testPNG(400, 300))
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, model.DocAntiqueDealerLicence, licence.Kind)
+	assert.Equal(t, "image/png", licence.ContentType)
+	assert.Equal(t, "320", licence.ThumbWidths)
+	assert.Contains(t, licence.URL, "/static/verifications/")
+
+	// 필수 서류(등기사항증명서)가 없으면 제출 불가
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", myURL+"/submit", bidderToken, submit, nil))
+	status, registration := uploadDocument(t, server.URL, bidderToken, model.DocCompanyRegistration, testPDF)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, model.DocCompanyRegistration, registration.Kind)
+	assert.Equal(t, "application/pdf", registration.ContentType)
+	assert.Empty(t, registration.ThumbWidths)
+	resp, err := http.Get(server.URL + registration.URL)
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+		assert.Equal(t, testPDF, body)
+	}
+	status, extra := uploadDocument(t, server.URL, bidderToken, model.DocIdentity, testPNG(20, 20))
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, http.StatusNoContent, doJSON(t, "DELETE", fmt.Sprintf("%s/documents/%d", myURL, extra.ID), bidderToken, nil, nil))
+
+	// 3) 제출: 허가 번호 형식 검사, 제출 후에는 서류 변경 불가
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", myURL+"/submit", bidderToken,
+		map[string]string{"company_name": "山田自動車", "licence_number": "123"}, nil))
+	var v model.DealerVerification
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", myURL+"/submit", bidderToken, submit, &v))
+	assert.Equal(t, model.VerificationPending, v.Status)
+	assert.Equal(t, "山田自動車", v.CompanyName)
+	assert.Equal(t, "301123456789", v.LicenceNumber)
+	assert.Len(t, v.Documents, 2)
+	status, _ = uploadDocument(t, server.URL, bidderToken, model.DocIdentity, testPNG(20, 20))
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "DELETE", fmt.Sprintf("%s/documents/%d", myURL, licence.ID), bidderToken, nil, nil))
+
+	// 4) 관리자 심사: 권한이 없으면 403, 반려에는 사유 필수, 심사가 끝난 신청은 409
+	adminURL := server.URL + "/admin/verifications"
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "GET", adminURL, bidderToken, nil, nil))
+	var queue struct {
+		Data       []model.DealerVerification `json:"data"`
+		TotalCount int64                      `json:"total_count"`
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", adminURL+"?status=pending", adminToken, nil, &queue))
+	if assert.Len(t, queue.Data, 1) {
+		assert.Equal(t, v.ID, queue.Data[0].ID)
+		assert.Len(t, queue.Data[0].Documents, 2)
+		assert.NotEmpty(t, queue.Data[0].Documents[0].URL)
+	}
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "GET", adminURL+"?status=unknown", adminToken, nil, nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, "POST", fmt.Sprintf("%s/%d/reject", adminURL, v.ID), adminToken, nil, nil))
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", fmt.Sprintf("%s/%d/reject", adminURL, v.ID), adminToken,
+		map[string]string{"reason": "許可証の画像が不鮮明"}, &v))
+	assert.Equal(t, model.VerificationRejected, v.Status)
+	assert.Equal(t, http.StatusConflict, doJSON(t, "POST", fmt.Sprintf("%s/%d/approve", adminURL, v.ID), adminToken, nil, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 1500}, nil))
+
+	// 5) 반려 후에는 새 신청으로 다시 제출, 승인되면 고액 입찰 가능
+	status, _ = uploadDocument(t, server.URL, bidderToken, model.DocAntiqueDealerLicence, testPNG(60, 40))
+	assert.Equal(t, http.StatusCreated, status)
+	status, _ = uploadDocument(t, server.URL, bidderToken, model.DocCompanyRegistration, testPDF)
+	assert.Equal(t, http.StatusCreated, status)
+	var again model.DealerVerification
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", myURL+"/submit", bidderToken, submit, &again))
+	assert.NotEqual(t, v.ID, again.ID)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", fmt.Sprintf("%s/%d/approve", adminURL, again.ID), adminToken, nil, &again))
+	assert.Equal(t, model.VerificationApproved, again.Status)
+	placeBid(t, server.URL, bidderToken, auc.ID, 1500)
+	var me struct {
+		DealerVerifiedAt *time.Time `json:"dealer_verified_at"`
+	}
+	doJSON(t, "GET", server.URL+"/users/me", bidderToken, nil, &me)
+	assert.NotNil(t, me.DealerVerifiedAt)
+	status, _ = uploadDocument(t, server.URL, bidderToken, model.DocIdentity, testPNG(20, 20))
+	assert.Equal(t, http.StatusConflict, status)
+
+	// 6) 승인 취소: 다시 고액 입찰 불가
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", fmt.Sprintf("%s/%d/revoke", adminURL, again.ID), adminToken,
+		map[string]string{"reason": "古物商許可の失効"}, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, "POST", bidsURL, bidderToken, map[string]int{"amount": 2000}, nil))
+
+	// 7) 상태 변경마다 신청자에게 알림, 감사 로그 기록
+	var kinds []string
+	app.DB.Model(&model.Notification{}).Where("kind LIKE ?", "verification_%").Order("id").Pluck("kind", &kinds)
+	assert.Equal(t, []string{"verification_submitted", "verification_rejected", "verification_submitted",
+		"verification_approved", "verification_revoked"}, kinds)
+	rejected := app.Mail.SentWithSubject("本人確認の申請が却下されました")
+	if assert.Len(t, rejected, 1) {
+		assert.Equal(t, "bidder@b.com", rejected[0].To)
+		assert.Contains(t, rejected[0].Body, "許可証の画像が不鮮明")
+	}
+	var actions []string
+	app.DB.Model(&model.AuditLog{}).Where("target_type = ?", service.AuditTargetVerification).Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{service.AuditVerificationSubmit, service.AuditVerificationReject, service.AuditVerificationSubmit,
+		service.AuditVerificationApprove, service.AuditVerificationRevoke}, actions)
+}
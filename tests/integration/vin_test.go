@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/ksj/car-auction/internal/vin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVINDecode(t *testing.T) {
+	// 북미 VIN: 체크 디지트 검증, 연식·공장 디코드
+	info, err := vin.Decode("1hgcm82633a004352", time.Now())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Honda", info.Maker)
+		assert.Equal(t, 2003, info.ModelYear)
+		assert.Equal(t, "Marysville, Ohio", info.Plant)
+		assert.True(t, info.SameMaker("ホンダ"))
+	}
+	_, err = vin.Decode("1HGCM82613A004352", time.Now())
+	assert.ErrorIs(t, err, vin.ErrCheckDigit)
+	_, err = vin.Decode("1HGCM8263OA004352", time.Now())
+	assert.ErrorIs(t, err, vin.ErrInvalidVIN)
+
+	// 중국 VIN 도 체크 디지트 필수
+	_, err = vin.Decode("LVSHCAMB7CE012345", time.Now())
+	assert.NoError(t, err)
+	_, err = vin.Decode("LVSHCAMB0CE012345", time.Now())
+	assert.ErrorIs(t, err, vin.ErrCheckDigit)
+
+	// 유럽 VIN 은 9 번째 자리를 체크 디지트로 쓰지 않으므로 불일치해도 허용 (문자 종류는 검증)
+	info, err = vin.Decode("WDD2040021A123456", time.Now())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Mercedes-Benz", info.Maker)
+	}
+	_, err = vin.Decode("WDD204002IA123456", time.Now())
+	assert.ErrorIs(t, err, vin.ErrInvalidVIN)
+
+	// 국내 차대번호: 형식 검증, 형식(型式)으로 차종 디코드
+	info, err = vin.DecodeChassis("zvw30-1234567")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Toyota", info.Maker)
+		assert.Equal(t, "Prius", info.ModelName)
+	}
+	_, err = vin.DecodeChassis("ZVW30")
+	assert.ErrorIs(t, err, vin.ErrInvalidChassisNumber)
+}
+
+func TestAuctionVIN(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	token := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	end := time.Now().Add(time.Hour)
+
+	// 1) 메이커·연식이 비어 있으면 VIN 에서 자동 입력
+	a := createAuction(t, server.URL, token, map[string]any{
+		"title": "Accord", "start_price": 100, "model_name": "Accord",
+		"vin": "1HGCM82633A004352", "end_at": end,
+	})
+	assert.Equal(t, "Honda", a.Maker)
+	assert.Equal(t, 2003, a.Year)
+	assert.Empty(t, a.SpecMismatch)
+
+	// 2) 같은 VIN 으로 진행 중인 경매가 있으면 409
+	code := doJSON(t, "POST", server.URL+"/auctions", token, map[string]any{
+		"title": "Accord again", "start_price": 100, "model_name": "Accord",
+		"vin": "1HGCM82633A004352", "end_at": end,
+	}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 경매가 끝나면 같은 VIN 으로 다시 출품 가능
+	adminToken := loginAdmin(t, app, server.URL)
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", fmt.Sprintf("%s/admin/auctions/%d/close", server.URL, a.ID), adminToken,
+		map[string]string{"reason": "relist"}, nil))
+	relisted := createAuction(t, server.URL, token, map[string]any{
+		"title": "Accord again", "start_price": 100, "model_name": "Accord",
+		"vin": "1HGCM82633A004352", "end_at": end,
+	})
+	assert.NotEqual(t, a.ID, relisted.ID)
+
+	// 3) 체크 디지트 오류는 400
+	code = doJSON(t, "POST", server.URL+"/auctions", token, map[string]any{
+		"title": "Bad", "start_price": 100, "maker": "Honda", "model_name": "Accord",
+		"vin": "1HGCM82613A004352", "end_at": end,
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 4) 차대번호와 입력값이 모순되면 표시 (같은 메이커의 일본어 표기는 모순이 아님)
+	b := createAuction(t, server.URL, token, map[string]any{
+		"title": "Prius?", "start_price": 100, "maker": "トヨタ", "model_name": "Aqua", "year": 2012,
+		"chassis_number": "ZVW30-1234567", "end_at": end,
+	})
+	assert.Equal(t, "model_name", b.SpecMismatch)
+
+	var detail service.AuctionDetail
+	doJSON(t, "GET", fmt.Sprintf("%s/auctions/%d", server.URL, b.ID), "", nil, &detail)
+	if assert.NotNil(t, detail.Vehicle) {
+		assert.Equal(t, "Prius", detail.Vehicle.ModelName)
+	}
+
+	// 5) 출품 폼용 디코드 API
+	var spec vin.Info
+	assert.Equal(t, http.StatusOK, doJSON(t, "GET", server.URL+"/vehicles/decode?vin=1HGCM82633A004352", "", nil, &spec))
+	assert.Equal(t, "Marysville, Ohio", spec.Plant)
+}
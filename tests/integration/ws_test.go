@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ksj/car-auction/internal/model"
+	"github.com/ksj/car-auction/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// createAuction은 판매자 토큰으로 경매를 하나 생성해 반환합니다.
+func createAuction(t *testing.T, baseURL, token string, body map[string]any) model.Auction {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", baseURL+"/auctions", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("경매 생성 요청 실패: %v", err)
+	}
+	defer resp.Body.Close()
+	if !assert.Equal(t, http.StatusCreated, resp.StatusCode) {
+		t.FailNow()
+	}
+	var a model.Auction
+	if err := json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		t.Fatalf("경매 응답 파싱 실패: %v", err)
+	}
+	return a
+}
+
+func TestAuctionViewerPresence(t *testing.T) {
+	app := setupApp(t)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	adminToken := loginAdmin(t, app, server.URL)
+	sellerToken := signupAndLogin(t, server.URL, "seller@b.com", "seller")
+	bidderToken := signupAndLogin(t, server.URL, "bidder@b.com", "bidder")
+	suspendedToken := signupAndLogin(t, server.URL, "suspended@b.com", "bidder")
+	var suspended model.User
+	app.DB.Where("email = ?", "suspended@b.com").First(&suspended)
+	suspendURL := server.URL + "/admin/users/" + strconv.Itoa(int(suspended.ID)) + "/suspend"
+	assert.Equal(t, http.StatusOK, doJSON(t, "POST", suspendURL, adminToken, map[string]string{"reason": "fraud"}, nil))
+	a := createAuction(t, server.URL, sellerToken, map[string]any{
+		"title": "Viewers", "start_price": 100, "maker": "Toyota", "model_name": "Prius",
+		"end_at": time.Now().Add(time.Hour),
+	})
+
+	// 1) 익명 1명 + 같은 입찰자가 두 개의 탭으로 접속 + 정지된 계정의 토큰은 익명으로 취급
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/auctions/" + strconv.Itoa(int(a.ID))
+	for _, u := range []string{wsURL, wsURL + "?token=" + bidderToken, wsURL + "?token=" + bidderToken, wsURL + "?token=" + suspendedToken} {
+		conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+		if err != nil {
+			t.Fatalf("웹소켓 접속 실패: %v", err)
+		}
+		defer conn.Close()
+	}
+
+	// 2) 상세 조회에 실시간 시청자 수가 포함되는지 확인
+	var detail service.AuctionDetail
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(server.URL + "/auctions/" + strconv.Itoa(int(a.ID)))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		_ = json.NewDecoder(resp.Body).Decode(&detail)
+		return detail.Viewers.Viewers == 4
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, detail.Viewers.Users)
+	assert.Equal(t, 1, detail.Viewers.Bidders)
+	assert.Equal(t, 2, detail.Viewers.Anonymous)
+}